@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/alert"
+	"ai-ops/internal/config"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未配置时使用的告警接收参数
+const (
+	defaultAlertListen      = "127.0.0.1:9095"
+	defaultAlertTimeout     = 5 * time.Minute
+	defaultAlertDedupWindow = 30 * time.Minute
+	defaultAlertRateWindow  = time.Hour
+)
+
+// alertReceiverCmd represents the alert-receiver command
+var alertReceiverCmd = &cobra.Command{
+	Use:   "alert-receiver",
+	Short: "接收告警 webhook 并自动诊断",
+	Long: `常驻运行的 HTTP 服务，在 POST /alerts 接收 Alertmanager webhook 或通用 JSON 告警，
+按路由规则生成诊断提示词，在智能体模式下只使用只读工具进行诊断，结果写入配置的输出目标
+（webhook、文件或标准输出），并记录到任务执行记录中（ai-ops scheduler history 查看）。
+
+通用 JSON 告警为对象或对象数组，需要 alertname、name 或 title 字段；labels、annotations
+对象直接使用，没有 labels 时顶层的字符串与数字字段作为标签。告警指纹优先使用 fingerprint
+或 id 字段，否则由标签计算。
+
+同一告警（指纹与状态）在 [alerts] dedup_window 内只诊断一次，在 rate_window 内最多诊断
+rate_limit 次；已恢复的告警默认不诊断。
+
+路由规则文件（[alerts] rules，默认 ~/.ai-ops/alerts.toml）示例:
+  [[route]]
+  name = "disk"
+  match = { alertname = "NodeDiskFull" }
+  match_re = { instance = "prod-.*" }
+  prompt = "主机 {{ .Labels.instance }} 磁盘告警：{{ .Annotations.summary }}，请诊断原因"
+  tools = ["sysinfo"]
+
+  [[output]]
+  type = "webhook"
+  url = "https://hooks.example.com/ops"
+
+收到 SIGINT/SIGTERM 后停止接收并等待正在执行的诊断结束，再次收到信号时立即退出。
+
+使用示例:
+  ai-ops alert-receiver
+  ai-ops alert-receiver --listen 0.0.0.0:9095 --rules /etc/ai-ops/alerts.toml`,
+	Annotations: map[string]string{annotationLongRunning: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		rulesPath, _ := cmd.Flags().GetString("rules")
+		if err := runAlertReceiver(listen, rulesPath); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			exitCode = exitCodeFor(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(alertReceiverCmd)
+
+	alertReceiverCmd.Flags().String("listen", "", "监听地址（默认使用 [alerts] listen 配置）")
+	alertReceiverCmd.Flags().String("rules", "", "路由规则文件（默认使用 [alerts] rules 配置）")
+}
+
+// runAlertReceiver 启动告警接收器，直到收到退出信号
+func runAlertReceiver(listen, rulesPath string) error {
+	alerts := config.GetConfig().Alerts
+	if listen == "" {
+		listen = alerts.Listen
+	}
+	if listen == "" {
+		listen = defaultAlertListen
+	}
+	if rulesPath == "" {
+		rulesPath = alerts.Rules
+	}
+	if rulesPath == "" {
+		rulesPath = alert.DefaultRulesPath()
+	}
+
+	rules, err := alert.LoadRules(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	// 第一次收到信号时停止接收，再次收到时立即退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; !ok {
+			return
+		}
+		util.Info("收到退出信号，停止接收告警并等待正在执行的诊断结束（再次发送信号立即退出）")
+		cancel()
+		if _, ok := <-signals; ok {
+			util.Warn("立即退出，正在执行的诊断被中断")
+			os.Exit(exitCanceled)
+		}
+	}()
+
+	runner, cleanup, err := newTaskRunner(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	limiter := alert.NewLimiter(
+		secondsOr(alerts.DedupWindow, defaultAlertDedupWindow),
+		alerts.RateLimit,
+		secondsOr(alerts.RateWindow, defaultAlertRateWindow))
+	receiver, err := alert.NewReceiver(runner, toolManager, rules, limiter, alert.Options{
+		Token:         os.ExpandEnv(alerts.Token),
+		Model:         alerts.Model,
+		Timeout:       secondsOr(alerts.Timeout, defaultAlertTimeout),
+		Resolved:      alerts.Resolved,
+		MaxConcurrent: alerts.MaxConcurrent,
+		QueueSize:     alerts.QueueSize,
+	})
+	if err != nil {
+		return err
+	}
+	if len(receiver.ReadOnlyTools()) == 0 {
+		util.Warn("没有可用的只读工具，诊断只能根据告警内容进行分析")
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return errors.WrapErrorWithDetails(errors.ErrCodeInitializationFailed,
+			"告警接收器启动失败", err, "监听地址: "+listen)
+	}
+	return receiver.Serve(ctx, listener)
+}
+
+// secondsOr 将以秒为单位的配置转换为时间间隔，未配置时返回默认值
+func secondsOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/util/errors"
+)
+
+// 标准输入最多读取的字节数，超出部分截断
+const maxStdinBytes = 256 * 1024
+
+// askCmd represents the ask command
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "单次提问（非交互模式）",
+	Long: `向AI提问一次并输出结果，适合在脚本和管道中使用。
+标准输入的内容会作为上下文附加到问题之后。
+
+输出格式:
+  text      纯文本（默认）
+  markdown  原始 Markdown
+  json      包含回答、工具调用记录与令牌用量的 JSON
+
+有风险（变更/破坏性）的工具调用会在终端中请求确认，无终端时按拒绝处理，
+可通过 config.toml 中的 [tools] approval 修改。
+
+退出码:
+  0 成功  1 其他错误  2 参数错误  3 配置错误
+  4 网络或模型服务错误  5 超时  6 模型响应无效
+  130 被中断（Ctrl+C 或 SIGTERM）
+
+使用示例:
+  ai-ops ask "当前系统负载如何"
+  journalctl -u nginx | ai-ops ask "为什么启动失败"
+  ai-ops ask --model openai --no-tools -o json "解释一下 OOM killer"
+  ai-ops ask --profile k8s "为什么 Pod 一直 Pending"`,
+	Annotations: map[string]string{annotationScriptOutput: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		exitCode = runAsk(cmd, args)
+	},
+}
+
+// askError JSON 输出中的错误信息
+type askError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// askResult ask 命令的 JSON 输出
+type askResult struct {
+	Adapter    string               `json:"adapter"`
+	Model      string               `json:"model"`
+	Mode       string               `json:"mode"`
+	Answer     string               `json:"answer"`
+	Thinking   string               `json:"thinking,omitempty"`
+	Rounds     int                  `json:"rounds"`
+	ToolCalls  []chat.ToolCallTrace `json:"tool_calls"`
+	Usage      llm.TokenUsage       `json:"usage"`
+	DurationMs int64                `json:"duration_ms"`
+	Agent      *chat.AgentReport    `json:"agent,omitempty"` // 智能体模式的执行报告
+	Error      *askError            `json:"error,omitempty"`
+	ExitCode   int                  `json:"exit_code"`
+}
+
+func init() {
+	rootCmd.AddCommand(askCmd)
+
+	askCmd.Flags().StringP("model", "m", "", "使用的模型（config.toml 中的模型名称，默认使用 default_model）")
+	askCmd.Flags().BoolP("agent", "a", false, "启用智能体模式（先制定计划再执行，输出执行报告）")
+	askCmd.Flags().Bool("no-tools", false, "禁用工具调用")
+	askCmd.Flags().StringP("profile", "p", "", "提示词模板名称（~/.ai-ops/prompts 中的模板，default 为内置模板）")
+	askCmd.Flags().StringP("output", "o", "text", "输出格式: text/markdown/json")
+}
+
+// runAsk 执行单次提问并返回退出码
+func runAsk(cmd *cobra.Command, args []string) int {
+	modelFlag, _ := cmd.Flags().GetString("model")
+	isAgent, _ := cmd.Flags().GetBool("agent")
+	noTools, _ := cmd.Flags().GetBool("no-tools")
+	output, _ := cmd.Flags().GetString("output")
+
+	result := askResult{Mode: getMode(isAgent), ToolCalls: []chat.ToolCallTrace{}}
+	fail := func(err error) int {
+		result.ExitCode = exitCodeFor(err)
+		if output == "json" {
+			result.Error = &askError{Code: errors.CodeOf(err), Message: err.Error()}
+			printJSON(result)
+		} else {
+			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		}
+		return result.ExitCode
+	}
+
+	if output != "text" && output != "markdown" && output != "json" {
+		return fail(errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "不支持的输出格式", output))
+	}
+
+	input, err := buildAskInput(args)
+	if err != nil {
+		return fail(err)
+	}
+
+	// 选择模型
+	modelName, client := getDefaultClient()
+	if modelFlag != "" {
+		adapter, exists := llm.GetAdapter(modelFlag)
+		if !exists {
+			return fail(errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", modelFlag))
+		}
+		modelName, client = modelFlag, adapter
+	}
+	if client == nil {
+		return fail(errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml"))
+	}
+	result.Adapter = modelName
+	result.Model = client.GetModelInfo().Name
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return fail(err)
+	}
+
+	prompts := prompt.NewLibrary(config.GetConfig().Prompts.Dir)
+	profile, err := resolvePromptProfile(cmd, prompts, "", result.Mode)
+	if err != nil {
+		return fail(err)
+	}
+
+	timeout := time.Duration(config.GetConfig().AI.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if !noTools {
+		_, stopMCP := startMCPService(ctx)
+		defer stopMCP()
+	}
+
+	// 标准输入可能是管道，通过终端设备确认有风险的工具调用
+	var approver chat.ToolApprover
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		approver = chat.NewTerminalApprover(tty, tty)
+	}
+
+	session := chat.NewSession(client, toolManager, chat.SessionConfig{
+		Mode:         result.Mode,
+		ModelName:    modelName,
+		Redactor:     redactor,
+		DisableTools: noTools,
+		ApprovalMode: config.GetConfig().Tools.Approval,
+		Approver:     approver,
+		AgentBudget:  agentBudget(),
+		Prompts:      prompts,
+		Profile:      profile,
+		PromptVars:   config.GetConfig().Prompts.Vars,
+	})
+
+	startTime := time.Now()
+	var answer string
+	if isAgent {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(ctx, input, chat.AgentHooks{})
+		if report != nil {
+			result.Agent = report
+			answer = report.Final
+		}
+	} else {
+		answer, err = session.ProcessMessage(ctx, input)
+	}
+	turn := session.LastTurn()
+	result.Rounds = turn.Rounds
+	result.ToolCalls = turn.ToolCalls
+	result.Usage = turn.Usage
+	result.DurationMs = time.Since(startTime).Milliseconds()
+	if err != nil {
+		return fail(err)
+	}
+
+	thinking := chat.ExtractThinking(answer)
+	result.Answer = thinking.Content
+	result.Thinking = thinking.Thinking
+
+	// 智能体模式输出包含计划和各步骤结果的完整报告
+	text := result.Answer
+	if result.Agent != nil {
+		text = result.Agent.Markdown()
+	}
+
+	switch output {
+	case "json":
+		printJSON(result)
+	case "markdown":
+		fmt.Println(text)
+	default:
+		fmt.Println(stripMarkdown(text))
+	}
+	return exitOK
+}
+
+// buildAskInput 合并命令行问题与标准输入内容
+func buildAskInput(args []string) (string, error) {
+	question := strings.TrimSpace(strings.Join(args, " "))
+
+	stdinContent, err := readStdin()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case question == "" && stdinContent == "":
+		return "", errors.NewError(errors.ErrCodeInvalidParameters, "请提供问题，或通过标准输入传入内容")
+	case stdinContent == "":
+		return question, nil
+	case question == "":
+		return stdinContent, nil
+	default:
+		return fmt.Sprintf("%s\n\n以下是通过标准输入提供的上下文：\n```\n%s\n```", question, stdinContent), nil
+	}
+}
+
+// readStdin 读取管道传入的标准输入，终端输入时返回空
+func readStdin() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, maxStdinBytes+1))
+	if err != nil {
+		return "", errors.WrapError(errors.ErrCodeInvalidParameters, "读取标准输入失败", err)
+	}
+
+	content := strings.TrimSpace(string(data))
+	if len(data) > maxStdinBytes {
+		content = string(data[:maxStdinBytes]) + fmt.Sprintf("\n[注意: 标准输入超过 %d 字节，已截断]", maxStdinBytes)
+	}
+	return content, nil
+}
+
+// printJSON 以缩进格式输出 JSON
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+var (
+	mdHeadingPattern  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdFencePattern    = regexp.MustCompile("(?m)^```[A-Za-z0-9_+-]*\\s*$\n?")
+	mdEmphasisPattern = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdCodePattern     = regexp.MustCompile("`([^`\n]+)`")
+	mdLinkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+)
+
+// stripMarkdown 去除常见的 Markdown 标记，得到适合终端与脚本处理的纯文本
+func stripMarkdown(text string) string {
+	text = mdFencePattern.ReplaceAllString(text, "")
+	text = mdHeadingPattern.ReplaceAllString(text, "")
+	text = mdEmphasisPattern.ReplaceAllString(text, "$1$2")
+	text = mdCodePattern.ReplaceAllString(text, "$1")
+	text = mdLinkPattern.ReplaceAllString(text, "$1 ($2)")
+	return strings.TrimSpace(text)
+}
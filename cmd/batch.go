@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/batch"
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/util/errors"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch <input.jsonl>",
+	Short: "批量执行 JSONL 文件中的提示词",
+	Long: `为输入文件中的每条提示词创建独立的会话并发执行，结果逐行写入输出 JSONL 文件，
+每行包含 id、状态、回答、工具调用记录、令牌用量与错误信息。
+
+输入文件每行为 {"id": "alert-1", "prompt": "..."}，也可以直接是 JSON 字符串；
+未提供 id 时使用行号。
+
+输出文件中已有结果的条目会被跳过，中断后重新执行相同命令即可继续处理剩余条目；
+--retry-failed 同时重新执行之前失败的条目（新结果追加在文件末尾，以最后一条为准）。
+
+网络错误、限流、服务不可用与单条超时会按退避时间重试。有风险的工具调用无法人工确认，
+按 [tools] approval 配置自动批准或拒绝（prompt 模式下拒绝）。
+
+退出码:
+  0 全部成功  1 存在失败的条目  2 参数错误  3 配置错误  130 被中断（Ctrl+C 或 SIGTERM）
+
+使用示例:
+  ai-ops batch alerts.jsonl -o results.jsonl
+  ai-ops batch alerts.jsonl -j 8 --retries 3 --no-tools \
+    --instruction "将以下告警分类为 磁盘/网络/应用/误报 之一，只输出类别"`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationScriptOutput: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		exitCode = runBatch(cmd, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringP("output", "o", "", "输出 JSONL 文件（默认为 <输入文件名>.results.jsonl）")
+	batchCmd.Flags().IntP("concurrency", "j", 4, "同时执行的条目数")
+	batchCmd.Flags().Duration("timeout", 0, "每次尝试的超时时间（默认使用 [ai] timeout）")
+	batchCmd.Flags().Int("retries", 2, "可恢复错误的重试次数")
+	batchCmd.Flags().Bool("retry-failed", false, "重新执行输出文件中失败的条目")
+	batchCmd.Flags().StringP("instruction", "i", "", "附加在每条提示词之前的公共指令")
+	batchCmd.Flags().StringP("model", "m", "", "使用的模型（config.toml 中的模型名称，默认使用 default_model）")
+	batchCmd.Flags().BoolP("agent", "a", false, "启用智能体模式")
+	batchCmd.Flags().Bool("no-tools", false, "禁用工具调用")
+	batchCmd.Flags().StringP("profile", "p", "", "提示词模板名称（~/.ai-ops/prompts 中的模板，default 为内置模板）")
+}
+
+// runBatch 批量执行输入文件中的提示词并返回退出码
+func runBatch(cmd *cobra.Command, inputPath string) int {
+	fail := func(err error) int {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".results.jsonl"
+	}
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	retries, _ := cmd.Flags().GetInt("retries")
+	retryFailed, _ := cmd.Flags().GetBool("retry-failed")
+	instruction, _ := cmd.Flags().GetString("instruction")
+	modelFlag, _ := cmd.Flags().GetString("model")
+	isAgent, _ := cmd.Flags().GetBool("agent")
+	noTools, _ := cmd.Flags().GetBool("no-tools")
+	if concurrency <= 0 || retries < 0 || timeout < 0 {
+		return fail(errors.NewError(errors.ErrCodeInvalidParameters, "并发数必须大于 0，重试次数与超时时间不能为负数"))
+	}
+	if timeout == 0 {
+		timeout = time.Duration(config.GetConfig().AI.Timeout) * time.Second
+	}
+
+	// 跳过输出文件中已有结果的条目
+	items, err := batch.ReadItems(inputPath)
+	if err != nil {
+		return fail(err)
+	}
+	completed, err := batch.LoadCompleted(outputPath, retryFailed)
+	if err != nil {
+		return fail(err)
+	}
+	pending := make([]batch.Item, 0, len(items))
+	for _, item := range items {
+		if !completed[item.ID] {
+			pending = append(pending, item)
+		}
+	}
+	skipped := len(items) - len(pending)
+	if len(pending) == 0 {
+		fmt.Fprintf(os.Stderr, "全部 %d 条均已有结果，无需执行（输出文件: %s）\n", len(items), outputPath)
+		return exitOK
+	}
+
+	// 选择模型
+	modelName, client := getDefaultClient()
+	if modelFlag != "" {
+		adapter, exists := llm.GetAdapter(modelFlag)
+		if !exists {
+			return fail(errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", modelFlag))
+		}
+		modelName, client = modelFlag, adapter
+	}
+	if client == nil {
+		return fail(errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml"))
+	}
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return fail(err)
+	}
+	prompts := prompt.NewLibrary(config.GetConfig().Prompts.Dir)
+	profile, err := resolvePromptProfile(cmd, prompts, "", getMode(isAgent))
+	if err != nil {
+		return fail(err)
+	}
+
+	output, err := batch.OpenOutput(outputPath)
+	if err != nil {
+		return fail(err)
+	}
+	defer output.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !noTools {
+		_, stopMCP := startMCPService(ctx)
+		defer stopMCP()
+	}
+
+	runner := batch.NewRunner(client, toolManager, chat.SessionConfig{
+		Mode:         getMode(isAgent),
+		ModelName:    modelName,
+		Redactor:     redactor,
+		DisableTools: noTools,
+		ApprovalMode: config.GetConfig().Tools.Approval,
+		AgentBudget:  agentBudget(),
+		Prompts:      prompts,
+		Profile:      profile,
+		PromptVars:   config.GetConfig().Prompts.Vars,
+	}, batch.Options{
+		Concurrency: concurrency,
+		Timeout:     timeout,
+		Retries:     retries,
+		Instruction: instruction,
+		OnResult:    printBatchProgress,
+	})
+
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "跳过 %d 条已有结果的条目\n", skipped)
+	}
+	fmt.Fprintf(os.Stderr, "开始执行 %d 条（并发 %d，模型 %s）\n", len(pending), concurrency, modelName)
+	summary := runner.Run(ctx, pending, output)
+
+	fmt.Fprintf(os.Stderr, "完成 %d/%d 条：成功 %d，失败 %d，令牌 %d；结果已写入 %s\n",
+		summary.Succeeded+summary.Failed, summary.Total, summary.Succeeded, summary.Failed,
+		summary.Usage.TotalTokens, outputPath)
+	switch {
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "已中断，重新执行相同命令可继续处理剩余条目")
+		return exitCodeFor(ctx.Err())
+	case summary.Failed > 0:
+		return exitGeneral
+	default:
+		return exitOK
+	}
+}
+
+// printBatchProgress 在标准错误输出每条结果的进度
+func printBatchProgress(done, total int, result *batch.Result) {
+	duration := time.Duration(result.DurationMs) * time.Millisecond
+	if result.Error != nil {
+		fmt.Fprintf(os.Stderr, "[%d/%d] ❌ %s（%s，尝试 %d 次）: %s\n", done, total, result.ID,
+			duration, result.Attempts, result.Error.Message)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%d/%d] ✅ %s（%s）\n", done, total, result.ID, duration)
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/bot"
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未配置时使用的监听地址
+const defaultBotListen = "127.0.0.1:8089"
+
+// botCmd represents the bot command
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "启动 IM 机器人（飞书、钉钉、Slack）",
+	Long: `以 IM 机器人的方式提供对话：接收平台的事件回调（校验签名），每个群聊话题或私聊对应一个
+持久化的会话，执行工具调用后在话题中回复。重启后同一话题继续使用原会话（对应关系保存在 [bot] state）。
+
+回调地址（在平台的开发者后台配置，需要能从公网访问，通常放在反向代理之后）:
+  POST /slack/events     Slack Events API 与 Interactivity（app_mention、message.im、message.channels）
+  POST /feishu/events    飞书事件订阅 v2（im.message.receive_v1、card.action.trigger）
+  POST /dingtalk/events  钉钉机器人 HTTP 消息接收
+  GET  /healthz          服务状态
+
+只有 allowed_users 中的用户可以对话和确认工具调用。有风险的工具调用（mutating/destructive，
+包括 MCP toolRisk 中配置的工具）按 [bot] approval 处理：prompt（默认）在话题中发送批准、拒绝按钮，
+超过 approval_timeout 未确认按拒绝处理；auto 自动批准；deny 一律拒绝。
+
+对话中的命令:
+  /new                   开始新会话
+  /approve <确认ID>       批准工具调用
+  /deny <确认ID> [原因]   拒绝工具调用
+  /help                  显示命令说明
+
+收到 SIGINT/SIGTERM 后停止接收回调并等待处理中的消息结束，再次收到信号时立即退出。
+
+使用示例:
+  ai-ops bot
+  ai-ops bot --listen 0.0.0.0:8089 -a`,
+	Annotations: map[string]string{annotationLongRunning: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBot(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			exitCode = exitCodeFor(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(botCmd)
+
+	botCmd.Flags().String("listen", "", "监听地址（默认使用 [bot] listen 配置）")
+	botCmd.Flags().StringP("model", "m", "", "使用的模型（默认使用 [bot] model 或 default_model）")
+	botCmd.Flags().BoolP("agent", "a", false, "使用智能体模式处理消息（默认使用 [bot] mode 配置）")
+	botCmd.Flags().StringP("profile", "p", "", "提示词模板名称（默认使用 [bot] profile 或 [prompts] profile）")
+}
+
+// runBot 启动 IM 机器人，直到收到退出信号
+func runBot(cmd *cobra.Command) error {
+	cfg := config.GetConfig().Bot
+	listen, _ := cmd.Flags().GetString("listen")
+	if listen == "" {
+		listen = cfg.Listen
+	}
+	if listen == "" {
+		listen = defaultBotListen
+	}
+	mode := cfg.Mode
+	if isAgent, _ := cmd.Flags().GetBool("agent"); isAgent || mode == "" {
+		mode = getMode(isAgent)
+	}
+
+	modelName, _ := cmd.Flags().GetString("model")
+	if modelName == "" {
+		modelName = cfg.Model
+	}
+	if modelName == "" {
+		modelName, _ = getDefaultClient()
+	}
+	if modelName == "" {
+		return errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml")
+	}
+	if _, exists := llm.GetAdapter(modelName); !exists {
+		return errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", modelName)
+	}
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return err
+	}
+	prompts := prompt.NewLibrary(config.GetConfig().Prompts.Dir)
+	profile, err := resolvePromptProfile(cmd, prompts, cfg.Profile, mode)
+	if err != nil {
+		return err
+	}
+	state, err := bot.LoadState(cfg.State)
+	if err != nil {
+		return err
+	}
+
+	// 第一次收到信号时停止接收，再次收到时立即退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; !ok {
+			return
+		}
+		util.Info("收到退出信号，停止接收消息并等待处理中的消息结束（再次发送信号立即退出）")
+		cancel()
+		if _, ok := <-signals; ok {
+			util.Warn("立即退出，处理中的消息被中断")
+			os.Exit(exitCanceled)
+		}
+	}()
+
+	_, stopMCP := startMCPService(ctx)
+	defer stopMCP()
+
+	b := bot.NewBot(toolManager, chat.SessionConfig{
+		Mode:         mode,
+		ModelName:    modelName,
+		Redactor:     redactor,
+		ApprovalMode: cfg.Approval,
+		AgentBudget:  agentBudget(),
+		Prompts:      prompts,
+		Profile:      profile,
+		PromptVars:   config.GetConfig().Prompts.Vars,
+	}, newSessionStore(), state, bot.Options{
+		Timeout:         secondsOr(cfg.Timeout, 0),
+		ApprovalTimeout: secondsOr(cfg.ApprovalTimeout, 0),
+		IdleTimeout:     secondsOr(cfg.IdleTimeout, 0),
+		MaxConcurrent:   cfg.MaxConcurrent,
+	})
+	// 密钥在替换环境变量后不能为空，否则回调无法校验，白名单中的用户 ID 也可以伪造
+	if cfg.Slack.Enable {
+		slack, err := bot.NewSlack(os.ExpandEnv(cfg.Slack.SigningSecret), os.ExpandEnv(cfg.Slack.BotToken),
+			cfg.Slack.APIBase)
+		if err != nil {
+			return err
+		}
+		b.AddPlatform(slack, cfg.Slack.AllowedUsers)
+	}
+	if cfg.Feishu.Enable {
+		feishu, err := bot.NewFeishu(os.ExpandEnv(cfg.Feishu.AppID), os.ExpandEnv(cfg.Feishu.AppSecret),
+			os.ExpandEnv(cfg.Feishu.VerificationToken), os.ExpandEnv(cfg.Feishu.EncryptKey), cfg.Feishu.APIBase)
+		if err != nil {
+			return err
+		}
+		b.AddPlatform(feishu, cfg.Feishu.AllowedUsers)
+	}
+	if cfg.DingTalk.Enable {
+		dingTalk, err := bot.NewDingTalk(os.ExpandEnv(cfg.DingTalk.AppSecret))
+		if err != nil {
+			return err
+		}
+		b.AddPlatform(dingTalk, cfg.DingTalk.AllowedUsers)
+	}
+	if len(b.Platforms()) == 0 {
+		return errors.NewError(errors.ErrCodeConfigInvalid, "没有启用任何 IM 平台，请在 [bot.slack]、[bot.feishu] 或 [bot.dingtalk] 中设置 enable = true")
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return errors.WrapErrorWithDetails(errors.ErrCodeInitializationFailed,
+			"IM 机器人启动失败", err, "监听地址: "+listen)
+	}
+	return b.Serve(ctx, listener)
+}
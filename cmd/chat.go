@@ -2,15 +2,24 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"ai-ops/internal/chat"
 	"ai-ops/internal/config"
 	"ai-ops/internal/llm"
 	"ai-ops/internal/mcp"
+	"ai-ops/internal/metrics"
+	"ai-ops/internal/notify"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
 	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
 )
 
 // chatCmd represents the chat command
@@ -26,12 +35,20 @@ var chatCmd = &cobra.Command{
 使用示例:
   ai-ops chat              # 普通对话模式
   ai-ops chat -a           # 智能体模式
-  ai-ops chat -a -t        # 智能体模式 + 显示思考过程`,
+  ai-ops chat -a -t        # 智能体模式 + 显示思考过程
+  ai-ops chat --tool-choice sysinfo  # 每轮对话首先强制调用 sysinfo 工具
+  ai-ops chat --resume     # 恢复最近一次会话
+  ai-ops chat --resume 20250101-120000-a1b2  # 恢复指定会话
+  ai-ops chat --profile k8s  # 使用 ~/.ai-ops/prompts 中的 k8s 提示词模板
+  ai-ops chat --plain      # 行模式，适用于串口、script 录制等不支持全屏界面的终端
+  ai-ops chat -a --notify ops  # 每轮的回答（智能体模式为执行报告）发送到 [notify.channels] 中的 ops 渠道
+  ai-ops chat < questions.txt > answers.txt  # 输入输出不是终端时自动使用行模式`,
+	Annotations: map[string]string{annotationLongRunning: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		util.Info("正在启动交互式对话模式...")
 
 		// 获取默认模型
-		client := getDefaultClient()
+		modelName, client := getDefaultClient()
 		if client == nil {
 			util.Error("没有可用的AI模型配置，请检查config.toml")
 			return
@@ -40,59 +57,150 @@ var chatCmd = &cobra.Command{
 		// 解析参数
 		isAgent, _ := cmd.Flags().GetBool("agent")
 		showThinking, _ := cmd.Flags().GetBool("think")
+		toolChoice, _ := cmd.Flags().GetString("tool-choice")
+
+		// 恢复历史会话，未显式指定的参数沿用会话原有设置
+		store := newSessionStore()
+		var transcript *chat.Transcript
+		if cmd.Flags().Changed("resume") {
+			resumeRef, _ := cmd.Flags().GetString("resume")
+			var err error
+			transcript, err = loadTranscript(store, resumeRef)
+			if err != nil {
+				util.Errorw("恢复会话失败", map[string]any{"error": err.Error()})
+				return
+			}
+			if !cmd.Flags().Changed("agent") {
+				isAgent = transcript.Meta.Mode == "agent"
+			}
+			showThinking = showThinking || transcript.Meta.ShowThinking
+			if adapter, exists := llm.GetAdapter(transcript.Meta.Adapter); exists {
+				modelName, client = transcript.Meta.Adapter, adapter
+			} else if transcript.Meta.Adapter != "" {
+				util.Warnw("会话原模型已不可用，使用默认模型", map[string]any{
+					"adapter": transcript.Meta.Adapter,
+					"default": modelName,
+				})
+			}
+		}
+
+		// 创建敏感信息脱敏器
+		redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+		if err != nil {
+			util.LogErrorWithFields(err, "脱敏器初始化失败", nil)
+			return
+		}
+
+		// 确定提示词模板，恢复的会话沿用原有模板
+		prompts := prompt.NewLibrary(config.GetConfig().Prompts.Dir)
+		resumedProfile := ""
+		if transcript != nil {
+			resumedProfile = transcript.Meta.Profile
+		}
+		profile, err := resolvePromptProfile(cmd, prompts, resumedProfile, getMode(isAgent))
+		if err != nil {
+			util.Errorw("提示词模板不可用", map[string]any{"error": err.Error()})
+			return
+		}
+
+		// 每轮的回答发送到指定的通知渠道
+		var observer *notifyObserver
+		if channels, _ := cmd.Flags().GetStringSlice("notify"); len(channels) > 0 {
+			observer, err = newNotifyObserver(channels)
+			if err != nil {
+				util.Errorw("通知渠道不可用", map[string]any{"error": err.Error()})
+				return
+			}
+			defer observer.Wait()
+		}
+
+		// 初始化MCP服务
+		mcpService, stopMCP := startMCPService(context.Background())
+		defer stopMCP()
 
 		// 创建会话配置
 		sessionConfig := chat.SessionConfig{
 			Mode:         getMode(isAgent),
 			ShowThinking: showThinking,
+			ModelName:    modelName,
+			ToolChoice:   llm.ParseToolChoice(toolChoice),
+			Redactor:     redactor,
+			Store:        store,
+			Resume:       transcript,
+			ApprovalMode: config.GetConfig().Tools.Approval,
+			AgentBudget:  agentBudget(),
+			Prompts:      prompts,
+			Profile:      profile,
+			PromptVars:   config.GetConfig().Prompts.Vars,
+			References:   referenceLimits(),
+			StatusBar:    statusBarOptions(mcpService),
+		}
+		if observer != nil {
+			sessionConfig.Observer = observer
 		}
 
-		// 初始化MCP服务
-		mcpService := mcp.NewMCPService(toolManager, "mcp_settings.json", 30*time.Second)
-		ctx := context.Background()
-
-		if err := mcpService.Initialize(ctx); err != nil {
-			util.Warnw("MCP服务初始化失败，将继续使用其他工具", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			// 确保在程序退出时清理MCP资源
-			defer func() {
-				if err := mcpService.Shutdown(); err != nil {
-					util.Warnw("MCP服务关闭失败", map[string]any{
-						"error": err.Error(),
-					})
-				}
-			}()
-
-			connectedServers := mcpService.GetConnectedServers()
-			if len(connectedServers) > 0 {
-				util.Debugw("MCP服务初始化成功", map[string]any{
-					"connected_servers": connectedServers,
-				})
+		// 启动对话，非终端环境或指定 --plain 时使用行模式
+		if plain, _ := cmd.Flags().GetBool("plain"); plain || !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+			if err := chat.RunPlainChat(client, toolManager, sessionConfig); err != nil {
+				util.Errorw("行模式对话异常退出", map[string]any{"error": err.Error()})
 			}
+		} else {
+			chat.RunChat(client, toolManager, sessionConfig)
 		}
 
-		// 启动对话
-		chat.RunChat(client, toolManager, sessionConfig)
-
 		util.Info("对话模式已退出。")
 	},
 }
 
-// getDefaultClient 获取默认配置的AI客户端
-func getDefaultClient() llm.ModelAdapter {
-	defaultModel := config.Config.AI.DefaultModel
+// isTerminal 判断文件是否为终端
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// startMCPService 初始化MCP服务并注册其工具，返回MCP服务与释放资源的函数。
+// 初始化失败时返回的服务为 nil，不影响其他工具的使用。
+func startMCPService(ctx context.Context) (*mcp.MCPService, func()) {
+	mcpService := mcp.NewMCPService(toolManager, "mcp_settings.json", 30*time.Second)
+
+	if err := mcpService.Initialize(ctx); err != nil {
+		util.Warnw("MCP服务初始化失败，将继续使用其他工具", map[string]any{
+			"error": err.Error(),
+		})
+		return nil, func() {}
+	}
+
+	metrics.Register("mcp", mcpService.CollectMetrics)
+
+	connectedServers := mcpService.GetConnectedServers()
+	if len(connectedServers) > 0 {
+		util.Debugw("MCP服务初始化成功", map[string]any{
+			"connected_servers": connectedServers,
+		})
+	}
+
+	return mcpService, func() {
+		if err := mcpService.Shutdown(); err != nil {
+			util.Warnw("MCP服务关闭失败", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// getDefaultClient 获取默认配置的AI客户端及其适配器名称
+func getDefaultClient() (string, llm.ModelAdapter) {
+	defaultModel := config.GetConfig().AI.DefaultModel
 	client, exists := llm.GetAdapter(defaultModel)
 	if !exists {
 		// 尝试获取任何可用的适配器
 		adapters := llm.ListAdapters()
 		if len(adapters) == 0 {
-			return nil
+			return "", nil
 		}
-		client, _ = llm.GetAdapter(adapters[0])
+		defaultModel = adapters[0]
+		client, _ = llm.GetAdapter(defaultModel)
 	}
-	return client
+	return defaultModel, client
 }
 
 // getMode 根据agent参数确定模式
@@ -103,10 +211,119 @@ func getMode(isAgent bool) string {
 	return "chat"
 }
 
+// agentBudget 根据配置生成智能体执行预算
+func agentBudget() chat.AgentBudget {
+	agent := config.GetConfig().Agent
+	return chat.AgentBudget{
+		MaxSteps:     agent.MaxSteps,
+		MaxToolCalls: agent.MaxToolCalls,
+		MaxTokens:    agent.MaxTokens,
+	}
+}
+
+// referenceLimits 根据配置生成 @文件 与 !命令 引用的限制
+func referenceLimits() chat.ReferenceLimits {
+	references := config.GetConfig().References
+	return chat.ReferenceLimits{
+		MaxFileSize:    references.MaxFileSize,
+		MaxTotalSize:   references.MaxTotalSize,
+		MaxFiles:       references.MaxFiles,
+		CommandTimeout: time.Duration(references.CommandTimeout) * time.Second,
+	}
+}
+
+// statusBarOptions 根据配置生成对话界面状态栏设置，MCP 服务不可用时不显示连接数
+func statusBarOptions(mcpService *mcp.MCPService) chat.StatusBarOptions {
+	ui := config.GetConfig().UI
+	options := chat.StatusBarOptions{
+		Items:    ui.StatusBar,
+		Currency: ui.Currency,
+	}
+	if mcpService != nil {
+		options.MCPStatus = mcpService.GetServerStatus
+	}
+	return options
+}
+
+// resolvePromptProfile 确定使用的提示词模板并检查能否加载：
+// --profile 优先，其次为 fallback（如恢复会话的模板），最后为 [prompts] profile 配置
+func resolvePromptProfile(cmd *cobra.Command, prompts *prompt.Library, fallback, mode string) (string, error) {
+	profile := config.GetConfig().Prompts.Profile
+	if fallback != "" {
+		profile = fallback
+	}
+	if cmd.Flags().Changed("profile") {
+		profile, _ = cmd.Flags().GetString("profile")
+	}
+	if _, err := prompts.Load(profile, mode); err != nil {
+		return "", err
+	}
+	return profile, nil
+}
+
 func init() {
 	rootCmd.AddCommand(chatCmd)
 
 	// 对话命令参数
 	chatCmd.Flags().BoolP("agent", "a", false, "启用智能体模式")
 	chatCmd.Flags().BoolP("think", "t", false, "显示AI思考过程")
+	chatCmd.Flags().String("tool-choice", "auto", "工具调用模式: auto/none/required 或指定工具名称")
+	chatCmd.Flags().StringP("profile", "p", "", "提示词模板名称（~/.ai-ops/prompts 中的模板，default 为内置模板）")
+	chatCmd.Flags().Bool("plain", false, "使用行模式界面（输入输出不是终端时自动启用）")
+	chatCmd.Flags().String("resume", "", "恢复已保存的会话: 会话ID（可为前缀）或 last，省略值时恢复最近一次会话")
+	chatCmd.Flags().Lookup("resume").NoOptDefVal = chat.LastSessionID
+	chatCmd.Flags().StringSlice("notify", nil, "将每轮的回答发送到通知渠道（[notify.channels] 中的名称，可指定多个）")
+}
+
+// notifyObserver 将每轮对话的回答发送到通知渠道，发送在后台进行，不阻塞对话
+type notifyObserver struct {
+	notifier *notify.Notifier
+	channels []string
+	mode     string
+	input    string
+	wg       sync.WaitGroup
+}
+
+// newNotifyObserver 创建通知观察者，渠道必须已在配置中定义
+func newNotifyObserver(channels []string) (*notifyObserver, error) {
+	notifier, err := notify.NewFromConfig(config.GetConfig().Notify)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range channels {
+		if !notifier.Has(name) {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid, "通知渠道不存在",
+				name+"（已配置: "+strings.Join(notifier.Channels(), ", ")+"）")
+		}
+	}
+	return &notifyObserver{notifier: notifier, channels: channels}, nil
+}
+
+// OnEvent 记录本轮输入，本轮完成后发送回答
+func (o *notifyObserver) OnEvent(event chat.Event) {
+	switch event.Type {
+	case chat.EventTurnStarted:
+		o.mode, o.input = event.Mode, event.Input
+	case chat.EventTurnFinished:
+		msg := notify.Message{
+			Title:   util.OneLine(o.input, 50),
+			Content: chat.ExtractThinking(event.Reply).Content,
+			Source:  o.mode,
+			Time:    event.Time,
+		}
+		for _, name := range o.channels {
+			o.wg.Add(1)
+			go func() {
+				defer o.wg.Done()
+				if err := o.notifier.Send(context.Background(), name, msg); err != nil {
+					util.Warnw("发送对话通知失败", map[string]any{"channel": name, "error": err.Error()})
+				}
+			}()
+		}
+	}
+}
+
+// Wait 等待发送中的通知完成
+func (o *notifyObserver) Wait() {
+	o.wg.Wait()
 }
@@ -33,12 +33,12 @@ func init() {
 func showConfig() {
 	fmt.Println("当前配置:")
 	fmt.Printf("  配置文件: %s\n", configPath)
-	fmt.Printf("  AI模型: %s\n", config.Config.AI.DefaultModel)
-	fmt.Printf("  日志级别: %s\n", config.Config.Logging.Level)
+	fmt.Printf("  AI模型: %s\n", config.GetConfig().AI.DefaultModel)
+	fmt.Printf("  日志级别: %s\n", config.GetConfig().Logging.Level)
 
 	if verbose {
 		// 检查默认模型的API密钥是否已配置
-		if modelConfig, exists := config.Config.AI.Models[config.Config.AI.DefaultModel]; exists {
+		if modelConfig, exists := config.GetConfig().AI.Models[config.GetConfig().AI.DefaultModel]; exists {
 			fmt.Printf("  API密钥已配置: %t\n", modelConfig.APIKey != "")
 		}
 	}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/eval"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/util/errors"
+)
+
+// evalCmd represents the eval command
+var evalCmd = &cobra.Command{
+	Use:   "eval <suite.yaml>",
+	Short: "执行评测套件，比较模型的回答质量",
+	Long: `按评测套件中的用例向一个或多个模型提问，检查工具调用与最终回答，
+输出每个模型的通过率、平均耗时、令牌用量与估算费用。
+
+每个用例包含:
+  input         发送给模型的输入
+  expect_tools  期望按顺序出现的工具调用（名称与参数匹配条件），[] 表示不应调用工具
+  expect        对回答的断言：contains、not_contains、regex，或由裁判模型判断的 judge
+  tools         工具的模拟结果，配置后工具不会真正执行（也可以在套件级别配置）
+
+未配置模拟结果的用例会真正执行工具，有风险的工具调用按 [tools] approval
+自动批准或拒绝（prompt 模式下拒绝）。
+
+退出码:
+  0 全部通过  1 存在失败的用例  2 参数错误  3 配置错误  130 被中断（Ctrl+C 或 SIGTERM）
+
+使用示例:
+  ai-ops eval suites/disk.yaml
+  ai-ops eval suites/disk.yaml -m openai,deepseek --junit report.xml
+  ai-ops eval suites/disk.yaml --run 'nginx' -o json`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationScriptOutput: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		exitCode = runEval(cmd, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+
+	evalCmd.Flags().StringSliceP("model", "m", nil, "参与评测的模型，逗号分隔（默认使用套件的 models 或 default_model）")
+	evalCmd.Flags().String("judge", "", "judge 断言使用的裁判模型（默认使用套件的 judge 或第一个参与评测的模型）")
+	evalCmd.Flags().String("run", "", "只执行名称匹配该正则表达式的用例")
+	evalCmd.Flags().Duration("timeout", 0, "单个用例的超时时间（默认使用套件的 timeout 或 [ai] timeout）")
+	evalCmd.Flags().String("junit", "", "将结果写入 JUnit XML 文件")
+	evalCmd.Flags().StringP("output", "o", "text", "输出格式: text/json")
+}
+
+// runEval 执行评测套件并返回退出码
+func runEval(cmd *cobra.Command, suitePath string) int {
+	fail := func(err error) int {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	models, _ := cmd.Flags().GetStringSlice("model")
+	judge, _ := cmd.Flags().GetString("judge")
+	filter, _ := cmd.Flags().GetString("run")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	junitPath, _ := cmd.Flags().GetString("junit")
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		return fail(errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "不支持的输出格式", output))
+	}
+	if timeout < 0 {
+		return fail(errors.NewError(errors.ErrCodeInvalidParameters, "超时时间不能为负数"))
+	}
+
+	options := eval.Options{Models: models, Judge: judge, Timeout: timeout, OnCase: printEvalProgress}
+	if filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return fail(errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "无效的用例过滤条件", err.Error()))
+		}
+		options.Filter = re
+	}
+
+	suite, err := eval.Load(suitePath)
+	if err != nil {
+		return fail(err)
+	}
+	if options.Timeout == 0 && suite.Timeout == "" {
+		options.Timeout = time.Duration(config.GetConfig().AI.Timeout) * time.Second
+	}
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return fail(err)
+	}
+	modelName, _ := getDefaultClient()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	_, stopMCP := startMCPService(ctx)
+	defer stopMCP()
+
+	runner := eval.NewRunner(toolManager, chat.SessionConfig{
+		ModelName:    modelName,
+		Redactor:     redactor,
+		ApprovalMode: config.GetConfig().Tools.Approval,
+		AgentBudget:  agentBudget(),
+		Prompts:      prompt.NewLibrary(config.GetConfig().Prompts.Dir),
+		Profile:      config.GetConfig().Prompts.Profile,
+		PromptVars:   config.GetConfig().Prompts.Vars,
+	}, options)
+
+	fmt.Fprintf(os.Stderr, "开始评测 %s（%d 个用例）\n", suite.Name, len(suite.Cases))
+	report, err := runner.Run(ctx, suite)
+	if report == nil {
+		return fail(err)
+	}
+
+	if junitPath != "" {
+		if err := eval.WriteJUnit(junitPath, report); err != nil {
+			return fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "JUnit 报告已写入 %s\n", junitPath)
+	}
+	if output == "json" {
+		printJSON(report)
+	} else {
+		printEvalReport(report)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "已中断，结果只包含已完成的用例")
+		return exitCodeFor(ctx.Err())
+	case !report.Passed():
+		return exitGeneral
+	default:
+		return exitOK
+	}
+}
+
+// printEvalProgress 在标准错误输出每个用例的结果
+func printEvalProgress(model *eval.ModelReport, result *eval.CaseResult) {
+	duration := time.Duration(result.DurationMs) * time.Millisecond
+	switch result.Status {
+	case eval.StatusPassed:
+		fmt.Fprintf(os.Stderr, "✅ [%s] %s（%s）\n", model.Adapter, result.Name, duration)
+	case eval.StatusFailed:
+		fmt.Fprintf(os.Stderr, "❌ [%s] %s（%s）: %s\n", model.Adapter, result.Name, duration,
+			strings.Join(result.Failures, "；"))
+	default:
+		fmt.Fprintf(os.Stderr, "⚠️  [%s] %s（%s）: %s\n", model.Adapter, result.Name, duration, result.Error)
+	}
+}
+
+// printEvalReport 输出每个模型的评测统计
+func printEvalReport(report *eval.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "模型\t模型名称\t通过\t失败\t错误\t通过率\t平均耗时\t令牌\t费用")
+	for _, model := range report.Models {
+		cost := "-"
+		if model.Priced {
+			cost = chat.FormatCost(model.Cost, config.GetConfig().UI.Currency)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f%%\t%s\t%d\t%s\n",
+			model.Adapter,
+			model.Model,
+			model.Passed,
+			model.Failed,
+			model.Errors,
+			model.PassRate()*100,
+			model.AvgLatency(),
+			model.Usage.TotalTokens,
+			cost)
+	}
+	w.Flush()
+	if report.Judge != "" {
+		fmt.Printf("\n裁判模型 %s 消耗令牌 %d\n", report.Judge, report.JudgeUsage.TotalTokens)
+	}
+}
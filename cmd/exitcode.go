@@ -0,0 +1,42 @@
+package cmd
+
+import "ai-ops/internal/util/errors"
+
+// 非交互命令的退出码，便于脚本区分错误类别
+const (
+	exitOK            = 0   // 成功
+	exitGeneral       = 1   // 其他错误
+	exitUsage         = 2   // 参数错误
+	exitConfig        = 3   // 配置错误（模型不存在、API Key 缺失等）
+	exitAPI           = 4   // 网络或模型服务错误（含限流、鉴权失败）
+	exitTimeout       = 5   // 超时
+	exitModelResponse = 6   // 模型响应无效
+	exitCanceled      = 130 // 被用户中断（Ctrl+C 或 SIGTERM）
+)
+
+// exitCodeFor 根据错误类别返回退出码
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	switch errors.CodeOf(err) {
+	case errors.ErrCodeInvalidParam, errors.ErrCodeInvalidParameters:
+		return exitUsage
+	case errors.ErrCodeConfigNotFound, errors.ErrCodeConfigInvalid, errors.ErrCodeConfigLoadFailed,
+		errors.ErrCodeConfigParseFailed, errors.ErrCodeInvalidConfig, errors.ErrCodeAPIKeyMissing,
+		errors.ErrCodeModelNotFound, errors.ErrCodeClientNotFound, errors.ErrCodeModelNotSupported:
+		return exitConfig
+	case errors.ErrCodeNetworkFailed, errors.ErrCodeAPIRequestFailed, errors.ErrCodeRateLimited,
+		errors.ErrCodeForbidden, errors.ErrCodeServiceUnavailable:
+		return exitAPI
+	case errors.ErrCodeTimeout:
+		return exitTimeout
+	case errors.ErrCodeContextCanceled:
+		return exitCanceled
+	case errors.ErrCodeAIResponseInvalid, errors.ErrCodeInvalidResponse:
+		return exitModelResponse
+	default:
+		return exitGeneral
+	}
+}
@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"ai-ops/internal/config"
 	"ai-ops/internal/llm"
+	"ai-ops/internal/metrics"
 	"ai-ops/internal/tools"
 	"ai-ops/internal/tools/plugins"
+	"ai-ops/internal/tracing"
 	"ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 )
@@ -22,8 +26,17 @@ var (
 	// llmManager 是全局的 AI 客户端管理器
 	// llmManager is deprecated.
 	toolManager tools.ToolManager
+	// exitCode 子命令设置的进程退出码，用于脚本判断错误类别
+	exitCode int
 )
 
+// annotationScriptOutput 标记输出供脚本解析的命令，此类命令的日志不写入标准输出
+const annotationScriptOutput = "script-output"
+
+// annotationLongRunning 标记长时间运行的命令，只有此类命令启动指标监听器，
+// 避免短命令与正在运行的对话、服务争用指标端口
+const annotationLongRunning = "long-running"
+
 // rootCmd 代表没有调用子命令时的基础命令
 var rootCmd = &cobra.Command{
 	Use:   "llm-ops",
@@ -31,7 +44,10 @@ var rootCmd = &cobra.Command{
 	Long: `AI-Ops 是一个基于人工智能的运维工具，
 提供智能对话、工具调用和自动化运维功能。`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return initializeApp()
+		return initializeApp(cmd)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		shutdownTracing()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// 默认行为：显示状态信息
@@ -46,6 +62,9 @@ func Execute() {
 		fmt.Fprintf(os.Stderr, "命令执行失败: %v\n", err)
 		os.Exit(1)
 	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
 
 func init() {
@@ -55,33 +74,48 @@ func init() {
 }
 
 // initializeApp 初始化应用
-func initializeApp() error {
+func initializeApp(cmd *cobra.Command) error {
 	// 1. 处理配置文件路径
 	if configPath == "" {
 		configPath = os.Getenv("AI_OPS_CONFIG")
 	}
 
+	// 输出供脚本解析的命令，配置加载期间的日志也不能写入标准输出
+	scriptOutput := cmd.Annotations[annotationScriptOutput] == "true"
+	if scriptOutput {
+		_ = util.InitLogger("warn", "text", "stderr", "")
+	}
+
 	// 2. 加载配置文件
 	if err := config.LoadConfig(configPath); err != nil {
 		return errors.WrapError(errors.ErrCodeConfigInvalid, "配置加载失败", err)
 	}
 
 	// 3. 根据verbose标志调整日志级别
-	logLevel := config.Config.Logging.Level
+	logLevel := config.GetConfig().Logging.Level
 	if verbose {
 		logLevel = "debug"
 	}
 
 	// 4. 初始化日志系统
-	logFormat := config.Config.Logging.Format
+	logFormat := config.GetConfig().Logging.Format
 	if logFormat == "" {
 		logFormat = "text"
 	}
-	logOutput := config.Config.Logging.Output
+	logOutput := config.GetConfig().Logging.Output
 	if logOutput == "" {
 		logOutput = "stdout"
 	}
-	logFile := config.Config.Logging.File
+	// 输出供脚本解析的命令，日志不写入标准输出，避免混入结果
+	if scriptOutput {
+		switch logOutput {
+		case "stdout":
+			logOutput = "stderr"
+		case "both":
+			logOutput = "file"
+		}
+	}
+	logFile := config.GetConfig().Logging.File
 
 	if err := util.InitLogger(logLevel, logFormat, logOutput, logFile); err != nil {
 		return errors.WrapError(errors.ErrCodeConfigInvalid, "日志系统初始化失败", err)
@@ -89,7 +123,7 @@ func initializeApp() error {
 
 	util.Info("应用配置加载完成")
 	util.Debugw("配置详情", map[string]any{
-		"default_model": config.Config.AI.DefaultModel,
+		"default_model": config.GetConfig().AI.DefaultModel,
 		"log_level":     logLevel,
 		"config_path":   configPath,
 	})
@@ -109,9 +143,78 @@ func initializeApp() error {
 		return errors.WrapError(errors.ErrCodeInitializationFailed, "工具管理器初始化失败", err)
 	}
 
+	// 8. 启动指标监听器（可选，仅长时间运行的命令）
+	initializeMetrics(cmd.Annotations[annotationLongRunning] == "true")
+
+	// 9. 初始化链路追踪（可选）
+	if err := initializeTracing(); err != nil {
+		return errors.WrapError(errors.ErrCodeInitializationFailed, "链路追踪初始化失败", err)
+	}
+
 	return nil
 }
 
+// initializeTracing 按配置创建追踪导出器并设置全局追踪提供者
+func initializeTracing() error {
+	tracingConfig := config.GetConfig().Tracing
+	if !tracingConfig.Enable {
+		return nil
+	}
+
+	serviceName := tracingConfig.ServiceName
+	if serviceName == "" {
+		serviceName = "ai-ops"
+	}
+
+	var exporter tracing.Exporter
+	switch tracingConfig.Exporter {
+	case "file":
+		fileExporter, err := tracing.NewFileExporter(tracingConfig.File)
+		if err != nil {
+			return err
+		}
+		exporter = fileExporter
+	default:
+		exporter = tracing.NewOTLPExporter(tracingConfig.Endpoint, tracingConfig.Headers, serviceName)
+	}
+
+	tracing.SetProvider(tracing.NewProvider(exporter))
+	util.Infow("链路追踪已启用", map[string]interface{}{
+		"exporter": tracingConfig.Exporter,
+		"endpoint": tracingConfig.Endpoint,
+		"file":     tracingConfig.File,
+	})
+	return nil
+}
+
+// shutdownTracing 导出剩余的追踪数据
+func shutdownTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		util.Warnw("链路追踪关闭失败", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// initializeMetrics 注册指标收集器，长时间运行的命令按配置启动 Prometheus 指标监听器。
+// 监听失败（如端口已被另一个 ai-ops 进程占用）只记录警告，不影响命令执行
+func initializeMetrics(longRunning bool) {
+	metrics.Register("llm", llm.CollectMetrics)
+	metrics.Register("tools", tools.CollectMetrics)
+
+	metricsConfig := config.GetConfig().Metrics
+	if !metricsConfig.Enable || !longRunning {
+		return
+	}
+
+	if _, err := metrics.StartServer(metricsConfig.Listen, metricsConfig.Path); err != nil {
+		util.Warnw("指标监听器启动失败，本进程不提供指标", map[string]interface{}{
+			"listen": metricsConfig.Listen,
+			"error":  err.Error(),
+		})
+	}
+}
+
 // initializeRegistries 初始化所有注册表
 func initializeRegistries() error {
 	// 初始化LLM注册表
@@ -151,7 +254,7 @@ func initializeTools() error {
 // initializeAIClients 初始化 AI 客户端
 func initializeAIClients() error {
 	// 从配置中创建和注册客户端
-	for name, modelConfig := range config.Config.AI.Models {
+	for name, modelConfig := range config.GetConfig().AI.Models {
 		if _, err := llm.CreateAdapter(name, modelConfig.Type, modelConfig); err != nil {
 			util.Warnw(fmt.Sprintf("创建 AI 适配器 '%s' 失败", name), map[string]interface{}{"error": err})
 			continue // 即使某个客户端失败，也继续尝试其他客户端
@@ -166,7 +269,7 @@ func initializeAIClients() error {
 
 	// 默认客户端的逻辑现在由使用方（例如 chat 命令）处理
 	// 这里只打印信息
-	defaultModelName := config.Config.AI.DefaultModel
+	defaultModelName := config.GetConfig().AI.DefaultModel
 	if _, exists := llm.GetAdapter(defaultModelName); !exists {
 		util.Warnw(fmt.Sprintf("配置的默认模型 '%s' 不可用，将使用第一个可用模型", defaultModelName), nil)
 		defaultModelName = llm.ListAdapters()[0]
@@ -186,7 +289,7 @@ func showStatus() {
 	fmt.Println("AI-Ops 框架初始化完成")
 	fmt.Println("配置文件加载成功")
 
-	defaultModelName := config.Config.AI.DefaultModel
+	defaultModelName := config.GetConfig().AI.DefaultModel
 	if adapter, exists := llm.GetAdapter(defaultModelName); exists {
 		fmt.Printf("默认AI模型: %s (%s)\n", adapter.GetModelInfo().Name, defaultModelName)
 	} else if len(llm.ListAdapters()) > 0 {
@@ -198,6 +301,6 @@ func showStatus() {
 		fmt.Println("默认AI模型: 未配置或初始化失败")
 	}
 
-	fmt.Printf("日志级别: %s\n", config.Config.Logging.Level)
+	fmt.Printf("日志级别: %s\n", config.GetConfig().Logging.Level)
 	fmt.Println("\n使用 'llm-ops --help' 查看可用命令")
 }
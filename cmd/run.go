@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/notify"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/task"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <task.toml>...",
+	Short: "执行任务文件",
+	Long: `立即执行一个或多个任务文件，执行结果写入任务配置的输出目标（默认为标准输出），
+并记录到执行记录中（ai-ops scheduler history 查看）。
+
+任务文件示例:
+  name = "disk-check"
+  prompt = "汇总本机的磁盘与负载情况，指出异常"
+  mode = "agent"                 # chat 或 agent
+  tools = ["sysinfo"]            # 允许调用的工具，省略时允许全部工具
+  schedule = "0 8 * * *"         # ai-ops scheduler 使用的执行计划
+  timeout = "5m"
+
+  [[output]]
+  type = "file"
+  path = "/var/log/ai-ops/{name}-{date}.md"
+
+  [[output]]
+  type = "notify"                # 发送到 [notify.channels] 中配置的渠道
+  channel = "ops"
+  when = "failure"
+
+有风险的工具调用默认拒绝执行，可在任务中设置 approval = "auto" 自动批准。
+
+退出码与 ask 命令相同，多个任务时返回第一个失败任务的退出码。
+
+使用示例:
+  ai-ops run disk-check.toml
+  ai-ops run ~/.ai-ops/tasks/*.toml`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: map[string]string{annotationScriptOutput: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		exitCode = runTasks(args)
+	},
+}
+
+// schedulerCmd represents the scheduler command
+var schedulerCmd = &cobra.Command{
+	Use:   "scheduler [task.toml...]",
+	Short: "按执行计划运行任务",
+	Long: `常驻运行，按任务文件中的 schedule 定时执行任务。未指定任务文件时加载
+任务目录（[tasks] dir，默认 ~/.ai-ops/tasks）中的全部任务。
+
+执行计划支持 5 段 cron 表达式（分 时 日 月 周，使用本地时区）、@hourly、@daily、
+@weekly、@monthly 以及 "@every 30m" 形式的固定间隔。同一任务上一次执行尚未结束时跳过本次执行，
+每次执行受任务 timeout（默认 [tasks] timeout）限制。
+
+收到 SIGINT/SIGTERM 后停止调度并等待正在执行的任务结束，再次收到信号时立即退出。
+
+使用示例:
+  ai-ops scheduler
+  ai-ops scheduler --dir /etc/ai-ops/tasks
+  ai-ops scheduler disk-check.toml nginx-errors.toml
+  ai-ops scheduler history disk-check`,
+	Annotations: map[string]string{annotationLongRunning: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if err := runScheduler(args, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			exitCode = exitCodeFor(err)
+		}
+	},
+}
+
+// schedulerHistoryCmd shows task run history
+var schedulerHistoryCmd = &cobra.Command{
+	Use:   "history [task]",
+	Short: "查看任务执行记录",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskName := ""
+		if len(args) > 0 {
+			taskName = args[0]
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		showTaskHistory(taskName, limit, asJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(schedulerCmd)
+	schedulerCmd.AddCommand(schedulerHistoryCmd)
+
+	schedulerCmd.Flags().String("dir", "", "任务文件目录（默认使用 [tasks] dir 配置）")
+	schedulerHistoryCmd.Flags().IntP("limit", "n", 20, "显示的记录条数，0 表示全部")
+	schedulerHistoryCmd.Flags().Bool("json", false, "以 JSON 格式输出完整记录")
+}
+
+// runTasks 依次执行任务文件，返回退出码
+func runTasks(paths []string) int {
+	tasks := make([]*task.Task, 0, len(paths))
+	for _, path := range paths {
+		t, err := task.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+			return exitCodeFor(err)
+		}
+		tasks = append(tasks, t)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner, cleanup, err := newTaskRunner(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		return exitCodeFor(err)
+	}
+	defer cleanup()
+
+	code := exitOK
+	for _, t := range tasks {
+		record := runner.Run(ctx, t, task.TriggerManual)
+		if err := record.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "错误: 任务 %s %s: %v\n", t.Name, record.StatusLabel(), err)
+			if code == exitOK {
+				code = exitCodeFor(err)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return code
+}
+
+// runScheduler 加载任务并按执行计划运行，直到收到退出信号
+func runScheduler(paths []string, dir string) error {
+	var tasks []*task.Task
+	if len(paths) > 0 {
+		for _, path := range paths {
+			t, err := task.Load(path)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, t)
+		}
+	} else {
+		if dir == "" {
+			dir = tasksDir()
+		}
+		var err error
+		if tasks, err = task.LoadDir(dir); err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return errors.NewErrorWithDetails(errors.ErrCodeNotFound, "任务目录中没有任务文件", dir)
+		}
+	}
+
+	// 第一次收到信号时停止调度，再次收到时立即退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; !ok {
+			return
+		}
+		util.Info("收到退出信号，停止调度并等待正在执行的任务结束（再次发送信号立即退出）")
+		cancel()
+		if _, ok := <-signals; ok {
+			util.Warn("立即退出，正在执行的任务被中断")
+			os.Exit(exitCanceled)
+		}
+	}()
+
+	runner, cleanup, err := newTaskRunner(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	scheduler, err := task.NewScheduler(runner, tasks)
+	if err != nil {
+		return err
+	}
+	util.Infow("调度器已启动", map[string]any{"tasks": len(scheduler.Tasks())})
+	scheduler.Run(ctx)
+	util.Info("调度器已退出")
+	return nil
+}
+
+// newTaskRunner 启动 MCP 服务并创建任务执行器，返回释放资源的函数
+func newTaskRunner(ctx context.Context) (*task.Runner, func(), error) {
+	modelName, client := getDefaultClient()
+	if client == nil {
+		return nil, nil, errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml")
+	}
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return nil, nil, err
+	}
+	notifier, err := notify.NewFromConfig(config.GetConfig().Notify)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, stopMCP := startMCPService(ctx)
+
+	base := chat.SessionConfig{
+		ModelName:   modelName,
+		Redactor:    redactor,
+		Store:       newSessionStore(),
+		AgentBudget: agentBudget(),
+		Prompts:     prompt.NewLibrary(config.GetConfig().Prompts.Dir),
+		Profile:     config.GetConfig().Prompts.Profile,
+		PromptVars:  config.GetConfig().Prompts.Vars,
+	}
+	timeout := time.Duration(config.GetConfig().Tasks.Timeout) * time.Second
+	return task.NewRunner(toolManager, base, newTaskHistory(), notifier, timeout), stopMCP, nil
+}
+
+// tasksDir 根据配置返回任务目录
+func tasksDir() string {
+	if config.GetConfig().Tasks.Dir != "" {
+		return config.GetConfig().Tasks.Dir
+	}
+	return task.DefaultDir()
+}
+
+// newTaskHistory 根据配置创建执行记录存储
+func newTaskHistory() *task.History {
+	return task.NewHistory(config.GetConfig().Tasks.History, config.GetConfig().Tasks.HistoryLimit)
+}
+
+// showTaskHistory 显示任务执行记录
+func showTaskHistory(taskName string, limit int, asJSON bool) {
+	history := newTaskHistory()
+	records, err := history.List(taskName, limit)
+	if err != nil {
+		fmt.Printf("❌ 读取执行记录失败: %v\n", err)
+		return
+	}
+	if asJSON {
+		if records == nil {
+			records = []*task.RunRecord{}
+		}
+		printJSON(records)
+		return
+	}
+	if len(records) == 0 {
+		fmt.Printf("暂无执行记录（文件: %s）\n", history.Path())
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t任务\t开始时间\t触发\t状态\t耗时\t工具调用\t错误")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			record.ID,
+			record.Task,
+			record.StartedAt.Format("2006-01-02 15:04:05"),
+			record.Trigger,
+			record.StatusLabel(),
+			time.Duration(record.DurationMs)*time.Millisecond,
+			len(record.ToolCalls),
+			util.OneLine(record.Error, 40))
+	}
+	w.Flush()
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/apiserver"
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未配置时使用的监听地址
+const defaultServeListen = "127.0.0.1:8088"
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动兼容 OpenAI 的 HTTP API 服务",
+	Long: `提供兼容 OpenAI Chat Completions 的 HTTP API，支持 OpenAI 协议的应用与 IDE 插件
+将 base URL 设置为 http://<listen>/v1 即可使用 ai-ops 的工具（sysinfo、MCP 等）。
+
+接口:
+  POST /v1/chat/completions  对话，支持 "stream": true（SSE）
+  GET  /v1/models            可用模型，即 config.toml 中的模型名称
+  GET  /healthz              服务状态
+
+工具调用在服务端执行，客户端只收到最终回答；请求中的 tools 字段被忽略。
+有风险的工具调用无法人工确认，按 [serve] approval 拒绝（默认）或自动批准。
+流式请求在工具执行期间发送 SSE 注释行保持连接，回答完成后一次性返回内容。
+
+配置 [serve] api_keys 后请求需携带 Authorization: Bearer <key>。
+错误响应为 {"error": {"code": "...", "message": "..."}}，code 为 ai-ops 的错误代码。
+
+收到 SIGINT/SIGTERM 后停止接收新请求并等待处理中的请求结束，再次收到信号时立即退出。
+
+使用示例:
+  ai-ops serve
+  ai-ops serve --listen 0.0.0.0:8088 --model openai
+  curl http://127.0.0.1:8088/v1/chat/completions -d '{"messages": [{"role": "user", "content": "当前系统负载如何"}]}'`,
+	Annotations: map[string]string{annotationLongRunning: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runServe(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			exitCode = exitCodeFor(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("listen", "", "监听地址（默认使用 [serve] listen 配置）")
+	serveCmd.Flags().StringP("model", "m", "", "请求未指定 model 时使用的模型（默认使用 [serve] model 或 default_model）")
+	serveCmd.Flags().BoolP("agent", "a", false, "使用智能体模式处理请求（默认使用 [serve] mode 配置）")
+	serveCmd.Flags().Bool("no-tools", false, "禁用工具调用")
+	serveCmd.Flags().StringP("profile", "p", "", "提示词模板名称（默认使用 [serve] profile 或 [prompts] profile）")
+}
+
+// runServe 启动 API 服务，直到收到退出信号
+func runServe(cmd *cobra.Command) error {
+	serve := config.GetConfig().Serve
+	listen, _ := cmd.Flags().GetString("listen")
+	if listen == "" {
+		listen = serve.Listen
+	}
+	if listen == "" {
+		listen = defaultServeListen
+	}
+	noTools, _ := cmd.Flags().GetBool("no-tools")
+	mode := serve.Mode
+	if isAgent, _ := cmd.Flags().GetBool("agent"); isAgent || mode == "" {
+		mode = getMode(isAgent)
+	}
+
+	// 默认模型
+	modelName, _ := cmd.Flags().GetString("model")
+	if modelName == "" {
+		modelName = serve.Model
+	}
+	if modelName == "" {
+		modelName, _ = getDefaultClient()
+	}
+	if modelName == "" {
+		return errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml")
+	}
+	if _, exists := llm.GetAdapter(modelName); !exists {
+		return errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", modelName)
+	}
+
+	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+	if err != nil {
+		return err
+	}
+	prompts := prompt.NewLibrary(config.GetConfig().Prompts.Dir)
+	profile, err := resolvePromptProfile(cmd, prompts, serve.Profile, mode)
+	if err != nil {
+		return err
+	}
+	approval := serve.Approval
+	if approval == "" {
+		approval = chat.ApprovalModeDeny
+	}
+
+	apiKeys := make([]string, 0, len(serve.APIKeys))
+	for _, key := range serve.APIKeys {
+		if key = os.ExpandEnv(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+	if len(apiKeys) == 0 {
+		util.Warn("未配置 [serve] api_keys，API 不校验访问密钥")
+	}
+
+	// 第一次收到信号时停止接收，再次收到时立即退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; !ok {
+			return
+		}
+		util.Info("收到退出信号，停止接收请求并等待处理中的请求结束（再次发送信号立即退出）")
+		cancel()
+		if _, ok := <-signals; ok {
+			util.Warn("立即退出，处理中的请求被中断")
+			os.Exit(exitCanceled)
+		}
+	}()
+
+	if !noTools {
+		_, stopMCP := startMCPService(ctx)
+		defer stopMCP()
+	}
+
+	server := apiserver.NewServer(toolManager, chat.SessionConfig{
+		Mode:         mode,
+		Redactor:     redactor,
+		DisableTools: noTools,
+		ApprovalMode: approval,
+		AgentBudget:  agentBudget(),
+		Prompts:      prompts,
+		Profile:      profile,
+		PromptVars:   config.GetConfig().Prompts.Vars,
+	}, apiserver.Options{
+		APIKeys:       apiKeys,
+		DefaultModel:  modelName,
+		Timeout:       secondsOr(serve.Timeout, 0),
+		MaxConcurrent: serve.MaxConcurrent,
+		MaxBodyBytes:  serve.MaxBodyBytes,
+		MaxMessages:   serve.MaxMessages,
+	})
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return errors.WrapErrorWithDetails(errors.ErrCodeInitializationFailed,
+			"API 服务启动失败", err, "监听地址: "+listen)
+	}
+	return server.Serve(ctx, listener)
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/util"
+)
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "会话管理",
+	Long:  "管理已保存的对话会话，可通过 ai-ops chat --resume 恢复",
+}
+
+// sessionsListCmd lists saved sessions
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出已保存的会话",
+	Run: func(cmd *cobra.Command, args []string) {
+		listSessions()
+	},
+}
+
+// sessionsShowCmd shows a saved session
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show [id|last]",
+	Short: "显示会话内容",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := chat.LastSessionID
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		showSession(ref)
+	},
+}
+
+// sessionsDeleteCmd deletes saved sessions
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete [id...]",
+	Short: "删除会话",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteSessions(args)
+	},
+}
+
+// sessionsExportCmd exports a saved session
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export [id|last]",
+	Short: "导出会话为 Markdown、HTML 或 JSON",
+	Long: `导出会话记录，包含用户与 AI 消息、思考过程、工具调用参数与结果、模型与令牌用量，
+便于粘贴到故障工单。未指定 --output 时输出到标准输出；未指定 --format 时按输出文件扩展名推断，
+否则使用 [sessions] export_format 配置。启用脱敏时导出的是脱敏后的内容。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := chat.LastSessionID
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		if err := exportSession(cmd, ref); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsDeleteCmd)
+	sessionsCmd.AddCommand(sessionsExportCmd)
+
+	sessionsExportCmd.Flags().StringP("format", "f", "", "导出格式：markdown、html、json")
+	sessionsExportCmd.Flags().StringP("output", "o", "", "输出文件路径，默认输出到标准输出")
+	sessionsExportCmd.Flags().Int("max-result", chat.DefaultExportMaxResult, "工具结果最多保留的字符数，0 表示不截断")
+}
+
+// newSessionStore 根据配置创建会话存储
+func newSessionStore() *chat.SessionStore {
+	return chat.NewSessionStore(config.GetConfig().Sessions.Dir)
+}
+
+// loadTranscript 解析会话引用并读取会话记录
+func loadTranscript(store *chat.SessionStore, ref string) (*chat.Transcript, error) {
+	id, err := store.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return store.Load(id)
+}
+
+// listSessions 列出已保存的会话
+func listSessions() {
+	store := newSessionStore()
+	summaries, err := store.List()
+	if err != nil {
+		fmt.Printf("❌ 读取会话失败: %v\n", err)
+		return
+	}
+	if len(summaries) == 0 {
+		fmt.Printf("暂无已保存的会话（目录: %s）\n", store.Dir())
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t更新时间\t模式\t模型\t轮数\t标题")
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			summary.ID,
+			summary.UpdatedAt.Format("2006-01-02 15:04"),
+			summary.Mode,
+			summary.Adapter,
+			summary.Turns,
+			util.OneLine(summary.Title, 40))
+	}
+	w.Flush()
+}
+
+// showSession 显示会话内容
+func showSession(ref string) {
+	transcript, err := loadTranscript(newSessionStore(), ref)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	meta := transcript.Meta
+	fmt.Printf("会话: %s\n", meta.ID)
+	fmt.Printf("模式: %s  模型: %s (%s)\n", meta.Mode, meta.Adapter, meta.Model)
+	fmt.Printf("创建时间: %s\n", meta.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Println(strings.Repeat("=", 40))
+
+	for _, entry := range transcript.Entries {
+		msg := entry.Message
+		if entry.Internal {
+			continue
+		}
+		timestamp := entry.Time.Format("15:04:05")
+		switch msg.Role {
+		case "user":
+			fmt.Printf("\n[%s] 👤 用户:\n%s\n", timestamp, msg.Content)
+		case "assistant":
+			for _, tc := range msg.ToolCalls {
+				args, _ := json.Marshal(tc.Arguments)
+				fmt.Printf("\n[%s] 🔧 调用工具: %s(%s)\n", timestamp, tc.Name, args)
+			}
+			if msg.Content != "" {
+				fmt.Printf("\n[%s] 🤖 AI:\n%s\n", timestamp, msg.Content)
+			}
+		case "tool":
+			fmt.Printf("\n[%s] 📋 工具结果 %s: %s\n", timestamp, msg.Name, util.OneLine(msg.Content, 200))
+		}
+	}
+}
+
+// exportSession 按命令行参数导出会话
+func exportSession(cmd *cobra.Command, ref string) error {
+	transcript, err := loadTranscript(newSessionStore(), ref)
+	if err != nil {
+		return err
+	}
+
+	opts := chat.DefaultExportOptions()
+	output, _ := cmd.Flags().GetString("output")
+	if formatFlag, _ := cmd.Flags().GetString("format"); formatFlag != "" {
+		if opts.Format, err = chat.ParseExportFormat(formatFlag); err != nil {
+			return err
+		}
+	} else if format, ok := chat.ExportFormatFromPath(output); ok {
+		opts.Format = format
+	}
+	if cmd.Flags().Changed("max-result") {
+		opts.MaxResultLength, _ = cmd.Flags().GetInt("max-result")
+	}
+
+	if output == "" {
+		return chat.Export(os.Stdout, transcript, opts)
+	}
+	if err := chat.ExportToFile(output, transcript, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ 会话 %s 已导出到 %s（%s）\n", transcript.Meta.ID, output, opts.Format)
+	return nil
+}
+
+// deleteSessions 删除会话
+func deleteSessions(refs []string) {
+	store := newSessionStore()
+	for _, ref := range refs {
+		id, err := store.Resolve(ref)
+		if err == nil {
+			err = store.Delete(id)
+		}
+		if err != nil {
+			fmt.Printf("❌ 删除会话 %s 失败: %v\n", ref, err)
+			continue
+		}
+		fmt.Printf("✅ 已删除会话 %s\n", id)
+	}
+}
@@ -0,0 +1,270 @@
+package alert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-ops/internal/util/errors"
+)
+
+// 告警状态
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// 告警来源格式
+const (
+	SourceAlertmanager = "alertmanager"
+	SourceGeneric      = "generic"
+)
+
+// Alert 统一格式的告警，Alertmanager 与通用 JSON 告警都转换为该格式
+type Alert struct {
+	Source       string            `json:"source"`
+	Status       string            `json:"status"`
+	Name         string            `json:"name"` // 告警名称，取 alertname 标签
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"starts_at,omitempty"`
+	GeneratorURL string            `json:"generator_url,omitempty"`
+	// Fingerprint 告警指纹，用于去重与限流；未提供时由标签计算
+	Fingerprint string `json:"fingerprint"`
+}
+
+// alertmanagerPayload Alertmanager webhook 请求体
+type alertmanagerPayload struct {
+	Status            string            `json:"status"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	Alerts            []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// Parse 解析 webhook 请求体。包含 alerts 数组的请求按 Alertmanager 格式解析，
+// 其余按通用 JSON 告警解析（单个对象或对象数组）。
+func Parse(body []byte) ([]*Alert, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return nil, errors.NewError(errors.ErrCodeInvalidParameters, "请求体为空")
+	}
+
+	var probe map[string]json.RawMessage
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(body, &probe); err != nil {
+			return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析告警 JSON", err)
+		}
+		if _, ok := probe["alerts"]; ok {
+			return parseAlertmanager(body)
+		}
+		alert, err := parseGeneric(probe)
+		if err != nil {
+			return nil, err
+		}
+		return []*Alert{alert}, nil
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析告警 JSON", err)
+	}
+	alerts := make([]*Alert, 0, len(items))
+	for _, item := range items {
+		alert, err := parseGeneric(item)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// parseAlertmanager 解析 Alertmanager webhook，公共标签与注解合并到每个告警
+func parseAlertmanager(body []byte) ([]*Alert, error) {
+	var payload alertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析 Alertmanager 告警", err)
+	}
+
+	alerts := make([]*Alert, 0, len(payload.Alerts))
+	for _, item := range payload.Alerts {
+		alert := &Alert{
+			Source:       SourceAlertmanager,
+			Status:       item.Status,
+			Labels:       merge(payload.CommonLabels, item.Labels),
+			Annotations:  merge(payload.CommonAnnotations, item.Annotations),
+			StartsAt:     item.StartsAt,
+			GeneratorURL: item.GeneratorURL,
+			Fingerprint:  item.Fingerprint,
+		}
+		if alert.Status == "" {
+			alert.Status = payload.Status
+		}
+		alert.normalize()
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// 通用告警中作为名称、注解与指纹的字段
+var (
+	genericNameKeys       = []string{"alertname", "name", "title"}
+	genericAnnotationKeys = []string{"summary", "description", "message"}
+	genericFingerprintKey = []string{"fingerprint", "id"}
+)
+
+// parseGeneric 解析通用 JSON 告警。labels、annotations 对象直接使用；
+// 没有 labels 时，顶层的字符串、数字与布尔字段作为标签。
+func parseGeneric(fields map[string]json.RawMessage) (*Alert, error) {
+	alert := &Alert{Source: SourceGeneric, Labels: map[string]string{}, Annotations: map[string]string{}}
+	if raw, ok := fields["labels"]; ok {
+		if err := json.Unmarshal(raw, &alert.Labels); err != nil {
+			return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "告警的 labels 必须是字符串映射", err)
+		}
+	}
+	if raw, ok := fields["annotations"]; ok {
+		if err := json.Unmarshal(raw, &alert.Annotations); err != nil {
+			return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "告警的 annotations 必须是字符串映射", err)
+		}
+	}
+
+	scalars := make(map[string]string, len(fields))
+	for key, raw := range fields {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			scalars[key] = v
+		case float64, bool:
+			scalars[key] = fmt.Sprint(v)
+		}
+	}
+
+	alert.Status = scalars["status"]
+	if _, hasLabels := fields["labels"]; !hasLabels {
+		for key, value := range scalars {
+			if key == "status" || contains(genericAnnotationKeys, key) || contains(genericFingerprintKey, key) {
+				continue
+			}
+			alert.Labels[key] = value
+		}
+	}
+	for _, key := range genericAnnotationKeys {
+		if value := scalars[key]; value != "" && alert.Annotations[key] == "" {
+			alert.Annotations[key] = value
+		}
+	}
+	for _, key := range genericNameKeys {
+		if value := scalars[key]; value != "" && alert.Labels["alertname"] == "" {
+			alert.Labels["alertname"] = value
+		}
+	}
+	for _, key := range genericFingerprintKey {
+		if value := scalars[key]; value != "" && alert.Fingerprint == "" {
+			alert.Fingerprint = value
+		}
+	}
+
+	if alert.Labels["alertname"] == "" {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "告警缺少名称",
+			"需要 alertname、name 或 title 字段")
+	}
+	alert.normalize()
+	return alert, nil
+}
+
+// normalize 填充名称、状态与指纹
+func (a *Alert) normalize() {
+	if a.Labels == nil {
+		a.Labels = map[string]string{}
+	}
+	a.Name = a.Labels["alertname"]
+	if a.Name == "" {
+		a.Name = "unknown"
+	}
+	if a.Status != StatusResolved {
+		a.Status = StatusFiring
+	}
+	if a.Fingerprint == "" {
+		a.Fingerprint = fingerprint(a.Labels)
+	}
+}
+
+// fingerprint 由排序后的标签计算告警指纹
+func fingerprint(labels map[string]string) string {
+	hash := sha256.New()
+	for _, key := range sortedKeys(labels) {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(labels[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// Markdown 返回告警详情，作为诊断结果的上下文
+func (a *Alert) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### 告警 %s（%s）\n\n", a.Name, a.Status)
+	if !a.StartsAt.IsZero() {
+		fmt.Fprintf(&b, "- 开始时间: %s\n", a.StartsAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "- 指纹: %s\n", a.Fingerprint)
+	for _, key := range sortedKeys(a.Labels) {
+		if key != "alertname" {
+			fmt.Fprintf(&b, "- %s: %s\n", key, a.Labels[key])
+		}
+	}
+	for _, key := range sortedKeys(a.Annotations) {
+		fmt.Fprintf(&b, "- %s: %s\n", key, a.Annotations[key])
+	}
+	if a.GeneratorURL != "" {
+		fmt.Fprintf(&b, "- 来源: %s\n", a.GeneratorURL)
+	}
+	return b.String()
+}
+
+// merge 合并两个映射，override 中的值优先
+func merge(base, override map[string]string) map[string]string {
+	result := make(map[string]string, len(base)+len(override))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range override {
+		result[key] = value
+	}
+	return result
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contains 判断字符串是否在列表中
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package alert
+
+import (
+	"sync"
+	"time"
+)
+
+// 告警的处理结果
+const (
+	DecisionAccepted    = "accepted"     // 开始诊断
+	DecisionDuplicate   = "duplicate"    // 去重窗口内已诊断过或正在诊断
+	DecisionRateLimited = "rate_limited" // 超过该指纹的诊断次数限制
+	DecisionQueueFull   = "queue_full"   // 等待中的诊断过多
+	DecisionIgnored     = "ignored"      // 已恢复的告警或路由配置为忽略
+)
+
+// Limiter 按告警指纹去重与限流
+type Limiter struct {
+	mu          sync.Mutex
+	dedupWindow time.Duration // 同一指纹与状态在该时间内只诊断一次
+	rateLimit   int           // rateWindow 内同一指纹最多诊断的次数，0 表示不限制
+	rateWindow  time.Duration
+	entries     map[string]*limiterEntry
+}
+
+// limiterEntry 一个指纹的诊断记录
+type limiterEntry struct {
+	last     map[string]time.Time // 按告警状态记录最近一次诊断的时间
+	running  bool
+	accepted []time.Time // rateWindow 内开始的诊断
+}
+
+// NewLimiter 创建去重与限流器
+func NewLimiter(dedupWindow time.Duration, rateLimit int, rateWindow time.Duration) *Limiter {
+	return &Limiter{
+		dedupWindow: dedupWindow,
+		rateLimit:   rateLimit,
+		rateWindow:  rateWindow,
+		entries:     make(map[string]*limiterEntry),
+	}
+}
+
+// Acquire 判断是否诊断该告警，返回 DecisionAccepted 时调用方在诊断结束后调用 Release
+func (l *Limiter) Acquire(alert *Alert, now time.Time) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	entry := l.entries[alert.Fingerprint]
+	if entry == nil {
+		entry = &limiterEntry{last: make(map[string]time.Time)}
+		l.entries[alert.Fingerprint] = entry
+	}
+
+	if entry.running {
+		return DecisionDuplicate
+	}
+	if last, ok := entry.last[alert.Status]; ok && now.Sub(last) < l.dedupWindow {
+		return DecisionDuplicate
+	}
+	if l.rateLimit > 0 && len(entry.accepted) >= l.rateLimit {
+		return DecisionRateLimited
+	}
+
+	entry.running = true
+	entry.last[alert.Status] = now
+	entry.accepted = append(entry.accepted, now)
+	return DecisionAccepted
+}
+
+// Release 标记诊断结束
+func (l *Limiter) Release(alert *Alert) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry := l.entries[alert.Fingerprint]; entry != nil {
+		entry.running = false
+	}
+}
+
+// Cancel 撤销一次已接受但未能开始的诊断，使告警可以重新发送
+func (l *Limiter) Cancel(alert *Alert) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := l.entries[alert.Fingerprint]
+	if entry == nil {
+		return
+	}
+	entry.running = false
+	delete(entry.last, alert.Status)
+	if n := len(entry.accepted); n > 0 {
+		entry.accepted = entry.accepted[:n-1]
+	}
+}
+
+// prune 清理过期的诊断记录，避免长期运行时占用的内存持续增长
+func (l *Limiter) prune(now time.Time) {
+	for fingerprint, entry := range l.entries {
+		kept := entry.accepted[:0]
+		for _, t := range entry.accepted {
+			if now.Sub(t) < l.rateWindow {
+				kept = append(kept, t)
+			}
+		}
+		entry.accepted = kept
+
+		for status, last := range entry.last {
+			if now.Sub(last) >= l.dedupWindow {
+				delete(entry.last, status)
+			}
+		}
+		if !entry.running && len(entry.accepted) == 0 && len(entry.last) == 0 {
+			delete(l.entries, fingerprint)
+		}
+	}
+}
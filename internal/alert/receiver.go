@@ -0,0 +1,337 @@
+package alert
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-ops/internal/task"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未设置时使用的接收参数
+const (
+	defaultMaxConcurrent = 2
+	defaultQueueSize     = 100
+	maxBodyBytes         = 1 << 20
+	shutdownTimeout      = 5 * time.Second
+)
+
+// Options 告警接收器参数
+type Options struct {
+	Token         string        // 请求需携带的 Bearer 令牌，为空时不校验
+	Model         string        // 诊断使用的模型适配器，路由可覆盖
+	Timeout       time.Duration // 诊断超时，路由可覆盖
+	Resolved      bool          // 是否诊断已恢复的告警
+	MaxConcurrent int           // 同时进行的诊断数
+	QueueSize     int           // 等待中的诊断上限，超出的告警不诊断
+}
+
+// Result 一条告警的处理结果，作为 webhook 响应返回
+type Result struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	Status      string `json:"status"`
+	Route       string `json:"route,omitempty"`
+	Decision    string `json:"decision"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Response webhook 响应
+type Response struct {
+	Received int       `json:"received"`
+	Results  []*Result `json:"results"`
+}
+
+// job 排队中的诊断
+type job struct {
+	alert *Alert
+	task  *task.Task
+}
+
+// Receiver 接收告警 webhook，按路由生成诊断任务，使用只读工具在智能体会话中执行
+type Receiver struct {
+	runner   *task.Runner
+	rules    *Rules
+	limiter  *Limiter
+	readOnly []string // 允许诊断使用的只读工具
+	options  Options
+
+	mu       sync.Mutex // 保护 queue 的关闭
+	queue    chan job
+	stopped  atomic.Bool
+	running  atomic.Int32
+	wg       sync.WaitGroup
+	startCtx context.Context
+}
+
+// NewReceiver 创建告警接收器，检查路由配置的工具均为只读工具
+func NewReceiver(runner *task.Runner, toolManager tools.ToolManager, rules *Rules, limiter *Limiter, options Options) (*Receiver, error) {
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = defaultMaxConcurrent
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = defaultQueueSize
+	}
+
+	var readOnly []string
+	if toolManager != nil {
+		for _, tool := range toolManager.GetTools() {
+			if tools.RiskOf(tool) == tools.RiskReadOnly {
+				readOnly = append(readOnly, tool.ID())
+			}
+		}
+	}
+	for _, route := range rules.Routes {
+		for _, name := range route.Tools {
+			if !contains(readOnly, name) {
+				return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid,
+					"告警诊断只能使用只读工具", "路由 "+route.Name+" 的工具 "+name+" 不存在或不是只读工具")
+			}
+		}
+	}
+
+	return &Receiver{
+		runner:   runner,
+		rules:    rules,
+		limiter:  limiter,
+		readOnly: readOnly,
+		options:  options,
+		queue:    make(chan job, options.QueueSize),
+	}, nil
+}
+
+// ReadOnlyTools 返回诊断可以使用的只读工具
+func (r *Receiver) ReadOnlyTools() []string {
+	return r.readOnly
+}
+
+// Handler 返回告警接收的 HTTP 处理器
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /alerts", errors.HTTPMiddleware(errors.HandlerFunc(r.handleAlerts)))
+	mux.Handle("GET /healthz", errors.HTTPMiddleware(errors.HandlerFunc(r.handleHealth)))
+	return mux
+}
+
+// Serve 在 listener 上接收告警，ctx 取消后停止接收，
+// 等待正在执行的诊断结束后返回，排队中的诊断被放弃
+func (r *Receiver) Serve(ctx context.Context, listener net.Listener) error {
+	r.startCtx = ctx
+	for i := 0; i < r.options.MaxConcurrent; i++ {
+		r.wg.Add(1)
+		go r.worker()
+	}
+
+	server := &http.Server{Handler: r.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	util.Infow("告警接收器已启动", map[string]any{
+		"address":        listener.Addr().String(),
+		"routes":         len(r.rules.Routes),
+		"read_only":      strings.Join(r.readOnly, ","),
+		"max_concurrent": r.options.MaxConcurrent,
+	})
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		if err == http.ErrServerClosed {
+			err = nil
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+
+	r.stopped.Store(true)
+	r.mu.Lock()
+	close(r.queue)
+	r.mu.Unlock()
+	r.wg.Wait()
+	util.Info("告警接收器已退出")
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "告警接收器异常退出", err)
+	}
+	return nil
+}
+
+// worker 依次执行排队中的诊断
+func (r *Receiver) worker() {
+	defer r.wg.Done()
+	for job := range r.queue {
+		if r.stopped.Load() {
+			util.Warnw("接收器已停止，放弃排队中的诊断", map[string]any{
+				"alert":       job.alert.Name,
+				"fingerprint": job.alert.Fingerprint,
+			})
+			r.limiter.Cancel(job.alert)
+			continue
+		}
+
+		r.running.Add(1)
+		// 停止接收时正在执行的诊断继续完成
+		r.runner.Run(context.WithoutCancel(r.startCtx), job.task, task.TriggerAlert)
+		r.running.Add(-1)
+		r.limiter.Release(job.alert)
+	}
+}
+
+// handleAlerts 接收 Alertmanager 或通用 JSON 告警，诊断在后台执行
+func (r *Receiver) handleAlerts(w http.ResponseWriter, req *http.Request) error {
+	if err := r.authorize(req); err != nil {
+		return err
+	}
+
+	body, err := readBody(w, req)
+	if err != nil {
+		return err
+	}
+	alerts, err := Parse(body)
+	if err != nil {
+		return err
+	}
+
+	response := Response{Received: len(alerts), Results: make([]*Result, 0, len(alerts))}
+	for _, alert := range alerts {
+		result := r.dispatch(alert)
+		util.Infow("收到告警", map[string]any{
+			"alert":       alert.Name,
+			"status":      alert.Status,
+			"fingerprint": alert.Fingerprint,
+			"route":       result.Route,
+			"decision":    result.Decision,
+		})
+		response.Results = append(response.Results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(response)
+}
+
+// handleHealth 返回接收器状态
+func (r *Receiver) handleHealth(w http.ResponseWriter, req *http.Request) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(map[string]any{
+		"status":  "ok",
+		"running": r.running.Load(),
+		"queued":  len(r.queue),
+	})
+}
+
+// authorize 校验 Bearer 令牌
+func (r *Receiver) authorize(req *http.Request) error {
+	if r.options.Token == "" {
+		return nil
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(r.options.Token)) != 1 {
+		return errors.NewError(errors.ErrCodeUnauthorized, "缺少或错误的访问令牌")
+	}
+	return nil
+}
+
+// readBody 读取请求体，超过上限时返回错误
+func readBody(w http.ResponseWriter, req *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodyBytes))
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "读取请求体失败", err)
+	}
+	return body, nil
+}
+
+// dispatch 按路由、去重与限流决定是否诊断告警，诊断任务进入队列
+func (r *Receiver) dispatch(alert *Alert) *Result {
+	result := &Result{Name: alert.Name, Fingerprint: alert.Fingerprint, Status: alert.Status}
+	if alert.Status == StatusResolved && !r.options.Resolved {
+		result.Decision = DecisionIgnored
+		return result
+	}
+	route := r.rules.Route(alert)
+	result.Route = route.Name
+	if route.Ignore {
+		result.Decision = DecisionIgnored
+		return result
+	}
+
+	result.Decision = r.limiter.Acquire(alert, time.Now())
+	if result.Decision != DecisionAccepted {
+		return result
+	}
+
+	t, err := r.buildTask(route, alert)
+	if err != nil {
+		r.limiter.Cancel(alert)
+		result.Decision = DecisionIgnored
+		result.Error = err.Error()
+		util.Warnw("生成诊断任务失败", map[string]any{"alert": alert.Name, "route": route.Name, "error": err.Error()})
+		return result
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped.Load() {
+		r.limiter.Cancel(alert)
+		result.Decision = DecisionQueueFull
+		return result
+	}
+	select {
+	case r.queue <- job{alert: alert, task: t}:
+	default:
+		r.limiter.Cancel(alert)
+		result.Decision = DecisionQueueFull
+	}
+	return result
+}
+
+// buildTask 生成告警的诊断任务：智能体模式、只读工具、拒绝有风险的工具调用
+func (r *Receiver) buildTask(route *Route, alert *Alert) (*task.Task, error) {
+	prompt, err := route.render(alert)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &task.Task{
+		Name:     "alert:" + alert.Name,
+		Prompt:   prompt,
+		Model:    route.Model,
+		Mode:     "agent",
+		Profile:  route.Profile,
+		Tools:    route.Tools,
+		Approval: "deny",
+		Timeout:  route.Timeout,
+		Outputs:  route.Outputs,
+		Context:  alert.Markdown(),
+	}
+	if t.Model == "" {
+		t.Model = r.options.Model
+	}
+	if len(t.Tools) == 0 {
+		t.Tools = r.readOnly
+	}
+	t.NoTools = len(t.Tools) == 0
+	if t.Timeout == "" && r.options.Timeout > 0 {
+		t.Timeout = r.options.Timeout.String()
+	}
+	if len(t.Outputs) == 0 {
+		t.Outputs = r.rules.Outputs
+	}
+	if err := t.Validate(); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeConfigInvalid, "诊断任务无效", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+
+	"ai-ops/internal/task"
+	"ai-ops/internal/util/errors"
+)
+
+// defaultPrompt 未配置 prompt 的路由使用的诊断提示词
+const defaultPrompt = `收到以下告警，请使用可用的只读工具收集信息，诊断可能的原因，评估影响范围，并给出处理建议。
+不要执行任何变更操作；需要人工执行的命令请在建议中列出。
+
+{{ .Markdown }}`
+
+// Rules 告警路由规则，由一个 TOML 文件定义
+type Rules struct {
+	Routes []*Route `toml:"route"`
+	// Outputs 诊断结果的默认输出目标，路由未配置 output 时使用
+	Outputs []task.Output `toml:"output"`
+
+	Path string `toml:"-"` // 规则文件路径
+}
+
+// Route 将匹配的告警映射到诊断提示词，按配置顺序匹配第一条
+type Route struct {
+	Name    string            `toml:"name"`
+	Match   map[string]string `toml:"match"`    // 标签等于指定值
+	MatchRE map[string]string `toml:"match_re"` // 标签匹配正则表达式（完整匹配）
+	// Prompt 诊断提示词模板（text/template），可使用 .Name、.Status、.Labels、.Annotations、.Markdown
+	Prompt  string        `toml:"prompt"`
+	Profile string        `toml:"profile"` // 系统提示词模板
+	Model   string        `toml:"model"`   // 模型适配器，默认使用 [alerts] model
+	Tools   []string      `toml:"tools"`   // 允许调用的工具，只能是只读工具，默认全部只读工具
+	Timeout string        `toml:"timeout"` // 诊断超时，默认使用 [alerts] timeout
+	Ignore  bool          `toml:"ignore"`  // 匹配的告警不诊断
+	Outputs []task.Output `toml:"output"`  // 覆盖默认的输出目标
+
+	matchRE  map[string]*regexp.Regexp
+	template *template.Template
+}
+
+// DefaultRulesPath 默认的规则文件 ~/.ai-ops/alerts.toml
+func DefaultRulesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ai-ops", "alerts.toml")
+	}
+	return filepath.Join(home, ".ai-ops", "alerts.toml")
+}
+
+// LoadRules 读取并校验规则文件，文件不存在时所有告警使用内置的诊断提示词
+func LoadRules(path string) (*Rules, error) {
+	rules := &Rules{Path: path}
+	meta, err := toml.DecodeFile(path, rules)
+	if err != nil {
+		if os.IsNotExist(err) {
+			rules = &Rules{}
+			return rules, rules.validate()
+		}
+		return nil, errors.WrapError(errors.ErrCodeConfigParseFailed, "解析告警规则失败: "+path, err)
+	}
+	// 拼写错误的字段会让路由悄悄失效，提前报错
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid,
+			"告警规则包含未知字段: "+path, strings.Join(keys, ", "))
+	}
+	if err := rules.validate(); err != nil {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid, "告警规则无效: "+path, err.Error())
+	}
+	return rules, nil
+}
+
+// validate 校验路由并编译正则表达式与提示词模板，末尾追加匹配全部告警的默认路由
+func (r *Rules) validate() error {
+	check := task.Task{Prompt: defaultPrompt, Outputs: r.Outputs}
+	if err := check.Validate(); err != nil {
+		return err
+	}
+	for i, route := range r.Routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route-%d", i+1)
+		}
+		if err := route.compile(); err != nil {
+			return fmt.Errorf("路由 %s: %w", route.Name, err)
+		}
+	}
+	r.Routes = append(r.Routes, &Route{Name: "default"})
+	return r.Routes[len(r.Routes)-1].compile()
+}
+
+// compile 编译路由的正则表达式与提示词模板，并校验任务相关的配置
+func (r *Route) compile() error {
+	r.matchRE = make(map[string]*regexp.Regexp, len(r.MatchRE))
+	for label, pattern := range r.MatchRE {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("标签 %s 的正则表达式无效: %w", label, err)
+		}
+		r.matchRE[label] = re
+	}
+
+	prompt := r.Prompt
+	if strings.TrimSpace(prompt) == "" {
+		prompt = defaultPrompt
+	}
+	tmpl, err := template.New(r.Name).Option("missingkey=zero").Parse(prompt)
+	if err != nil {
+		return fmt.Errorf("提示词模板无效: %w", err)
+	}
+	r.template = tmpl
+
+	// 借助任务校验检查超时与输出目标
+	check := task.Task{Prompt: prompt, Timeout: r.Timeout, Outputs: r.Outputs}
+	if err := check.Validate(); err != nil {
+		return err
+	}
+	r.Outputs = check.Outputs
+	return nil
+}
+
+// Route 返回匹配告警的第一条路由
+func (r *Rules) Route(alert *Alert) *Route {
+	for _, route := range r.Routes {
+		if route.matches(alert) {
+			return route
+		}
+	}
+	return nil
+}
+
+// matches 判断告警是否满足路由的全部匹配条件
+func (r *Route) matches(alert *Alert) bool {
+	for label, value := range r.Match {
+		if alert.Labels[label] != value {
+			return false
+		}
+	}
+	for label, re := range r.matchRE {
+		if !re.MatchString(alert.Labels[label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// render 生成告警的诊断提示词
+func (r *Route) render(alert *Alert) (string, error) {
+	var b strings.Builder
+	if err := r.template.Execute(&b, alert); err != nil {
+		return "", errors.WrapError(errors.ErrCodeConfigInvalid, "生成诊断提示词失败: 路由 "+r.Name, err)
+	}
+	return b.String(), nil
+}
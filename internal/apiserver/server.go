@@ -0,0 +1,432 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未设置时使用的服务参数
+const (
+	defaultTimeout       = 5 * time.Minute
+	defaultMaxConcurrent = 4
+	defaultMaxBodyBytes  = 1 << 20
+	defaultMaxMessages   = 100
+	keepAliveInterval    = 15 * time.Second
+)
+
+// Options API 服务参数，未设置的项使用默认值
+type Options struct {
+	APIKeys       []string      // 允许访问的 API 密钥，为空时不校验
+	DefaultModel  string        // 请求未指定 model 时使用的模型
+	Timeout       time.Duration // 单个请求的超时，包含全部工具调用
+	MaxConcurrent int           // 同时处理的请求数，超出时返回 429
+	MaxBodyBytes  int64         // 请求体上限
+	MaxMessages   int           // 单个请求的消息数上限
+}
+
+// Server 兼容 OpenAI Chat Completions 的 HTTP 服务。
+// 每个请求使用独立的会话，工具调用在服务端执行，客户端只收到最终回答。
+type Server struct {
+	toolManager tools.ToolManager
+	base        chat.SessionConfig
+	options     Options
+	slots       chan struct{} // 并发请求的令牌
+}
+
+// NewServer 创建 API 服务，base 为每个请求会话的公共设置
+func NewServer(toolManager tools.ToolManager, base chat.SessionConfig, options Options) *Server {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = defaultMaxConcurrent
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if options.MaxMessages <= 0 {
+		options.MaxMessages = defaultMaxMessages
+	}
+	return &Server{
+		toolManager: toolManager,
+		base:        base,
+		options:     options,
+		slots:       make(chan struct{}, options.MaxConcurrent),
+	}
+}
+
+// Handler 返回 API 的 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/chat/completions", errors.HTTPMiddleware(errors.HandlerFunc(s.handleChatCompletions)))
+	mux.Handle("GET /v1/models", errors.HTTPMiddleware(errors.HandlerFunc(s.handleModels)))
+	mux.Handle("GET /healthz", errors.HTTPMiddleware(errors.HandlerFunc(s.handleHealth)))
+	return mux
+}
+
+// Serve 在 listener 上提供服务，ctx 取消后停止接收新请求，等待处理中的请求结束后返回
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	util.Infow("API 服务已启动", map[string]any{
+		"address":        listener.Addr().String(),
+		"models":         strings.Join(llm.ListAdapters(), ","),
+		"mode":           s.base.Mode,
+		"max_concurrent": s.options.MaxConcurrent,
+	})
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+	}
+
+	// 处理中的请求最长执行 Timeout，等待时间不超过该值
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.options.Timeout)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	util.Info("API 服务已退出")
+	if err != nil && err != http.ErrServerClosed {
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "API 服务异常退出", err)
+	}
+	return nil
+}
+
+// handleChatCompletions 处理 /v1/chat/completions，支持 SSE 流式响应
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) error {
+	if err := s.authorize(r); err != nil {
+		return err
+	}
+	req, err := s.decodeRequest(w, r)
+	if err != nil {
+		return err
+	}
+	modelName, client, err := s.resolveModel(req.Model)
+	if err != nil {
+		return err
+	}
+	history, system, input, err := convertMessages(req.Messages)
+	if err != nil {
+		return err
+	}
+	if len(req.Tools) > 0 {
+		util.Debugw("忽略客户端定义的工具，使用服务端工具", map[string]any{"model": modelName})
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		return errors.NewErrorWithDetails(errors.ErrCodeRateLimited, "并发请求过多，请稍后重试",
+			fmt.Sprintf("最多同时处理 %d 个请求", s.options.MaxConcurrent))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.options.Timeout)
+	defer cancel()
+
+	// 每个请求使用独立的脱敏映射，否则其他客户端可以让模型复述占位符取回原文
+	config := s.base
+	config.ModelName = modelName
+	config.Redactor = s.base.Redactor.Fresh()
+	history, system = redactRequest(config.Redactor, history, system, input)
+	config.History = history
+	session := chat.NewSession(client, s.toolManager, config)
+	defer session.Close()
+	if system != "" {
+		session.SetSystemPrompt(session.SystemPrompt() + "\n\n" + system)
+	}
+
+	completion := &ChatCompletion{ID: newCompletionID(), Created: time.Now().Unix(), Model: modelName}
+	startTime := time.Now()
+	defer func() {
+		turn := session.LastTurn()
+		util.Infow("API 请求完成", map[string]any{
+			"id":           completion.ID,
+			"model":        modelName,
+			"stream":       req.Stream,
+			"rounds":       turn.Rounds,
+			"tool_calls":   len(turn.ToolCalls),
+			"total_tokens": turn.Usage.TotalTokens,
+			"duration_ms":  time.Since(startTime).Milliseconds(),
+		})
+	}()
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		return s.stream(ctx, w, session, input, completion, includeUsage)
+	}
+
+	answer, err := s.run(ctx, session, input)
+	if err != nil {
+		return err
+	}
+	usage := session.LastTurn().Usage
+	completion.Object = "chat.completion"
+	completion.Choices = []Choice{{
+		Message:      &ResponseMessage{Role: "assistant", Content: answer},
+		FinishReason: finishStop(),
+	}}
+	completion.Usage = &usage
+	return writeJSON(w, completion)
+}
+
+// stream 以 SSE 返回回答。模型调用与工具执行期间发送注释行保持连接，
+// 完成后依次发送角色、内容、结束原因与可选的用量数据块。
+func (s *Server) stream(ctx context.Context, w http.ResponseWriter, session *chat.Session, input string, completion *ChatCompletion, includeUsage bool) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.NewError(errors.ErrCodeInternalErr, "响应不支持流式输出")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	completion.Object = "chat.completion.chunk"
+	send := func(choices []Choice, usage *llm.TokenUsage) {
+		chunk := *completion
+		chunk.Choices = choices
+		chunk.Usage = usage
+		writeEvent(w, chunk)
+		flusher.Flush()
+	}
+	send([]Choice{{Delta: &ResponseMessage{Role: "assistant"}}}, nil)
+
+	type outcome struct {
+		answer string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		answer, err := s.run(ctx, session, input)
+		done <- outcome{answer: answer, err: err}
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	var result outcome
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case result = <-done:
+			break wait
+		}
+	}
+
+	// 响应头已发送，错误作为数据事件返回
+	if result.err != nil {
+		errors.DefaultHandler.HandleError(result.err)
+		_, body := errors.NewErrorResponse(result.err)
+		writeEvent(w, body)
+		flusher.Flush()
+		return nil
+	}
+
+	send([]Choice{{Delta: &ResponseMessage{Content: result.answer}}}, nil)
+	send([]Choice{{Delta: &ResponseMessage{}, FinishReason: finishStop()}}, nil)
+	if includeUsage {
+		usage := session.LastTurn().Usage
+		send([]Choice{}, &usage)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+	return nil
+}
+
+// run 在会话中执行一轮对话，返回不含思考过程的回答
+func (s *Server) run(ctx context.Context, session *chat.Session, input string) (string, error) {
+	var answer string
+	var err error
+	if s.base.Mode == "agent" {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(ctx, input, chat.AgentHooks{})
+		if report != nil {
+			answer = report.Final
+		}
+	} else {
+		answer, err = session.ProcessMessage(ctx, input)
+	}
+	if err != nil {
+		return "", err
+	}
+	return chat.ExtractThinking(answer).Content, nil
+}
+
+// handleModels 返回可用的模型，即 config.toml 中配置的模型名称
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) error {
+	if err := s.authorize(r); err != nil {
+		return err
+	}
+	names := llm.ListAdapters()
+	sort.Strings(names)
+	list := ModelList{Object: "list", Data: make([]Model, 0, len(names))}
+	for _, name := range names {
+		adapter, ok := llm.GetAdapter(name)
+		if !ok {
+			continue
+		}
+		list.Data = append(list.Data, Model{ID: name, Object: "model", OwnedBy: adapter.GetModelInfo().Type})
+	}
+	return writeJSON(w, list)
+}
+
+// handleHealth 返回服务状态
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) error {
+	return writeJSON(w, map[string]any{
+		"status":  "ok",
+		"running": len(s.slots),
+	})
+}
+
+// authorize 校验 Bearer API 密钥
+func (s *Server) authorize(r *http.Request) error {
+	if len(s.options.APIKeys) == 0 {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok {
+		for _, key := range s.options.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+				return nil
+			}
+		}
+	}
+	return errors.NewError(errors.ErrCodeUnauthorized, "缺少或错误的 API 密钥")
+}
+
+// decodeRequest 读取并校验请求体
+func (s *Server) decodeRequest(w http.ResponseWriter, r *http.Request) (*ChatCompletionRequest, error) {
+	var req ChatCompletionRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.options.MaxBodyBytes))
+	if err := decoder.Decode(&req); err != nil {
+		if errors.CodeOf(err) == errors.ErrCodeInvalidParameters {
+			return nil, err
+		}
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析请求 JSON", err)
+	}
+	if len(req.Messages) == 0 {
+		return nil, errors.NewError(errors.ErrCodeInvalidParameters, "messages 不能为空")
+	}
+	if len(req.Messages) > s.options.MaxMessages {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "消息数超过上限",
+			fmt.Sprintf("最多 %d 条", s.options.MaxMessages))
+	}
+	return &req, nil
+}
+
+// resolveModel 按名称查找模型适配器，未指定时使用默认模型
+func (s *Server) resolveModel(name string) (string, llm.ModelAdapter, error) {
+	if name == "" {
+		name = s.options.DefaultModel
+	}
+	client, ok := llm.GetAdapter(name)
+	if !ok {
+		return "", nil, errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在",
+			name+"（可用模型见 /v1/models）")
+	}
+	return name, client, nil
+}
+
+// convertMessages 将请求消息转换为会话历史：system 与 developer 消息合并为附加的系统提示词，
+// 最后一条消息必须是用户消息，作为本轮输入。工具在服务端执行，客户端的工具结果消息被忽略。
+func convertMessages(messages []ChatMessage) (history []llm.Message, system, input string, err error) {
+	var systems []string
+	last := len(messages) - 1
+	for i, msg := range messages {
+		content := strings.TrimSpace(string(msg.Content))
+		switch msg.Role {
+		case "system", "developer":
+			if content != "" {
+				systems = append(systems, content)
+			}
+		case "user":
+			if i == last {
+				input = content
+			} else if content != "" {
+				history = append(history, llm.Message{Role: "user", Content: content})
+			}
+		case "assistant":
+			if content != "" {
+				history = append(history, llm.Message{Role: "assistant", Content: content})
+			}
+		case "tool", "function":
+		default:
+			return nil, "", "", errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+				"不支持的消息角色", msg.Role)
+		}
+	}
+	if messages[last].Role != "user" || input == "" {
+		return nil, "", "", errors.NewError(errors.ErrCodeInvalidParameters, "最后一条消息必须是非空的用户消息")
+	}
+	return history, strings.Join(systems, "\n\n"), input, nil
+}
+
+// redactRequest 脱敏客户端提供的历史消息与系统提示词，最后一条用户消息 input 由会话脱敏。
+// 先登记请求中已有的占位符，避免新分配的占位符与客户端发送的占位符同名
+func redactRequest(redactor *redact.Redactor, history []llm.Message, system, input string) ([]llm.Message, string) {
+	if redactor == nil {
+		return history, system
+	}
+	redactor.Reserve(system)
+	redactor.Reserve(input)
+	for _, msg := range history {
+		redactor.Reserve(msg.Content)
+	}
+	system, _ = redactor.Redact(system)
+	for i := range history {
+		history[i].Content, _ = redactor.Redact(history[i].Content)
+	}
+	return history, system
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeEvent 写入一个 SSE 数据事件
+func writeEvent(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		util.Warnw("序列化流式响应失败", map[string]any{"error": err.Error()})
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// newCompletionID 生成响应ID
+func newCompletionID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "chatcmpl-" + hex.EncodeToString(buf)
+}
+
+// finishStop 返回结束原因 stop
+func finishStop() *string {
+	reason := "stop"
+	return &reason
+}
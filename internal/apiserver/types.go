@@ -0,0 +1,101 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"strings"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util/errors"
+)
+
+// ChatCompletionRequest OpenAI Chat Completions 请求中支持的字段，其余字段忽略
+type ChatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	// Tools 客户端定义的工具。工具在服务端执行，客户端工具不会提供给模型
+	Tools json.RawMessage `json:"tools,omitempty"`
+}
+
+// StreamOptions 流式响应选项
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatMessage 请求中的一条消息
+type ChatMessage struct {
+	Role    string         `json:"role"`
+	Content MessageContent `json:"content"`
+}
+
+// MessageContent 消息内容，可以是字符串或内容片段数组，只支持文本片段
+type MessageContent string
+
+// UnmarshalJSON 解析字符串或 [{"type": "text", "text": "..."}] 形式的内容
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = MessageContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		// null 等其他内容按空内容处理，如只包含工具调用的助手消息
+		*c = ""
+		return nil
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+				"不支持的消息内容类型", part.Type+"（只支持 text）")
+		}
+		texts = append(texts, part.Text)
+	}
+	*c = MessageContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// ChatCompletion 非流式响应，object 为 chat.completion；
+// 流式响应的每个数据块使用同一结构，object 为 chat.completion.chunk
+type ChatCompletion struct {
+	ID      string          `json:"id"`
+	Object  string          `json:"object"`
+	Created int64           `json:"created"`
+	Model   string          `json:"model"`
+	Choices []Choice        `json:"choices"`
+	Usage   *llm.TokenUsage `json:"usage,omitempty"`
+}
+
+// Choice 响应中的一个候选回答
+type Choice struct {
+	Index        int              `json:"index"`
+	Message      *ResponseMessage `json:"message,omitempty"`
+	Delta        *ResponseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+// ResponseMessage 响应中的助手消息或流式增量
+type ResponseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Model /v1/models 中的一个模型，id 为 config.toml 中的模型名称
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// ModelList /v1/models 响应
+type ModelList struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+}
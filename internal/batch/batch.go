@@ -0,0 +1,164 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 执行结果状态
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Item 输入文件中的一条待执行提示词
+type Item struct {
+	ID     string // 条目标识，未配置时使用行号
+	Line   int    // 所在行号
+	Prompt string
+}
+
+// inputRecord 输入文件中的一行，也可以直接是 JSON 字符串
+type inputRecord struct {
+	ID     any    `json:"id"`
+	Prompt string `json:"prompt"`
+}
+
+// ErrorInfo 执行失败的原因
+type ErrorInfo struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result 一条提示词的执行结果，写入输出文件的一行
+type Result struct {
+	ID         string               `json:"id"`
+	Line       int                  `json:"line"`
+	Status     string               `json:"status"`
+	Adapter    string               `json:"adapter"`
+	Model      string               `json:"model"`
+	Answer     string               `json:"answer,omitempty"`
+	Thinking   string               `json:"thinking,omitempty"`
+	Agent      *chat.AgentReport    `json:"agent,omitempty"` // 智能体模式的执行报告
+	Rounds     int                  `json:"rounds"`
+	ToolCalls  []chat.ToolCallTrace `json:"tool_calls"`
+	Usage      llm.TokenUsage       `json:"usage"`
+	Attempts   int                  `json:"attempts"`
+	DurationMs int64                `json:"duration_ms"`
+	FinishedAt time.Time            `json:"finished_at"`
+	Error      *ErrorInfo           `json:"error,omitempty"`
+}
+
+// ReadItems 读取 JSONL 输入文件，每行为 {"id": ..., "prompt": ...} 或 JSON 字符串，跳过空行
+func ReadItems(path string) ([]Item, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "输入文件不存在", path)
+		}
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "打开输入文件失败", err)
+	}
+	defer file.Close()
+
+	var items []Item
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		item, err := parseItem(text, line)
+		if err != nil {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+				fmt.Sprintf("输入文件第 %d 行无效", line), err.Error())
+		}
+		if previous, exists := seen[item.ID]; exists {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+				fmt.Sprintf("输入文件第 %d 行的 id 与第 %d 行重复", line, previous), item.ID)
+		}
+		seen[item.ID] = line
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "读取输入文件失败", err)
+	}
+	return items, nil
+}
+
+// parseItem 解析输入文件中的一行
+func parseItem(text string, line int) (Item, error) {
+	item := Item{ID: fmt.Sprint(line), Line: line}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal([]byte(text), &item.Prompt); err != nil {
+			return item, err
+		}
+	} else {
+		var record inputRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return item, err
+		}
+		item.Prompt = record.Prompt
+		if record.ID != nil {
+			item.ID = fmt.Sprint(record.ID)
+		}
+	}
+
+	if strings.TrimSpace(item.Prompt) == "" {
+		return item, fmt.Errorf("prompt 不能为空")
+	}
+	return item, nil
+}
+
+// LoadCompleted 读取已有的输出文件，返回已有结果的条目 ID。
+// successOnly 为 true 时只返回执行成功的条目，失败的条目会重新执行。
+func LoadCompleted(path string, successOnly bool) (map[string]bool, error) {
+	completed := make(map[string]bool)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return completed, nil
+		}
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "打开输出文件失败", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var result Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			// 进程异常退出可能留下不完整的最后一行，对应的条目重新执行
+			util.Debugw("忽略无法解析的输出记录", map[string]any{"file": path, "error": err.Error()})
+			continue
+		}
+		// 同一条目可能因重新执行失败条目而有多条结果，以最后一条为准
+		completed[result.ID] = !successOnly || result.Status == StatusSuccess
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "读取输出文件失败", err)
+	}
+
+	for id, done := range completed {
+		if !done {
+			delete(completed, id)
+		}
+	}
+	return completed, nil
+}
@@ -0,0 +1,248 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未设置时使用的执行参数
+const (
+	defaultConcurrency = 4
+	defaultTimeout     = 2 * time.Minute
+	defaultRetryDelay  = 2 * time.Second
+	maxRetryDelay      = 30 * time.Second
+)
+
+// Options 批量执行参数，未设置的项使用默认值
+type Options struct {
+	Concurrency int           // 同时执行的条目数
+	Timeout     time.Duration // 每次尝试的超时时间
+	Retries     int           // 网络、限流、超时等可恢复错误的重试次数
+	RetryDelay  time.Duration // 首次重试前的等待时间，之后每次加倍
+	// Instruction 附加在每条提示词之前的公共指令（空表示不附加）
+	Instruction string
+	// OnResult 每条结果写入后调用，用于显示进度
+	OnResult func(done, total int, result *Result)
+}
+
+// Summary 批量执行的统计
+type Summary struct {
+	Total     int // 本次执行的条目数（不含跳过的条目）
+	Succeeded int
+	Failed    int
+	Usage     llm.TokenUsage
+}
+
+// Runner 为每条提示词创建独立的会话并发执行，结果逐行写入输出
+type Runner struct {
+	client      llm.ModelAdapter
+	toolManager tools.ToolManager
+	base        chat.SessionConfig
+	options     Options
+
+	mu      sync.Mutex // 保护输出与统计
+	out     io.Writer
+	summary Summary
+	done    int
+}
+
+// NewRunner 创建批量执行器，base 为每个会话的公共设置
+func NewRunner(client llm.ModelAdapter, toolManager tools.ToolManager, base chat.SessionConfig, options Options) *Runner {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = defaultRetryDelay
+	}
+	return &Runner{client: client, toolManager: toolManager, base: base, options: options}
+}
+
+// Run 执行全部条目并将结果写入 out。ctx 取消后不再开始新的条目，
+// 被中断的条目不写入结果，重新执行时会继续处理。
+func (r *Runner) Run(ctx context.Context, items []Item, out io.Writer) Summary {
+	r.out = out
+	r.summary = Summary{Total: len(items)}
+	r.done = 0
+
+	queue := make(chan Item)
+	var wg sync.WaitGroup
+	for i := 0; i < r.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if result := r.process(ctx, item); result != nil {
+					r.write(result)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, item := range items {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queue <- item:
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return r.summary
+}
+
+// process 执行一条提示词，可恢复的错误按退避时间重试。ctx 取消导致的失败返回 nil。
+func (r *Runner) process(ctx context.Context, item Item) *Result {
+	prompt := item.Prompt
+	if r.options.Instruction != "" {
+		prompt = r.options.Instruction + "\n\n" + item.Prompt
+	}
+
+	startTime := time.Now()
+	delay := r.options.RetryDelay
+	var result *Result
+	for attempt := 1; ; attempt++ {
+		var err error
+		result, err = r.attempt(ctx, item, prompt)
+		result.Attempts = attempt
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		result.Status = StatusFailed
+		result.Error = &ErrorInfo{Code: errors.CodeOf(err), Message: err.Error()}
+		if attempt > r.options.Retries || !retryable(err) {
+			break
+		}
+
+		util.Warnw("批量条目执行失败，准备重试", map[string]any{
+			"id":      item.ID,
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	result.DurationMs = time.Since(startTime).Milliseconds()
+	result.FinishedAt = time.Now()
+	return result
+}
+
+// attempt 使用新的会话执行一次提示词
+func (r *Runner) attempt(ctx context.Context, item Item, prompt string) (*Result, error) {
+	result := &Result{
+		ID:        item.ID,
+		Line:      item.Line,
+		Status:    StatusSuccess,
+		Adapter:   r.base.ModelName,
+		Model:     r.client.GetModelInfo().Name,
+		ToolCalls: []chat.ToolCallTrace{},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+	defer cancel()
+
+	// 每条输入使用独立的脱敏映射，其他输入中的原文不会被还原到本条的回答中
+	config := r.base
+	config.Redactor = r.base.Redactor.Fresh()
+	session := chat.NewSession(r.client, r.toolManager, config)
+	defer session.Close()
+
+	var answer string
+	var err error
+	if r.base.Mode == "agent" {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(ctx, prompt, chat.AgentHooks{})
+		if report != nil {
+			result.Agent = report
+			answer = report.Final
+		}
+	} else {
+		answer, err = session.ProcessMessage(ctx, prompt)
+	}
+
+	turn := session.LastTurn()
+	result.Rounds = turn.Rounds
+	result.ToolCalls = turn.ToolCalls
+	result.Usage = turn.Usage
+	thinking := chat.ExtractThinking(answer)
+	result.Answer = thinking.Content
+	result.Thinking = thinking.Thinking
+	return result, err
+}
+
+// write 将结果写入输出的一行并更新统计
+func (r *Runner) write(result *Result) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		util.Warnw("序列化批量结果失败", map[string]any{"id": result.ID, "error": err.Error()})
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.out.Write(append(data, '\n')); err != nil {
+		util.Errorw("写入批量结果失败", map[string]any{"id": result.ID, "error": err.Error()})
+	}
+	r.done++
+	if result.Status == StatusSuccess {
+		r.summary.Succeeded++
+	} else {
+		r.summary.Failed++
+	}
+	r.summary.Usage.PromptTokens += result.Usage.PromptTokens
+	r.summary.Usage.CompletionTokens += result.Usage.CompletionTokens
+	r.summary.Usage.TotalTokens += result.Usage.TotalTokens
+	if r.options.OnResult != nil {
+		r.options.OnResult(r.done, r.summary.Total, result)
+	}
+}
+
+// retryable 判断错误是否可以通过重试恢复
+func retryable(err error) bool {
+	switch errors.CodeOf(err) {
+	case errors.ErrCodeNetworkFailed, errors.ErrCodeTimeout, errors.ErrCodeRateLimited,
+		errors.ErrCodeServiceUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenOutput 以追加方式打开输出文件，上次异常退出留下不完整的最后一行时先补全换行
+func OpenOutput(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "打开输出文件失败", err)
+	}
+
+	info, err := file.Stat()
+	if err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, _ = file.Write([]byte("\n"))
+		}
+	}
+	return file, nil
+}
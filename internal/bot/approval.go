@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/util"
+)
+
+// 确认请求中参数展示的最大字符数
+const maxApprovalArgsRunes = 1500
+
+// pendingApproval 等待用户点击按钮的确认请求
+type pendingApproval struct {
+	id       string
+	platform string
+	target   Target
+	toolName string
+	reply    chan chat.ApprovalResponse
+}
+
+// approver 在对话中发送确认按钮，等待白名单中的用户批准或拒绝
+type approver struct {
+	bot  *Bot
+	conv *conversation
+}
+
+// Approve 发送确认请求并等待结果，超时按拒绝处理
+func (a *approver) Approve(ctx context.Context, req chat.ApprovalRequest) (chat.ApprovalResponse, error) {
+	b := a.bot
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return chat.ApprovalResponse{Decision: chat.ApprovalDenied, Reason: "机器人正在退出"}, nil
+	}
+	pending := &pendingApproval{
+		id:       newApprovalID(),
+		platform: a.conv.platform.Name(),
+		target:   a.conv.target,
+		toolName: req.ToolName,
+		reply:    make(chan chat.ApprovalResponse, 1),
+	}
+	b.approvals[pending.id] = pending
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.approvals, pending.id)
+		b.mu.Unlock()
+	}()
+
+	sendCtx, cancel := context.WithTimeout(ctx, replyTimeout)
+	err := a.conv.platform.AskApproval(sendCtx, pending.target, Approval{ID: pending.id, Text: formatApproval(req, pending.id)})
+	cancel()
+	if err != nil {
+		util.Warnw("发送确认请求失败", map[string]any{
+			"platform":  pending.platform,
+			"tool_name": req.ToolName,
+			"error":     err.Error(),
+		})
+		return chat.ApprovalResponse{Decision: chat.ApprovalDenied, Reason: "无法在对话中发送确认请求"}, nil
+	}
+	util.Infow("等待确认工具调用", map[string]any{
+		"platform":     pending.platform,
+		"conversation": a.conv.key,
+		"approval":     pending.id,
+		"tool_name":    req.ToolName,
+	})
+
+	timer := time.NewTimer(b.options.ApprovalTimeout)
+	defer timer.Stop()
+	select {
+	case response := <-pending.reply:
+		return response, nil
+	case <-timer.C:
+		b.reply(a.conv.platform, pending.target, fmt.Sprintf("⌛ 确认超时，已拒绝执行 %s（确认ID `%s`）", req.ToolName, pending.id))
+		return chat.ApprovalResponse{Decision: chat.ApprovalDenied, Reason: "等待确认超时"}, nil
+	case <-ctx.Done():
+		return chat.ApprovalResponse{}, ctx.Err()
+	}
+}
+
+// resolveApproval 处理按钮或文本命令的确认结果，只能确认同一平台、同一对话中的请求
+func (b *Bot) resolveApproval(entry *platformEntry, event *Event, id string, approve bool, reason string) {
+	b.mu.Lock()
+	pending := b.approvals[id]
+	if pending != nil && pending.platform == entry.Name() && pending.target.Chat == event.Target.Chat {
+		delete(b.approvals, id)
+	} else {
+		pending = nil
+	}
+	b.mu.Unlock()
+	if pending == nil {
+		b.replyAsync(entry, event.Target, fmt.Sprintf("确认请求 `%s` 不存在或已处理", id))
+		return
+	}
+
+	response := chat.ApprovalResponse{Decision: chat.ApprovalApproved}
+	message := fmt.Sprintf("✅ %s 批准执行 %s", event.user(), pending.toolName)
+	if !approve {
+		response = chat.ApprovalResponse{Decision: chat.ApprovalDenied, Reason: reason}
+		message = fmt.Sprintf("🚫 %s 拒绝执行 %s", event.user(), pending.toolName)
+		if reason != "" {
+			message += "，原因: " + reason
+		}
+	}
+	pending.reply <- response
+	util.Infow("工具调用已在 IM 中确认", map[string]any{
+		"platform":  entry.Name(),
+		"approval":  id,
+		"tool_name": pending.toolName,
+		"user":      event.user(),
+		"approved":  approve,
+	})
+	b.replyAsync(entry, pending.target, message)
+}
+
+// formatApproval 生成确认请求的 Markdown 描述
+func formatApproval(req chat.ApprovalRequest, id string) string {
+	args, err := json.MarshalIndent(req.Arguments, "", "  ")
+	if err != nil {
+		args = []byte(fmt.Sprintf("%v", req.Arguments))
+	}
+	title := fmt.Sprintf("模型请求执行%s操作: %s", req.Risk.Label(), req.ToolName)
+	if req.Title != "" {
+		title = req.Title
+	}
+	return fmt.Sprintf("**⚠️ %s**\n参数:\n```json\n%s\n```\n确认ID `%s`，也可以回复 `/approve %s` 或 `/deny %s 原因`",
+		title, util.Ellipsize(string(args), maxApprovalArgsRunes), id, id, id)
+}
+
+// newApprovalID 生成确认请求ID
+func newApprovalID() string {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai-ops/internal/chat"
+)
+
+// fakePlatform 记录发送的回复与确认请求
+type fakePlatform struct {
+	name      string
+	mu        sync.Mutex
+	replies   []string
+	approvals chan Approval
+}
+
+func newFakePlatform(name string) *fakePlatform {
+	return &fakePlatform{name: name, approvals: make(chan Approval, 1)}
+}
+
+func (p *fakePlatform) Name() string { return p.name }
+
+func (p *fakePlatform) Parse(http.Header, []byte) (*Event, any, error) { return nil, nil, nil }
+
+func (p *fakePlatform) Reply(_ context.Context, _ Target, markdown string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replies = append(p.replies, markdown)
+	return nil
+}
+
+func (p *fakePlatform) AskApproval(_ context.Context, _ Target, approval Approval) error {
+	p.approvals <- approval
+	return nil
+}
+
+// replied 判断是否发送过包含 text 的回复
+func (p *fakePlatform) replied(text string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, reply := range p.replies {
+		if strings.Contains(reply, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDispatchRejectsDisallowedUser(t *testing.T) {
+	bot := NewBot(nil, chat.SessionConfig{}, nil, nil, Options{})
+	platform := newFakePlatform("slack")
+	bot.AddPlatform(platform, []string{"U1"})
+	entry := bot.platforms["slack"]
+
+	bot.mu.Lock()
+	bot.approvals["abc"] = &pendingApproval{id: "abc", platform: "slack", target: Target{Chat: "C1"}, reply: make(chan chat.ApprovalResponse, 1)}
+	bot.mu.Unlock()
+
+	bot.dispatch(entry, &Event{Kind: EventAction, Users: []string{"U2"}, Target: Target{Chat: "C1"}, ApprovalID: "abc", Approve: true})
+	bot.wg.Wait()
+	if !platform.replied("没有使用权限") {
+		t.Errorf("白名单以外的用户应收到无权限提示: %q", platform.replies)
+	}
+	if _, ok := bot.approvals["abc"]; !ok {
+		t.Error("白名单以外的用户不应能处理确认请求")
+	}
+}
+
+func TestResolveApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		chat     string
+		command  string
+		want     chat.ApprovalDecision
+		resolved bool
+	}{
+		{name: "同一对话批准", platform: "slack", chat: "C1", command: "/approve", want: chat.ApprovalApproved, resolved: true},
+		{name: "同一对话拒绝", platform: "slack", chat: "C1", command: "/deny", want: chat.ApprovalDenied, resolved: true},
+		{name: "其他对话", platform: "slack", chat: "C2", command: "/approve"},
+		{name: "其他平台", platform: "feishu", chat: "C1", command: "/approve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := NewBot(nil, chat.SessionConfig{}, nil, nil, Options{ApprovalTimeout: 200 * time.Millisecond})
+			slack := newFakePlatform("slack")
+			feishu := newFakePlatform("feishu")
+			bot.AddPlatform(slack, []string{"U1"})
+			bot.AddPlatform(feishu, []string{"U1"})
+			conv := &conversation{key: "slack:C1", platform: bot.platforms["slack"], target: Target{Chat: "C1"}}
+
+			type result struct {
+				response chat.ApprovalResponse
+				err      error
+			}
+			done := make(chan result, 1)
+			go func() {
+				response, err := (&approver{bot: bot, conv: conv}).Approve(context.Background(), chat.ApprovalRequest{ToolName: "execute_command"})
+				done <- result{response, err}
+			}()
+			approval := <-slack.approvals
+
+			sender := bot.platforms[tt.platform]
+			bot.dispatch(sender, &Event{Users: []string{"U1"}, Target: Target{Chat: tt.chat}, Text: tt.command + " " + approval.ID + " 不安全"})
+			got := <-done
+			bot.wg.Wait()
+			if got.err != nil {
+				t.Fatalf("Approve: %v", got.err)
+			}
+			if !tt.resolved {
+				if got.response.Reason != "等待确认超时" {
+					t.Errorf("其他对话的确认不应生效: %+v", got.response)
+				}
+				if sender := bot.platforms[tt.platform].Platform.(*fakePlatform); !sender.replied("不存在或已处理") {
+					t.Errorf("发送者应收到确认请求不存在的提示: %q", sender.replies)
+				}
+				return
+			}
+			if got.response.Decision != tt.want {
+				t.Errorf("Decision = %v, want %v", got.response.Decision, tt.want)
+			}
+			if tt.want == chat.ApprovalDenied && got.response.Reason != "不安全" {
+				t.Errorf("Reason = %q, want 不安全", got.response.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,644 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未设置时使用的机器人参数
+const (
+	defaultTimeout         = 10 * time.Minute
+	defaultApprovalTimeout = 5 * time.Minute
+	defaultIdleTimeout     = time.Hour
+	defaultMaxConcurrent   = 4
+	maxBodyBytes           = 1 << 20
+	maxQueuedMessages      = 5                // 每个对话排队等待处理的消息上限
+	maxReplyRunes          = 8000             // 回复的最大字符数，超出部分截断
+	replyTimeout           = 15 * time.Second // 发送一条回复的超时
+	eventDedupWindow       = 10 * time.Minute // 平台重复投递的事件在该时间内被丢弃
+	shutdownTimeout        = 5 * time.Second
+)
+
+// 事件类型
+const (
+	EventMessage = "message" // 用户发送的消息
+	EventAction  = "action"  // 用户点击了确认按钮
+)
+
+// Event 平台回调中需要处理的事件
+type Event struct {
+	Kind string
+	// ID 消息或事件ID，用于丢弃平台的重复投递，为空时不去重
+	ID string
+	// Users 发送者的用户 ID，同一用户在平台上可能有多种 ID，任一在白名单中即可
+	Users []string
+	// UserName 展示用的用户名称，为空时使用第一个用户 ID
+	UserName string
+	// Conversation 对话标识（群聊中的话题或私聊），每个对话对应一个 ai-ops 会话
+	Conversation string
+	// Followup 只在对话已存在时处理，如 Slack 话题中没有提及机器人的后续消息
+	Followup bool
+	Text     string // 消息文本，已去掉提及机器人的部分
+	Target   Target // 回复的位置
+
+	ApprovalID string // 点击按钮对应的确认请求
+	Approve    bool   // 点击的是否为批准按钮
+}
+
+// user 返回展示用的用户名称
+func (e *Event) user() string {
+	if e.UserName != "" {
+		return e.UserName
+	}
+	if len(e.Users) > 0 {
+		return e.Users[0]
+	}
+	return "未知用户"
+}
+
+// Target 回复消息的位置，各字段的含义由平台决定
+type Target struct {
+	Chat    string // Slack 频道、飞书群聊或钉钉会话
+	Thread  string // Slack 话题的 thread_ts、飞书被回复的消息 ID
+	Webhook string // 钉钉会话的回复地址
+}
+
+// Approval 发送到对话中的确认请求
+type Approval struct {
+	ID   string
+	Text string // Markdown 描述：工具、风险等级与参数
+}
+
+// Platform IM 平台的回调解析与消息发送
+type Platform interface {
+	// Name 平台名称，回调地址为 POST /<name>/events
+	Name() string
+	// Parse 校验回调签名并解析请求。response 非 nil 时作为 HTTP 响应返回（如地址验证的 challenge），
+	// event 为 nil 表示不需要处理的回调
+	Parse(header http.Header, body []byte) (event *Event, response any, err error)
+	// Reply 在对话中回复 Markdown 消息
+	Reply(ctx context.Context, target Target, markdown string) error
+	// AskApproval 在对话中发送带批准、拒绝按钮的确认请求
+	AskApproval(ctx context.Context, target Target, approval Approval) error
+}
+
+// Options 机器人参数，未设置的项使用默认值
+type Options struct {
+	Timeout         time.Duration // 单条消息的处理超时，包含等待确认的时间
+	ApprovalTimeout time.Duration // 等待确认的超时，超时按拒绝处理
+	IdleTimeout     time.Duration // 对话空闲多久后释放会话，之后的消息从会话记录恢复
+	MaxConcurrent   int           // 同时处理的消息数
+}
+
+// platformEntry 已启用的平台及其用户白名单
+type platformEntry struct {
+	Platform
+	allowed map[string]bool
+}
+
+// allows 判断用户是否在白名单中
+func (p *platformEntry) allows(users []string) bool {
+	for _, user := range users {
+		if p.allowed[user] {
+			return true
+		}
+	}
+	return false
+}
+
+// conversation 一个平台对话及其会话，同一对话的消息依次处理
+type conversation struct {
+	key        string
+	platform   *platformEntry
+	target     Target // 正在处理的消息的回复位置
+	queue      []*Event
+	running    bool
+	session    *chat.Session // 只由处理消息的协程使用
+	lastActive time.Time
+}
+
+// Bot IM 机器人：接收平台的事件回调，每个对话对应一个持久化的 ai-ops 会话，
+// 有风险的工具调用在对话中发送确认按钮，只有白名单中的用户可以使用
+type Bot struct {
+	toolManager tools.ToolManager
+	base        chat.SessionConfig
+	store       *chat.SessionStore
+	state       *State
+	options     Options
+	platforms   map[string]*platformEntry
+
+	mu            sync.Mutex
+	conversations map[string]*conversation
+	approvals     map[string]*pendingApproval
+	seen          map[string]time.Time // 已处理的事件ID
+	stopped       bool
+
+	slots chan struct{} // 并发处理的令牌
+	wg    sync.WaitGroup
+}
+
+// NewBot 创建机器人，base 为每个会话的公共设置，会话保存在 store 中，对话与会话的对应关系保存在 state 中
+func NewBot(toolManager tools.ToolManager, base chat.SessionConfig, store *chat.SessionStore, state *State, options Options) *Bot {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.ApprovalTimeout <= 0 {
+		options.ApprovalTimeout = defaultApprovalTimeout
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = defaultIdleTimeout
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = defaultMaxConcurrent
+	}
+	return &Bot{
+		toolManager:   toolManager,
+		base:          base,
+		store:         store,
+		state:         state,
+		options:       options,
+		platforms:     make(map[string]*platformEntry),
+		conversations: make(map[string]*conversation),
+		approvals:     make(map[string]*pendingApproval),
+		seen:          make(map[string]time.Time),
+		slots:         make(chan struct{}, options.MaxConcurrent),
+	}
+}
+
+// AddPlatform 启用平台，只有 allowedUsers 中的用户可以对话和确认工具调用
+func (b *Bot) AddPlatform(platform Platform, allowedUsers []string) {
+	allowed := make(map[string]bool, len(allowedUsers))
+	for _, user := range allowedUsers {
+		allowed[user] = true
+	}
+	b.platforms[platform.Name()] = &platformEntry{Platform: platform, allowed: allowed}
+}
+
+// Platforms 返回已启用的平台名称
+func (b *Bot) Platforms() []string {
+	names := make([]string, 0, len(b.platforms))
+	for name := range b.platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handler 返回接收平台回调的 HTTP 处理器
+func (b *Bot) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for name, entry := range b.platforms {
+		mux.Handle("POST /"+name+"/events", errors.HTTPMiddleware(b.handleEvents(entry)))
+	}
+	mux.Handle("GET /healthz", errors.HTTPMiddleware(errors.HandlerFunc(b.handleHealth)))
+	return mux
+}
+
+// Serve 在 listener 上接收平台回调，ctx 取消后停止接收，
+// 未处理的排队消息被放弃，等待中的确认按拒绝处理，等待正在处理的消息结束后返回
+func (b *Bot) Serve(ctx context.Context, listener net.Listener) error {
+	if len(b.platforms) == 0 {
+		return errors.NewError(errors.ErrCodeConfigInvalid, "没有启用任何 IM 平台")
+	}
+
+	server := &http.Server{Handler: b.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	janitorDone := make(chan struct{})
+	go b.releaseIdle(janitorDone)
+	util.Infow("IM 机器人已启动", map[string]any{
+		"address":        listener.Addr().String(),
+		"platforms":      strings.Join(b.Platforms(), ","),
+		"mode":           b.base.Mode,
+		"max_concurrent": b.options.MaxConcurrent,
+	})
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		if err == http.ErrServerClosed {
+			err = nil
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	close(janitorDone)
+
+	b.mu.Lock()
+	b.stopped = true
+	for id, pending := range b.approvals {
+		pending.reply <- chat.ApprovalResponse{Decision: chat.ApprovalDenied, Reason: "机器人正在退出"}
+		delete(b.approvals, id)
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+
+	b.mu.Lock()
+	for key, conv := range b.conversations {
+		if conv.session != nil {
+			_ = conv.session.Close()
+		}
+		delete(b.conversations, key)
+	}
+	b.mu.Unlock()
+	util.Info("IM 机器人已退出")
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "IM 机器人异常退出", err)
+	}
+	return nil
+}
+
+// handleEvents 处理平台回调，消息在后台处理，回调立即返回
+func (b *Bot) handleEvents(entry *platformEntry) errors.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			return errors.WrapError(errors.ErrCodeInvalidParameters, "读取请求体失败", err)
+		}
+		event, response, err := entry.Parse(r.Header, body)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			b.dispatch(entry, event)
+		}
+		if response == nil {
+			response = map[string]any{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		return json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleHealth 返回服务状态
+func (b *Bot) handleHealth(w http.ResponseWriter, r *http.Request) error {
+	b.mu.Lock()
+	conversations, approvals := len(b.conversations), len(b.approvals)
+	b.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(map[string]any{
+		"status":            "ok",
+		"platforms":         b.Platforms(),
+		"conversations":     conversations,
+		"running":           len(b.slots),
+		"pending_approvals": approvals,
+	})
+}
+
+// dispatch 校验用户并处理事件：按钮与文本命令立即处理，其余消息进入对话的队列
+func (b *Bot) dispatch(entry *platformEntry, event *Event) {
+	if event.Followup && !b.knows(event.Conversation) {
+		return
+	}
+	if b.duplicate(entry.Name(), event.ID) {
+		util.Debugw("丢弃重复投递的事件", map[string]any{"platform": entry.Name(), "id": event.ID})
+		return
+	}
+	if !entry.allows(event.Users) {
+		util.Warnw("拒绝白名单以外的用户", map[string]any{
+			"platform":     entry.Name(),
+			"users":        strings.Join(event.Users, ","),
+			"conversation": event.Conversation,
+		})
+		b.replyAsync(entry, event.Target, fmt.Sprintf("⛔ 你没有使用权限，请联系管理员将你的用户 ID `%s` 加入白名单", strings.Join(event.Users, "` 或 `")))
+		return
+	}
+
+	if event.Kind == EventAction {
+		b.resolveApproval(entry, event, event.ApprovalID, event.Approve, "")
+		return
+	}
+	text := strings.TrimSpace(event.Text)
+	if text == "" {
+		return
+	}
+	command, args, _ := strings.Cut(text, " ")
+	switch command {
+	case "/approve", "/deny":
+		id, reason, _ := strings.Cut(strings.TrimSpace(args), " ")
+		b.resolveApproval(entry, event, id, command == "/approve", strings.TrimSpace(reason))
+	case "/new":
+		b.resetConversation(entry, event)
+	case "/help":
+		b.replyAsync(entry, event.Target, helpText)
+	default:
+		event.Text = text
+		b.enqueue(entry, event)
+	}
+}
+
+// helpText 对话中的命令说明
+const helpText = `**ai-ops 机器人**
+直接发送问题即可，同一话题中的消息共享上下文。
+- ` + "`/new`" + ` 开始新会话，清空当前话题的上下文
+- ` + "`/approve <确认ID>`" + ` 批准执行工具调用
+- ` + "`/deny <确认ID> [原因]`" + ` 拒绝执行工具调用
+- ` + "`/help`" + ` 显示本说明`
+
+// knows 判断对话是否已存在（内存中或对应关系文件中）
+func (b *Bot) knows(key string) bool {
+	b.mu.Lock()
+	_, ok := b.conversations[key]
+	b.mu.Unlock()
+	return ok || b.state.Get(key) != ""
+}
+
+// duplicate 判断事件是否已处理过，同时清理过期的记录
+func (b *Bot) duplicate(platform, id string) bool {
+	if id == "" {
+		return false
+	}
+	key := platform + ":" + id
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for seenKey, at := range b.seen {
+		if now.Sub(at) > eventDedupWindow {
+			delete(b.seen, seenKey)
+		}
+	}
+	if _, ok := b.seen[key]; ok {
+		return true
+	}
+	b.seen[key] = now
+	return false
+}
+
+// enqueue 将消息加入对话的队列，对话没有在处理消息时启动处理协程
+func (b *Bot) enqueue(entry *platformEntry, event *Event) {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	conv := b.conversations[event.Conversation]
+	if conv == nil {
+		conv = &conversation{key: event.Conversation, platform: entry}
+		b.conversations[event.Conversation] = conv
+	}
+	if len(conv.queue) >= maxQueuedMessages {
+		b.mu.Unlock()
+		b.replyAsync(entry, event.Target, "⏳ 前面还有消息在处理，请稍后再发送")
+		return
+	}
+	conv.queue = append(conv.queue, event)
+	conv.lastActive = time.Now()
+	start := !conv.running
+	conv.running = true
+	if start {
+		b.wg.Add(1)
+	}
+	b.mu.Unlock()
+
+	if start {
+		go b.work(conv)
+	}
+}
+
+// work 依次处理对话中排队的消息
+func (b *Bot) work(conv *conversation) {
+	defer b.wg.Done()
+	for {
+		b.mu.Lock()
+		if len(conv.queue) == 0 || b.stopped {
+			conv.queue = nil
+			conv.running = false
+			conv.lastActive = time.Now()
+			b.mu.Unlock()
+			return
+		}
+		event := conv.queue[0]
+		conv.queue = conv.queue[1:]
+		conv.target = event.Target
+		b.mu.Unlock()
+
+		b.process(conv, event)
+	}
+}
+
+// process 在对话的会话中执行一轮对话并回复
+func (b *Bot) process(conv *conversation, event *Event) {
+	b.slots <- struct{}{}
+	defer func() { <-b.slots }()
+
+	startTime := time.Now()
+	session, err := b.session(conv, event)
+	if err != nil {
+		util.Errorw("创建会话失败", map[string]any{"conversation": conv.key, "error": err.Error()})
+		b.reply(conv.platform, event.Target, "❌ "+errorMessage(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.options.Timeout)
+	defer cancel()
+	answer, err := b.run(ctx, session, event.Text)
+	var reply strings.Builder
+	for _, notice := range session.TakeNotices() {
+		reply.WriteString("> ⚠️ " + notice + "\n\n")
+	}
+	switch {
+	case err != nil && ctx.Err() == context.DeadlineExceeded:
+		reply.WriteString(fmt.Sprintf("⌛ 处理超时（超过 %s），请缩小问题范围后重试", b.options.Timeout))
+	case err != nil:
+		reply.WriteString("❌ " + errorMessage(err))
+	default:
+		reply.WriteString(answer)
+	}
+	b.reply(conv.platform, event.Target, truncateReply(reply.String(), session.ID()))
+
+	turn := session.LastTurn()
+	fields := map[string]any{
+		"platform":     conv.platform.Name(),
+		"conversation": conv.key,
+		"session":      session.ID(),
+		"user":         event.user(),
+		"rounds":       turn.Rounds,
+		"tool_calls":   len(turn.ToolCalls),
+		"total_tokens": turn.Usage.TotalTokens,
+		"duration_ms":  time.Since(startTime).Milliseconds(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		util.Warnw("IM 消息处理失败", fields)
+		return
+	}
+	util.Infow("IM 消息处理完成", fields)
+}
+
+// session 返回对话的会话：内存中没有时按对应关系从会话记录恢复，没有记录时创建新会话
+func (b *Bot) session(conv *conversation, event *Event) (*chat.Session, error) {
+	if conv.session != nil {
+		return conv.session, nil
+	}
+	client, ok := llm.GetAdapter(b.base.ModelName)
+	if !ok {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", b.base.ModelName)
+	}
+
+	// 每个对话使用独立的脱敏映射，否则其他对话中的用户可以让模型复述占位符取回原文；
+	// 恢复的会话由 NewSession 登记历史中的占位符，新的敏感信息从其后编号
+	config := b.base
+	config.Store = b.store
+	config.Approver = &approver{bot: b, conv: conv}
+	config.Redactor = b.base.Redactor.Fresh()
+	if id := b.state.Get(conv.key); id != "" {
+		transcript, err := b.store.Load(id)
+		if err != nil {
+			util.Warnw("会话记录不可用，开始新会话", map[string]any{
+				"conversation": conv.key,
+				"session":      id,
+				"error":        err.Error(),
+			})
+		} else {
+			config.Resume = transcript
+		}
+	}
+
+	session := chat.NewSession(client, b.toolManager, config)
+	if config.Resume == nil {
+		// 立即创建会话文件，便于通过 ai-ops sessions 查看
+		if _, err := session.Save(conv.platform.Name() + ": " + util.OneLine(event.Text, 40)); err != nil {
+			util.Warnw("保存会话失败", map[string]any{"session": session.ID(), "error": err.Error()})
+		}
+		if err := b.state.Set(conv.key, session.ID()); err != nil {
+			util.Warnw("保存对话与会话的对应关系失败", map[string]any{"conversation": conv.key, "error": err.Error()})
+		}
+	}
+	util.Infow("对话会话已就绪", map[string]any{
+		"conversation": conv.key,
+		"session":      session.ID(),
+		"resumed":      config.Resume != nil,
+	})
+	conv.session = session
+	return session, nil
+}
+
+// run 执行一轮对话，返回不含思考过程的回答
+func (b *Bot) run(ctx context.Context, session *chat.Session, input string) (string, error) {
+	var answer string
+	var err error
+	if b.base.Mode == "agent" {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(ctx, input, chat.AgentHooks{})
+		if report != nil {
+			answer = report.Final
+			if answer == "" {
+				answer = report.Markdown()
+			}
+		}
+	} else {
+		answer, err = session.ProcessMessage(ctx, input)
+	}
+	if err != nil {
+		return "", err
+	}
+	return chat.ExtractThinking(answer).Content, nil
+}
+
+// resetConversation 结束对话当前的会话，下一条消息开始新会话
+func (b *Bot) resetConversation(entry *platformEntry, event *Event) {
+	b.mu.Lock()
+	conv := b.conversations[event.Conversation]
+	if conv != nil && conv.running {
+		b.mu.Unlock()
+		b.replyAsync(entry, event.Target, "⏳ 正在处理消息，请稍后再开始新会话")
+		return
+	}
+	if conv != nil {
+		if conv.session != nil {
+			_ = conv.session.Close()
+		}
+		delete(b.conversations, event.Conversation)
+	}
+	b.mu.Unlock()
+
+	if err := b.state.Delete(event.Conversation); err != nil {
+		util.Warnw("保存对话与会话的对应关系失败", map[string]any{"conversation": event.Conversation, "error": err.Error()})
+	}
+	b.replyAsync(entry, event.Target, "🆕 已开始新会话，之前的上下文不再使用")
+}
+
+// releaseIdle 定期释放空闲对话的会话，会话记录与对应关系保留
+func (b *Bot) releaseIdle(done <-chan struct{}) {
+	interval := min(b.options.IdleTimeout/2, time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			b.mu.Lock()
+			for key, conv := range b.conversations {
+				if conv.running || now.Sub(conv.lastActive) < b.options.IdleTimeout {
+					continue
+				}
+				if conv.session != nil {
+					_ = conv.session.Close()
+				}
+				delete(b.conversations, key)
+				util.Debugw("释放空闲对话的会话", map[string]any{"conversation": key})
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// reply 发送回复，失败时记录日志
+func (b *Bot) reply(platform *platformEntry, target Target, markdown string) {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+	if err := platform.Reply(ctx, target, markdown); err != nil {
+		util.Warnw("发送 IM 回复失败", map[string]any{"platform": platform.Name(), "error": err.Error()})
+	}
+}
+
+// replyAsync 在后台发送回复，用于回调处理中需要立即返回的场景
+func (b *Bot) replyAsync(platform *platformEntry, target Target, markdown string) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.reply(platform, target, markdown)
+	}()
+}
+
+// errorMessage 返回展示给用户的错误消息，服务端错误不包含内部细节
+func errorMessage(err error) string {
+	_, body := errors.NewErrorResponse(err)
+	if body.Error == nil || body.Error.Message == "" {
+		return "处理失败"
+	}
+	message := body.Error.Message
+	if body.Error.Details != "" {
+		message += ": " + body.Error.Details
+	}
+	return message
+}
+
+// truncateReply 截断过长的回复，提示完整内容所在的会话
+func truncateReply(text, sessionID string) string {
+	short, truncated := util.TruncateRunes(text, maxReplyRunes)
+	if !truncated {
+		return text
+	}
+	return short + fmt.Sprintf("\n\n…（回复过长已截断，完整内容可用 `ai-ops sessions show %s` 查看）", sessionID)
+}
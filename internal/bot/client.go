@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-ops/internal/util/errors"
+)
+
+// 读取的响应体上限
+const maxResponseBytes = 64 * 1024
+
+// postJSON 以 POST 发送 JSON，非 2xx 状态码返回错误，result 非 nil 时解析响应体
+func postJSON(ctx context.Context, target string, headers map[string]string, payload, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "序列化消息失败", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeConfigInvalid, "创建请求失败", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "IM 平台请求失败", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode >= 300 {
+		return errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed,
+			fmt.Sprintf("IM 平台返回状态码 %d", resp.StatusCode), strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return errors.WrapErrorWithDetails(errors.ErrCodeInvalidResponse, "无法解析 IM 平台的响应", err, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// signatureEqual 以固定时间比较签名
+func signatureEqual(expected, actual string) bool {
+	return actual != "" && hmac.Equal([]byte(expected), []byte(actual))
+}
+
+// decodeEvent 解析回调的 JSON 请求体
+func decodeEvent(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析回调 JSON", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/notify"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 回调时间戳与本机时间的最大偏差。签名只覆盖时间戳，不覆盖请求体，偏差越小截获的签名可重放的时间越短
+const dingTalkMaxClockSkew = 5 * time.Minute
+
+// sessionWebhook 允许的地址，回复发送到请求体中的地址，必须限制为钉钉的服务器，
+// 否则截获签名的人可以让机器人把工具结果发送到任意地址
+var dingTalkWebhookHosts = map[string]bool{
+	"oapi.dingtalk.com": true,
+	"api.dingtalk.com":  true,
+}
+
+// DingTalk 企业内部应用机器人，以 HTTP 方式接收消息，通过消息中的 sessionWebhook 回复。
+// 钉钉没有话题，每个单聊或群聊对应一个对话；确认按钮通过 dtmd 链接以用户身份发送确认命令。
+//
+// 钉钉的签名只覆盖时间戳，请求体中的发送者 ID 没有经过认证：截获一次请求的人可以换上任意请求体。
+// 每个签名只接受一次，但截获后抢先发送的请求仍会被当作白名单用户的消息与确认命令处理。
+type DingTalk struct {
+	appSecret string
+
+	mu        sync.Mutex
+	usedSigns map[string]time.Time // 已接受的签名及其时间戳，超出时间偏差后清理
+}
+
+// NewDingTalk 创建钉钉平台，签名密钥为 app_secret，不允许为空
+func NewDingTalk(appSecret string) (*DingTalk, error) {
+	if appSecret == "" {
+		return nil, errors.NewError(errors.ErrCodeConfigInvalid, "dingtalk 的 app_secret 不能为空（检查引用的环境变量是否已设置）")
+	}
+	return &DingTalk{appSecret: appSecret, usedSigns: make(map[string]time.Time)}, nil
+}
+
+func (d *DingTalk) Name() string { return "dingtalk" }
+
+// Parse 校验签名后解析文本消息
+func (d *DingTalk) Parse(header http.Header, body []byte) (*Event, any, error) {
+	if err := d.verify(header); err != nil {
+		return nil, nil, err
+	}
+	var msg struct {
+		MsgID   string `json:"msgId"`
+		MsgType string `json:"msgtype"`
+		Text    struct {
+			Content string `json:"content"`
+		} `json:"text"`
+		ConversationID string `json:"conversationId"`
+		SenderStaffID  string `json:"senderStaffId"`
+		SenderID       string `json:"senderId"`
+		SenderNick     string `json:"senderNick"`
+		SessionWebhook string `json:"sessionWebhook"`
+	}
+	if err := decodeEvent(body, &msg); err != nil {
+		return nil, nil, err
+	}
+	if msg.MsgType != "text" || msg.SessionWebhook == "" {
+		return nil, nil, nil
+	}
+	if !dingTalkWebhookAllowed(msg.SessionWebhook) {
+		return nil, nil, errors.NewError(errors.ErrCodeInvalidParameters, "sessionWebhook 不是钉钉的地址")
+	}
+
+	var users []string
+	for _, id := range []string{msg.SenderStaffID, msg.SenderID} {
+		if id != "" {
+			users = append(users, id)
+		}
+	}
+	return &Event{
+		Kind:         EventMessage,
+		ID:           msg.MsgID,
+		Users:        users,
+		UserName:     msg.SenderNick,
+		Conversation: "dingtalk:" + msg.ConversationID,
+		Text:         msg.Text.Content,
+		Target:       Target{Chat: msg.ConversationID, Webhook: msg.SessionWebhook},
+	}, nil, nil
+}
+
+// verify 校验请求头中的签名：Base64(HmacSHA256(app_secret, timestamp + "\n" + app_secret))，时间戳为毫秒。
+// 签名不覆盖请求体，同一签名在时间偏差内只接受一次，防止换上其他请求体重放
+func (d *DingTalk) verify(header http.Header) error {
+	timestamp := header.Get("timestamp")
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.UnixMilli(ms)).Abs() > dingTalkMaxClockSkew {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求时间戳无效或已过期")
+	}
+	mac := hmac.New(sha256.New, []byte(d.appSecret))
+	mac.Write([]byte(timestamp + "\n" + d.appSecret))
+	if !signatureEqual(base64.StdEncoding.EncodeToString(mac.Sum(nil)), header.Get("sign")) {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求签名校验失败")
+	}
+	if !d.useSign(timestamp+"\n"+header.Get("sign"), time.UnixMilli(ms)) {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求签名已使用过")
+	}
+	return nil
+}
+
+// useSign 登记已接受的签名，签名已登记过时返回 false。同时清理超出时间偏差、不会再通过校验的记录
+func (d *DingTalk) useSign(key string, signedAt time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for used, at := range d.usedSigns {
+		if time.Since(at) > dingTalkMaxClockSkew {
+			delete(d.usedSigns, used)
+		}
+	}
+	if _, ok := d.usedSigns[key]; ok {
+		return false
+	}
+	d.usedSigns[key] = signedAt
+	return true
+}
+
+// dingTalkWebhookAllowed 检查 sessionWebhook 是否为钉钉服务器的 https 地址
+func dingTalkWebhookAllowed(webhook string) bool {
+	u, err := url.Parse(webhook)
+	return err == nil && u.Scheme == "https" && u.User == nil && dingTalkWebhookHosts[u.Host]
+}
+
+// Reply 通过 sessionWebhook 回复 Markdown 消息
+func (d *DingTalk) Reply(ctx context.Context, target Target, markdown string) error {
+	return d.send(ctx, target, map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": dingTalkTitle(markdown),
+			"text":  notify.ToDingTalk(markdown),
+		},
+	})
+}
+
+// AskApproval 发送带批准、拒绝按钮的 ActionCard。按钮使用 dtmd 链接，点击后以用户身份发送确认命令；
+// 群聊中机器人只能收到提及它的消息，需要回复 @机器人 /approve <确认ID>
+func (d *DingTalk) AskApproval(ctx context.Context, target Target, approval Approval) error {
+	button := func(label, command string) map[string]string {
+		return map[string]string{
+			"title":     label,
+			"actionURL": "dtmd://dingtalkclient/sendMessage?content=" + url.PathEscape(command+" "+approval.ID),
+		}
+	}
+	return d.send(ctx, target, map[string]any{
+		"msgtype": "actionCard",
+		"actionCard": map[string]any{
+			"title":          "工具调用确认",
+			"text":           notify.ToDingTalk(approval.Text),
+			"btnOrientation": "1",
+			"btns":           []map[string]string{button("批准", "/approve"), button("拒绝", "/deny")},
+		},
+	})
+}
+
+// send 发送消息到会话的回复地址
+func (d *DingTalk) send(ctx context.Context, target Target, payload map[string]any) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := postJSON(ctx, target.Webhook, nil, payload, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "钉钉拒绝了消息",
+			fmt.Sprintf("errcode=%d, errmsg=%s", result.ErrCode, result.ErrMsg))
+	}
+	return nil
+}
+
+// dingTalkTitle 取第一行非空文本作为消息标题（显示在会话列表与通知中）
+func dingTalkTitle(markdown string) string {
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "#>*` ")
+		if line != "" {
+			return util.OneLine(line, 30)
+		}
+	}
+	return "ai-ops"
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"ai-ops/internal/util/errors"
+)
+
+const dingTalkTestWebhook = "https://oapi.dingtalk.com/robot/sendBySession?session=abc"
+
+// dingTalkHeader 生成带签名的钉钉回调请求头
+func dingTalkHeader(secret string, ts time.Time) http.Header {
+	timestamp := strconv.FormatInt(ts.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	header := http.Header{}
+	header.Set("timestamp", timestamp)
+	header.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+// dingTalkMessage 生成钉钉文本消息的请求体
+func dingTalkMessage(staffID, webhook, text string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"msgId":          "msg1",
+		"msgtype":        "text",
+		"text":           map[string]string{"content": text},
+		"conversationId": "cid1",
+		"senderStaffId":  staffID,
+		"sessionWebhook": webhook,
+	})
+	return body
+}
+
+func TestDingTalkVerify(t *testing.T) {
+	const secret = "ding-secret"
+	body := dingTalkMessage("staff1", dingTalkTestWebhook, "磁盘满了")
+	tampered := dingTalkHeader(secret, time.Now())
+	tampered.Set("sign", "x"+tampered.Get("sign"))
+	tests := []struct {
+		name   string
+		header http.Header
+		ok     bool
+	}{
+		{name: "签名正确", header: dingTalkHeader(secret, time.Now()), ok: true},
+		{name: "签名被篡改", header: tampered},
+		{name: "密钥错误", header: dingTalkHeader("wrong", time.Now())},
+		{name: "时间戳过期", header: dingTalkHeader(secret, time.Now().Add(-10*time.Minute))},
+		{name: "时间戳超前", header: dingTalkHeader(secret, time.Now().Add(10*time.Minute))},
+		{name: "缺少签名", header: http.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ding, err := NewDingTalk(secret)
+			if err != nil {
+				t.Fatalf("NewDingTalk: %v", err)
+			}
+			event, _, err := ding.Parse(tt.header, body)
+			if !tt.ok {
+				if !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+					t.Fatalf("Parse() error = %v, want UNAUTHORIZED", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			want := Target{Chat: "cid1", Webhook: dingTalkTestWebhook}
+			if event == nil || event.Text != "磁盘满了" || event.Target != want {
+				t.Errorf("事件解析不正确: %+v", event)
+			}
+		})
+	}
+}
+
+func TestDingTalkReplay(t *testing.T) {
+	const secret = "ding-secret"
+	ding, err := NewDingTalk(secret)
+	if err != nil {
+		t.Fatalf("NewDingTalk: %v", err)
+	}
+	header := dingTalkHeader(secret, time.Now())
+	if _, _, err := ding.Parse(header, dingTalkMessage("staff1", dingTalkTestWebhook, "hi")); err != nil {
+		t.Fatalf("第一次请求应通过: %v", err)
+	}
+	// 签名不覆盖请求体，换上其他发送者与命令重放同一签名
+	forged := dingTalkMessage("admin", dingTalkTestWebhook, "/approve abc123")
+	if _, _, err := ding.Parse(header, forged); !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+		t.Errorf("重放的签名应返回 UNAUTHORIZED: %v", err)
+	}
+	if _, _, err := ding.Parse(dingTalkHeader(secret, time.Now().Add(time.Millisecond)), forged); err != nil {
+		t.Errorf("新的签名应通过: %v", err)
+	}
+}
+
+func TestDingTalkWebhookAllowed(t *testing.T) {
+	tests := []struct {
+		webhook string
+		ok      bool
+	}{
+		{webhook: dingTalkTestWebhook, ok: true},
+		{webhook: "https://api.dingtalk.com/v1.0/robot/x", ok: true},
+		{webhook: "http://oapi.dingtalk.com/robot/sendBySession"},
+		{webhook: "https://evil.example.com/robot"},
+		{webhook: "https://oapi.dingtalk.com.evil.example.com/robot"},
+		{webhook: "https://user@oapi.dingtalk.com/robot"},
+		{webhook: "https://oapi.dingtalk.com:8443/robot"},
+		{webhook: "http://127.0.0.1:8080/robot"},
+		{webhook: "://bad"},
+	}
+	ding, err := NewDingTalk("ding-secret")
+	if err != nil {
+		t.Fatalf("NewDingTalk: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.webhook, func(t *testing.T) {
+			if got := dingTalkWebhookAllowed(tt.webhook); got != tt.ok {
+				t.Errorf("dingTalkWebhookAllowed() = %v, want %v", got, tt.ok)
+			}
+			_, _, err := ding.Parse(dingTalkHeader("ding-secret", time.Now()), dingTalkMessage("staff1", tt.webhook, "hi"))
+			if (err == nil) != tt.ok {
+				t.Errorf("Parse() error = %v, want ok %v", err, tt.ok)
+			}
+			// 同一毫秒内的签名相同，避免被当作重放
+			time.Sleep(2 * time.Millisecond)
+		})
+	}
+}
+
+func TestNewDingTalkRequiresSecret(t *testing.T) {
+	if _, err := NewDingTalk(""); err == nil {
+		t.Error("app_secret 为空时应报错")
+	}
+}
@@ -0,0 +1,348 @@
+package bot
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/notify"
+	"ai-ops/internal/util/errors"
+)
+
+// 飞书开放平台参数
+const (
+	feishuAPIBase        = "https://open.feishu.cn"
+	feishuDecisionAllow  = "approve"
+	feishuDecisionReject = "deny"
+	feishuTokenMargin    = 5 * time.Minute // tenant_access_token 提前刷新的时间
+	feishuMaxClockSkew   = 5 * time.Minute // 请求时间戳与本机时间的最大偏差，超出视为重放
+)
+
+// 消息文本中的提及占位符，如 @_user_1
+var feishuMentionPattern = regexp.MustCompile(`@_user_\d+`)
+
+// Feishu 通过事件订阅（v2）接收消息与卡片按钮回调，以消息卡片回复到消息所在的话题
+type Feishu struct {
+	appID             string
+	appSecret         string
+	verificationToken string
+	encryptKey        string
+	apiBase           string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFeishu 创建飞书平台，apiBase 为空时使用 https://open.feishu.cn。
+// verification_token 与 encrypt_key 都为空时无法校验回调，不允许创建
+func NewFeishu(appID, appSecret, verificationToken, encryptKey, apiBase string) (*Feishu, error) {
+	if appID == "" || appSecret == "" {
+		return nil, errors.NewError(errors.ErrCodeConfigInvalid, "feishu 的 app_id 与 app_secret 不能为空（检查引用的环境变量是否已设置）")
+	}
+	if verificationToken == "" && encryptKey == "" {
+		return nil, errors.NewError(errors.ErrCodeConfigInvalid, "feishu 的 verification_token 与 encrypt_key 不能都为空（检查引用的环境变量是否已设置）")
+	}
+	if apiBase == "" {
+		apiBase = feishuAPIBase
+	}
+	return &Feishu{
+		appID:             appID,
+		appSecret:         appSecret,
+		verificationToken: verificationToken,
+		encryptKey:        encryptKey,
+		apiBase:           strings.TrimRight(apiBase, "/"),
+	}, nil
+}
+
+func (f *Feishu) Name() string { return "feishu" }
+
+// Parse 解密并校验事件，处理地址验证、接收消息与卡片按钮回调
+func (f *Feishu) Parse(header http.Header, body []byte) (*Event, any, error) {
+	var encrypted struct {
+		Encrypt string `json:"encrypt"`
+	}
+	if err := decodeEvent(body, &encrypted); err != nil {
+		return nil, nil, err
+	}
+	plain := body
+	if encrypted.Encrypt != "" {
+		if f.encryptKey == "" {
+			return nil, nil, errors.NewError(errors.ErrCodeUnauthorized, "收到加密的事件，但未配置 encrypt_key")
+		}
+		var err error
+		if plain, err = f.decrypt(encrypted.Encrypt); err != nil {
+			return nil, nil, errors.WrapError(errors.ErrCodeUnauthorized, "解密事件失败", err)
+		}
+	}
+
+	var envelope struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Token     string `json:"token"`
+		Header    struct {
+			EventType string `json:"event_type"`
+			Token     string `json:"token"`
+		} `json:"header"`
+		Event json.RawMessage `json:"event"`
+	}
+	if err := decodeEvent(plain, &envelope); err != nil {
+		return nil, nil, err
+	}
+	if envelope.Type == "url_verification" {
+		if f.verificationToken != "" && !signatureEqual(f.verificationToken, envelope.Token) {
+			return nil, nil, errors.NewError(errors.ErrCodeUnauthorized, "Verification Token 校验失败")
+		}
+		return nil, map[string]string{"challenge": envelope.Challenge}, nil
+	}
+
+	if f.encryptKey != "" {
+		if err := f.verify(header, body); err != nil {
+			return nil, nil, err
+		}
+	}
+	if f.verificationToken != "" && !signatureEqual(f.verificationToken, envelope.Header.Token) {
+		return nil, nil, errors.NewError(errors.ErrCodeUnauthorized, "Verification Token 校验失败")
+	}
+
+	switch envelope.Header.EventType {
+	case "im.message.receive_v1":
+		return f.parseMessage(envelope.Event)
+	case "card.action.trigger":
+		return f.parseAction(envelope.Event)
+	}
+	return nil, nil, nil
+}
+
+// verify 校验请求签名 sha256(timestamp + nonce + encrypt_key + body) 的十六进制，时间戳为秒。
+// 只有配置了 encrypt_key 时签名覆盖时间戳，仅使用 Verification Token 时无法防止重放
+func (f *Feishu) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Lark-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > feishuMaxClockSkew {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求时间戳无效或已过期")
+	}
+	sum := sha256.Sum256([]byte(timestamp + header.Get("X-Lark-Request-Nonce") + f.encryptKey + string(body)))
+	if !signatureEqual(hex.EncodeToString(sum[:]), header.Get("X-Lark-Signature")) {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求签名校验失败")
+	}
+	return nil
+}
+
+// feishuUserID 飞书用户的各种 ID
+type feishuUserID struct {
+	OpenID  string `json:"open_id"`
+	UserID  string `json:"user_id"`
+	UnionID string `json:"union_id"`
+}
+
+// ids 返回非空的用户 ID
+func (u feishuUserID) ids() []string {
+	var ids []string
+	for _, id := range []string{u.OpenID, u.UserID, u.UnionID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// parseMessage 解析接收消息事件，只处理用户发送的文本消息
+func (f *Feishu) parseMessage(raw json.RawMessage) (*Event, any, error) {
+	var ev struct {
+		Sender struct {
+			SenderID   feishuUserID `json:"sender_id"`
+			SenderType string       `json:"sender_type"`
+		} `json:"sender"`
+		Message struct {
+			MessageID   string `json:"message_id"`
+			RootID      string `json:"root_id"`
+			ChatID      string `json:"chat_id"`
+			MessageType string `json:"message_type"`
+			Content     string `json:"content"`
+		} `json:"message"`
+	}
+	if err := decodeEvent(raw, &ev); err != nil {
+		return nil, nil, err
+	}
+	if ev.Sender.SenderType != "user" || ev.Message.MessageType != "text" {
+		return nil, nil, nil
+	}
+	var content struct {
+		Text string `json:"text"`
+	}
+	if err := decodeEvent([]byte(ev.Message.Content), &content); err != nil {
+		return nil, nil, err
+	}
+
+	// 话题中的消息以话题的第一条消息作为对话标识
+	root := ev.Message.RootID
+	if root == "" {
+		root = ev.Message.MessageID
+	}
+	return &Event{
+		Kind:         EventMessage,
+		ID:           ev.Message.MessageID,
+		Users:        ev.Sender.SenderID.ids(),
+		Conversation: "feishu:" + ev.Message.ChatID + ":" + root,
+		Text:         feishuMentionPattern.ReplaceAllString(content.Text, ""),
+		Target:       Target{Chat: ev.Message.ChatID, Thread: ev.Message.MessageID},
+	}, nil, nil
+}
+
+// parseAction 解析卡片按钮回调，按钮的 value 中包含确认ID与选择
+func (f *Feishu) parseAction(raw json.RawMessage) (*Event, any, error) {
+	var ev struct {
+		Operator feishuUserID `json:"operator"`
+		Action   struct {
+			Value struct {
+				Approval string `json:"approval"`
+				Decision string `json:"decision"`
+			} `json:"value"`
+		} `json:"action"`
+		Context struct {
+			OpenMessageID string `json:"open_message_id"`
+			OpenChatID    string `json:"open_chat_id"`
+		} `json:"context"`
+	}
+	if err := decodeEvent(raw, &ev); err != nil {
+		return nil, nil, err
+	}
+	if ev.Action.Value.Approval == "" {
+		return nil, nil, nil
+	}
+	return &Event{
+		Kind:       EventAction,
+		Users:      ev.Operator.ids(),
+		Target:     Target{Chat: ev.Context.OpenChatID, Thread: ev.Context.OpenMessageID},
+		ApprovalID: ev.Action.Value.Approval,
+		Approve:    ev.Action.Value.Decision == feishuDecisionAllow,
+	}, nil, nil
+}
+
+// decrypt 解密事件：密钥为 encrypt_key 的 SHA256，AES-256-CBC，密文前 16 字节为 IV
+func (f *Feishu) decrypt(encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("密文长度不正确")
+	}
+	key := sha256.Sum256([]byte(f.encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(plain, data[aes.BlockSize:])
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plain) {
+		return nil, fmt.Errorf("填充不正确")
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// Reply 以 Markdown 卡片回复到话题中
+func (f *Feishu) Reply(ctx context.Context, target Target, markdown string) error {
+	return f.replyCard(ctx, target, map[string]any{
+		"config":   map[string]any{"wide_screen_mode": true},
+		"elements": []map[string]any{{"tag": "markdown", "content": notify.ToFeishu(markdown)}},
+	})
+}
+
+// AskApproval 回复带批准、拒绝按钮的卡片
+func (f *Feishu) AskApproval(ctx context.Context, target Target, approval Approval) error {
+	button := func(label, decision, style string) map[string]any {
+		return map[string]any{
+			"tag":   "button",
+			"text":  map[string]string{"tag": "plain_text", "content": label},
+			"type":  style,
+			"value": map[string]string{"approval": approval.ID, "decision": decision},
+		}
+	}
+	return f.replyCard(ctx, target, map[string]any{
+		"config": map[string]any{"wide_screen_mode": true},
+		"header": map[string]any{
+			"title":    map[string]string{"tag": "plain_text", "content": "工具调用确认"},
+			"template": "orange",
+		},
+		"elements": []map[string]any{
+			{"tag": "markdown", "content": notify.ToFeishu(approval.Text)},
+			{"tag": "action", "actions": []map[string]any{
+				button("批准", feishuDecisionAllow, "primary"),
+				button("拒绝", feishuDecisionReject, "danger"),
+			}},
+		},
+	})
+}
+
+// replyCard 回复消息卡片，reply_in_thread 使回复出现在话题中
+func (f *Feishu) replyCard(ctx context.Context, target Target, card map[string]any) error {
+	token, err := f.tenantAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(card)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "序列化消息卡片失败", err)
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	endpoint := f.apiBase + "/open-apis/im/v1/messages/" + url.PathEscape(target.Thread) + "/reply"
+	err = postJSON(ctx, endpoint, map[string]string{"Authorization": "Bearer " + token}, map[string]any{
+		"msg_type":        "interactive",
+		"content":         string(content),
+		"reply_in_thread": true,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	if result.Code != 0 {
+		return errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "飞书拒绝了消息",
+			fmt.Sprintf("code=%d, msg=%s", result.Code, result.Msg))
+	}
+	return nil
+}
+
+// tenantAccessToken 返回缓存的 tenant_access_token，过期前重新获取
+func (f *Feishu) tenantAccessToken(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Now().Before(f.expiresAt) {
+		return f.accessToken, nil
+	}
+
+	var result struct {
+		Code              int    `json:"code"`
+		Msg               string `json:"msg"`
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"` // 有效期（秒）
+	}
+	err := postJSON(ctx, f.apiBase+"/open-apis/auth/v3/tenant_access_token/internal", nil,
+		map[string]string{"app_id": f.appID, "app_secret": f.appSecret}, &result)
+	if err != nil {
+		return "", err
+	}
+	if result.Code != 0 || result.TenantAccessToken == "" {
+		return "", errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "获取飞书 tenant_access_token 失败",
+			fmt.Sprintf("code=%d, msg=%s", result.Code, result.Msg))
+	}
+	f.accessToken = result.TenantAccessToken
+	f.expiresAt = time.Now().Add(max(time.Duration(result.Expire)*time.Second-feishuTokenMargin, time.Minute))
+	return f.accessToken, nil
+}
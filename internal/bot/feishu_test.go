@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"ai-ops/internal/util/errors"
+)
+
+// feishuMessage 生成飞书接收消息事件的明文
+func feishuMessage(token, openID, chatID, text string) []byte {
+	content, _ := json.Marshal(map[string]string{"text": "@_user_1 " + text})
+	body, _ := json.Marshal(map[string]any{
+		"schema": "2.0",
+		"header": map[string]any{"event_type": "im.message.receive_v1", "token": token},
+		"event": map[string]any{
+			"sender": map[string]any{"sender_id": map[string]string{"open_id": openID}, "sender_type": "user"},
+			"message": map[string]any{
+				"message_id":   "om_1",
+				"chat_id":      chatID,
+				"message_type": "text",
+				"content":      string(content),
+			},
+		},
+	})
+	return body
+}
+
+// feishuEncrypt 按飞书的方式加密事件：AES-256-CBC，密钥为 encrypt_key 的 SHA256，密文前 16 字节为 IV
+func feishuEncrypt(t *testing.T, encryptKey string, plain []byte) []byte {
+	t.Helper()
+	key := sha256.Sum256([]byte(encryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	plain = append(plain, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	data := make([]byte, aes.BlockSize+len(plain))
+	if _, err := rand.Read(data[:aes.BlockSize]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, data[:aes.BlockSize]).CryptBlocks(data[aes.BlockSize:], plain)
+	body, _ := json.Marshal(map[string]string{"encrypt": base64.StdEncoding.EncodeToString(data)})
+	return body
+}
+
+// feishuHeader 生成带签名的飞书回调请求头
+func feishuHeader(encryptKey string, ts time.Time, body []byte) http.Header {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	sum := sha256.Sum256([]byte(timestamp + "nonce" + encryptKey + string(body)))
+	header := http.Header{}
+	header.Set("X-Lark-Request-Timestamp", timestamp)
+	header.Set("X-Lark-Request-Nonce", "nonce")
+	header.Set("X-Lark-Signature", hex.EncodeToString(sum[:]))
+	return header
+}
+
+func TestFeishuVerify(t *testing.T) {
+	const encryptKey = "ek-1"
+	body := feishuEncrypt(t, encryptKey, feishuMessage("vt", "ou_1", "oc_1", "磁盘满了"))
+	other := feishuEncrypt(t, encryptKey, feishuMessage("vt", "ou_2", "oc_1", "删除数据"))
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		ok     bool
+	}{
+		{name: "签名正确", header: feishuHeader(encryptKey, time.Now(), body), body: body, ok: true},
+		{name: "请求体被替换", header: feishuHeader(encryptKey, time.Now(), body), body: other},
+		{name: "密钥错误", header: feishuHeader("wrong", time.Now(), body), body: body},
+		{name: "时间戳过期", header: feishuHeader(encryptKey, time.Now().Add(-10*time.Minute), body), body: body},
+		{name: "时间戳超前", header: feishuHeader(encryptKey, time.Now().Add(10*time.Minute), body), body: body},
+		{name: "缺少签名", header: http.Header{}, body: body},
+	}
+	feishu, err := NewFeishu("cli_1", "secret", "vt", encryptKey, "")
+	if err != nil {
+		t.Fatalf("NewFeishu: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, _, err := feishu.Parse(tt.header, tt.body)
+			if !tt.ok {
+				if !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+					t.Fatalf("Parse() error = %v, want UNAUTHORIZED", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if event == nil || event.Text != " 磁盘满了" || len(event.Users) != 1 || event.Users[0] != "ou_1" {
+				t.Errorf("事件解析不正确: %+v", event)
+			}
+		})
+	}
+}
+
+func TestFeishuVerificationToken(t *testing.T) {
+	feishu, err := NewFeishu("cli_1", "secret", "vt", "", "")
+	if err != nil {
+		t.Fatalf("NewFeishu: %v", err)
+	}
+	if event, _, err := feishu.Parse(http.Header{}, feishuMessage("vt", "ou_1", "oc_1", "hi")); err != nil || event == nil {
+		t.Errorf("Verification Token 正确时应解析事件: %v, %v", event, err)
+	}
+	if _, _, err := feishu.Parse(http.Header{}, feishuMessage("forged", "ou_1", "oc_1", "hi")); !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+		t.Errorf("Verification Token 错误时应返回 UNAUTHORIZED: %v", err)
+	}
+	challenge := []byte(`{"type":"url_verification","challenge":"ch1","token":"forged"}`)
+	if _, _, err := feishu.Parse(http.Header{}, challenge); !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+		t.Errorf("地址验证的 token 错误时应返回 UNAUTHORIZED: %v", err)
+	}
+	// 未配置 encrypt_key 时不接受加密的事件
+	encrypted := feishuEncrypt(t, "ek-1", feishuMessage("vt", "ou_1", "oc_1", "hi"))
+	if _, _, err := feishu.Parse(http.Header{}, encrypted); !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+		t.Errorf("收到加密事件时应返回 UNAUTHORIZED: %v", err)
+	}
+}
+
+func TestNewFeishuRequiresSecrets(t *testing.T) {
+	if _, err := NewFeishu("", "secret", "vt", "", ""); err == nil {
+		t.Error("app_id 为空时应报错")
+	}
+	if _, err := NewFeishu("cli_1", "secret", "", "", ""); err == nil {
+		t.Error("verification_token 与 encrypt_key 都为空时应报错")
+	}
+}
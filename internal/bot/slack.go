@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-ops/internal/notify"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// Slack 回调参数
+const (
+	slackAPIBase         = "https://slack.com/api"
+	slackMaxClockSkew    = 5 * time.Minute // 请求时间戳与本机时间的最大偏差，超出视为重放
+	slackActionApprove   = "approve"
+	slackActionDeny      = "deny"
+	slackMaxSectionRunes = 2900 // section 文本上限为 3000 个字符
+)
+
+var (
+	slackMentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+	slackUnescaper      = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+)
+
+// Slack 通过 Events API 接收消息，Interactivity 接收按钮点击，使用 Web API 回复到消息所在的话题。
+// 频道中提及机器人开始对话，之后同一话题中的消息不需要再提及；私聊中的每条消息都会处理。
+type Slack struct {
+	signingSecret string
+	botToken      string
+	apiBase       string
+}
+
+// NewSlack 创建 Slack 平台，apiBase 为空时使用 https://slack.com/api。
+// 签名密钥为空时任何人都能伪造回调，因此不允许为空
+func NewSlack(signingSecret, botToken, apiBase string) (*Slack, error) {
+	if signingSecret == "" || botToken == "" {
+		return nil, errors.NewError(errors.ErrCodeConfigInvalid, "slack 的 signing_secret 与 bot_token 不能为空（检查引用的环境变量是否已设置）")
+	}
+	if apiBase == "" {
+		apiBase = slackAPIBase
+	}
+	return &Slack{signingSecret: signingSecret, botToken: botToken, apiBase: strings.TrimRight(apiBase, "/")}, nil
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+// Parse 校验签名后解析事件回调或按钮点击（Interactivity 与事件可使用同一地址）
+func (s *Slack) Parse(header http.Header, body []byte) (*Event, any, error) {
+	if err := s.verify(header, body); err != nil {
+		return nil, nil, err
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return s.parseAction(body)
+	}
+
+	var envelope struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Event     struct {
+			Type        string `json:"type"`
+			Subtype     string `json:"subtype"`
+			User        string `json:"user"`
+			BotID       string `json:"bot_id"`
+			Text        string `json:"text"`
+			Channel     string `json:"channel"`
+			ChannelType string `json:"channel_type"`
+			TS          string `json:"ts"`
+			ThreadTS    string `json:"thread_ts"`
+		} `json:"event"`
+	}
+	if err := decodeEvent(body, &envelope); err != nil {
+		return nil, nil, err
+	}
+	switch envelope.Type {
+	case "url_verification":
+		return nil, map[string]string{"challenge": envelope.Challenge}, nil
+	case "event_callback":
+	default:
+		return nil, nil, nil
+	}
+
+	ev := envelope.Event
+	// 忽略机器人（包括自己）发送的消息与编辑、删除等消息子类型
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" {
+		return nil, nil, nil
+	}
+	followup := false
+	switch ev.Type {
+	case "app_mention":
+	case "message":
+		// 频道中没有提及机器人的消息只在已有对话的话题中处理
+		if ev.ChannelType != "im" {
+			if ev.ThreadTS == "" {
+				return nil, nil, nil
+			}
+			followup = true
+		}
+	default:
+		return nil, nil, nil
+	}
+
+	thread := ev.ThreadTS
+	if thread == "" {
+		thread = ev.TS
+	}
+	return &Event{
+		Kind: EventMessage,
+		// 提及机器人的消息会同时产生 app_mention 与 message 事件，按消息去重
+		ID:           ev.Channel + ":" + ev.TS,
+		Users:        []string{ev.User},
+		Conversation: "slack:" + ev.Channel + ":" + thread,
+		Followup:     followup,
+		Text:         slackUnescaper.Replace(slackMentionPattern.ReplaceAllString(ev.Text, "")),
+		Target:       Target{Chat: ev.Channel, Thread: thread},
+	}, nil, nil
+}
+
+// parseAction 解析 Interactivity 的 block_actions 回调
+func (s *Slack) parseAction(body []byte) (*Event, any, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, nil, errors.WrapError(errors.ErrCodeInvalidParameters, "无法解析按钮回调", err)
+	}
+	var payload struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		Message struct {
+			TS       string `json:"ts"`
+			ThreadTS string `json:"thread_ts"`
+		} `json:"message"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := decodeEvent([]byte(values.Get("payload")), &payload); err != nil {
+		return nil, nil, err
+	}
+	if payload.Type != "block_actions" || len(payload.Actions) == 0 {
+		return nil, nil, nil
+	}
+	action := payload.Actions[0]
+	if action.ActionID != slackActionApprove && action.ActionID != slackActionDeny {
+		return nil, nil, nil
+	}
+	thread := payload.Message.ThreadTS
+	if thread == "" {
+		thread = payload.Message.TS
+	}
+	return &Event{
+		Kind:       EventAction,
+		Users:      []string{payload.User.ID},
+		Target:     Target{Chat: payload.Channel.ID, Thread: thread},
+		ApprovalID: action.Value,
+		Approve:    action.ActionID == slackActionApprove,
+	}, nil, nil
+}
+
+// verify 校验请求签名：v0= + HmacSHA256(signing_secret, "v0:" + timestamp + ":" + body) 的十六进制
+func (s *Slack) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > slackMaxClockSkew {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求时间戳无效或已过期")
+	}
+	mac := hmac.New(sha256.New, []byte(s.signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	if !signatureEqual("v0="+hex.EncodeToString(mac.Sum(nil)), header.Get("X-Slack-Signature")) {
+		return errors.NewError(errors.ErrCodeUnauthorized, "请求签名校验失败")
+	}
+	return nil
+}
+
+// Reply 在话题中回复 mrkdwn 消息
+func (s *Slack) Reply(ctx context.Context, target Target, markdown string) error {
+	return s.postMessage(ctx, map[string]any{
+		"channel":   target.Chat,
+		"thread_ts": target.Thread,
+		"text":      notify.ToSlack(markdown),
+	})
+}
+
+// AskApproval 在话题中发送带批准、拒绝按钮的消息，按钮的 value 为确认ID
+func (s *Slack) AskApproval(ctx context.Context, target Target, approval Approval) error {
+	text := util.Ellipsize(notify.ToSlack(approval.Text), slackMaxSectionRunes)
+	button := func(label, actionID, style string) map[string]any {
+		return map[string]any{
+			"type":      "button",
+			"text":      map[string]string{"type": "plain_text", "text": label},
+			"action_id": actionID,
+			"value":     approval.ID,
+			"style":     style,
+		}
+	}
+	return s.postMessage(ctx, map[string]any{
+		"channel":   target.Chat,
+		"thread_ts": target.Thread,
+		"text":      text,
+		"blocks": []map[string]any{
+			{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+			{"type": "actions", "elements": []map[string]any{
+				button("批准", slackActionApprove, "primary"),
+				button("拒绝", slackActionDeny, "danger"),
+			}},
+		},
+	})
+}
+
+// postMessage 调用 chat.postMessage
+func (s *Slack) postMessage(ctx context.Context, payload map[string]any) error {
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + s.botToken}
+	if err := postJSON(ctx, s.apiBase+"/chat.postMessage", headers, payload, &result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "Slack 拒绝了消息", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"ai-ops/internal/util/errors"
+)
+
+// slackHeader 生成带签名的 Slack 回调请求头
+func slackHeader(secret string, ts time.Time, body string) http.Header {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestSlackVerify(t *testing.T) {
+	const secret = "slack-secret"
+	body := `{"type":"event_callback","event":{"type":"app_mention","user":"U1","ts":"1.1","text":"<@B> 磁盘满了","channel":"C1"}}`
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		ok     bool
+	}{
+		{name: "签名正确", header: slackHeader(secret, time.Now(), body), body: body, ok: true},
+		{name: "允许的时间偏差内", header: slackHeader(secret, time.Now().Add(-4*time.Minute), body), body: body, ok: true},
+		{name: "请求体被篡改", header: slackHeader(secret, time.Now(), body), body: body[:len(body)-2] + " }}"},
+		{name: "签名密钥错误", header: slackHeader("wrong", time.Now(), body), body: body},
+		{name: "时间戳过期", header: slackHeader(secret, time.Now().Add(-10*time.Minute), body), body: body},
+		{name: "时间戳超前", header: slackHeader(secret, time.Now().Add(10*time.Minute), body), body: body},
+		{name: "缺少签名", header: http.Header{"X-Slack-Request-Timestamp": {strconv.FormatInt(time.Now().Unix(), 10)}}, body: body},
+	}
+	slack, err := NewSlack(secret, "xoxb-1", "")
+	if err != nil {
+		t.Fatalf("NewSlack: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, _, err := slack.Parse(tt.header, []byte(tt.body))
+			if !tt.ok {
+				if !errors.IsErrorCode(err, errors.ErrCodeUnauthorized) {
+					t.Fatalf("Parse() error = %v, want UNAUTHORIZED", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if event == nil || event.Text != " 磁盘满了" || event.Target != (Target{Chat: "C1", Thread: "1.1"}) {
+				t.Errorf("事件解析不正确: %+v", event)
+			}
+		})
+	}
+}
+
+func TestNewSlackRequiresSecrets(t *testing.T) {
+	if _, err := NewSlack("", "xoxb-1", ""); err == nil {
+		t.Error("signing_secret 为空时应报错")
+	}
+	if _, err := NewSlack("secret", "", ""); err == nil {
+		t.Error("bot_token 为空时应报错")
+	}
+}
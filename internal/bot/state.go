@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"ai-ops/internal/util/errors"
+)
+
+// State 平台对话与 ai-ops 会话的对应关系，保存在 JSON 文件中，重启后对话继续使用原会话
+type State struct {
+	mu       sync.Mutex
+	path     string
+	sessions map[string]string // 对话标识 -> 会话ID
+}
+
+// DefaultStatePath 默认的对应关系文件 ~/.ai-ops/bot/conversations.json
+func DefaultStatePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ai-ops", "bot", "conversations.json")
+	}
+	return filepath.Join(homeDir, ".ai-ops", "bot", "conversations.json")
+}
+
+// LoadState 读取对应关系文件，path 为空时使用默认路径，文件不存在时返回空的对应关系
+func LoadState(path string) (*State, error) {
+	if path == "" {
+		path = DefaultStatePath()
+	}
+	state := &State{path: path, sessions: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.WrapErrorWithDetails(errors.ErrCodeConfigLoadFailed, "读取对话状态文件失败", err, path)
+	}
+	if err := json.Unmarshal(data, &state.sessions); err != nil {
+		return nil, errors.WrapErrorWithDetails(errors.ErrCodeConfigParseFailed, "对话状态文件格式错误", err, path)
+	}
+	return state, nil
+}
+
+// Get 返回对话对应的会话ID，没有时返回空字符串
+func (s *State) Get(conversation string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[conversation]
+}
+
+// Set 记录对话对应的会话并保存
+func (s *State) Set(conversation, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[conversation] = sessionID
+	return s.save()
+}
+
+// Delete 删除对话的对应关系并保存
+func (s *State) Delete(conversation string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[conversation]; !ok {
+		return nil
+	}
+	delete(s.sessions, conversation)
+	return s.save()
+}
+
+// save 先写入临时文件再替换，避免写入中断导致文件损坏，调用方需持有锁
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s.sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,443 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tracing"
+	"ai-ops/internal/util"
+)
+
+// 智能体预算的默认值
+const (
+	defaultAgentMaxSteps     = 10
+	defaultAgentMaxToolCalls = 20
+	defaultAgentMaxTokens    = 200000
+	// 相同的工具调用被跳过的次数达到该值时判定为陷入循环
+	maxRepeatedToolCalls = 3
+)
+
+// 智能体停止原因
+const (
+	AgentStopCompleted    = "completed"      // 模型给出了最终回答
+	AgentStopMaxSteps     = "max_steps"      // 达到步骤数上限
+	AgentStopMaxToolCalls = "max_tool_calls" // 达到工具调用次数上限
+	AgentStopMaxTokens    = "max_tokens"     // 达到令牌数上限
+	AgentStopLoop         = "loop_detected"  // 反复发起相同的工具调用
+)
+
+// 步骤执行结果
+const (
+	StepSucceeded = "success" // 所有工具调用均成功
+	StepFailed    = "failed"  // 存在失败或被拒绝的工具调用
+	StepSkipped   = "skipped" // 所有工具调用均因重复或超出预算被跳过
+)
+
+// AgentBudget 智能体单个任务的执行预算
+type AgentBudget struct {
+	MaxSteps     int // 最多的模型请求次数（不含计划和总结）
+	MaxToolCalls int // 最多执行的工具调用次数
+	MaxTokens    int // 最多消耗的令牌数
+}
+
+// withDefaults 为未设置的预算项填充默认值
+func (b AgentBudget) withDefaults() AgentBudget {
+	if b.MaxSteps <= 0 {
+		b.MaxSteps = defaultAgentMaxSteps
+	}
+	if b.MaxToolCalls <= 0 {
+		b.MaxToolCalls = defaultAgentMaxToolCalls
+	}
+	if b.MaxTokens <= 0 {
+		b.MaxTokens = defaultAgentMaxTokens
+	}
+	return b
+}
+
+// AgentStep 智能体执行的一个步骤
+type AgentStep struct {
+	Index     int             `json:"index"`
+	Thought   string          `json:"thought,omitempty"` // 模型在调用工具时给出的说明
+	ToolCalls []ToolCallTrace `json:"tool_calls"`
+	Outcome   string          `json:"outcome"`
+	Replanned bool            `json:"replanned,omitempty"` // 失败后是否要求模型重新规划
+}
+
+// AgentReport 智能体任务的执行报告
+type AgentReport struct {
+	Goal       string         `json:"goal"`
+	Plan       string         `json:"plan"`
+	Steps      []AgentStep    `json:"steps"`
+	Final      string         `json:"final"`
+	StopReason string         `json:"stop_reason"`
+	ToolCalls  int            `json:"tool_calls"`
+	Usage      llm.TokenUsage `json:"usage"`
+}
+
+// AgentHooks 执行过程中的回调，用于实时展示进度
+type AgentHooks struct {
+	OnPlan func(plan string)
+	OnStep func(step AgentStep)
+}
+
+// AgentRunner 以“计划-执行-观察”的方式运行智能体任务
+type AgentRunner struct {
+	session *Session
+	budget  AgentBudget
+	hooks   AgentHooks
+}
+
+// NewAgentRunner 创建智能体执行器，任务在会话的上下文中执行，结束后整合到会话历史
+func NewAgentRunner(session *Session, budget AgentBudget, hooks AgentHooks) *AgentRunner {
+	return &AgentRunner{
+		session: session,
+		budget:  budget.withDefaults(),
+		hooks:   hooks,
+	}
+}
+
+const (
+	agentPlanPrompt = `请先为上面的任务制定执行计划：列出编号步骤，每一步说明目的和计划使用的工具。
+只输出计划，不要调用工具，也不要给出结论。`
+	agentExecutePrompt = `请按照上述计划逐步执行，需要时调用工具，根据工具结果调整后续步骤。
+全部完成后直接给出最终结论，不要再调用工具。`
+	agentReplanPrompt = `第 %d 步存在失败的工具调用：%s
+请分析失败原因，必要时调整计划后继续执行，不要重复完全相同的调用。`
+	agentSummaryPrompt = `已停止执行（%s）。请不要再调用工具，根据目前获得的信息给出结论，并说明尚未完成的部分。`
+)
+
+// Run 执行任务并返回执行报告
+func (a *AgentRunner) Run(ctx context.Context, goal string) (report *AgentReport, err error) {
+	s := a.session
+	s.syncClient()
+	report = &AgentReport{Goal: goal, Steps: []AgentStep{}}
+	startTime := s.beginTurn("agent", goal)
+	defer func() {
+		reply := ""
+		if err == nil {
+			reply = report.Markdown()
+		}
+		s.endTurn(startTime, reply, report.Usage, err)
+	}()
+
+	ctx, span := tracing.Start(ctx, "chat.agent_run", tracing.SpanKindInternal)
+	defer func() {
+		span.RecordError(err)
+		span.SetAttributes(map[string]any{
+			"agent.steps":            len(report.Steps),
+			"agent.stop_reason":      report.StopReason,
+			"chat.tool_calls":        report.ToolCalls,
+			"llm.usage.total_tokens": report.Usage.TotalTokens,
+		})
+		span.End()
+	}()
+
+	// 任务执行期间不修剪历史，避免目标和计划被移出上下文；消息数量受步骤预算约束，结束后整合为一问一答
+	s.trimHistory()
+	roundStartIndex := len(s.messages)
+	redactedGoal, redactedCount := s.redactor.Redact(goal)
+	s.appendMessage(llm.Message{Role: "user", Content: redactedGoal})
+	defer func() {
+		if redactedCount > 0 {
+			s.notices = append(s.notices, fmt.Sprintf("已脱敏 %d 处敏感信息后发送给模型", redactedCount))
+		}
+		if err != nil {
+			// 失败或取消的任务不保留在历史记录中
+			s.rollbackRound(roundStartIndex)
+		}
+	}()
+
+	// 计划阶段：禁用工具，只让模型输出计划
+	planMessages := append(append([]llm.Message{}, s.messages...), llm.Message{Role: "user", Content: agentPlanPrompt})
+	planResp, err := a.send(ctx, planMessages, llm.ToolChoice{Mode: llm.ToolChoiceNone}, report)
+	if err != nil {
+		if ctx.Err() != nil {
+			return report, interruptedError(ctx)
+		}
+		return report, fmt.Errorf("制定执行计划失败: %w", err)
+	}
+	report.Plan = s.redactor.Restore(planResp.Content)
+	s.emit(Event{Type: EventAgentPlan, Plan: report.Plan})
+	if a.hooks.OnPlan != nil {
+		a.hooks.OnPlan(report.Plan)
+	}
+	s.appendMessage(llm.Message{Role: "assistant", Content: planResp.Content})
+	s.appendInternalMessage(llm.Message{Role: "user", Content: agentExecutePrompt})
+
+	seenCalls := make(map[string]int) // 工具调用签名 -> 首次出现的步骤
+	repeated := 0
+
+	for {
+		if reason := a.exhausted(report, repeated); reason != "" {
+			report.StopReason = reason
+			break
+		}
+
+		resp, err := a.send(ctx, s.messages, llm.ToolChoice{Mode: llm.ToolChoiceAuto}, report)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, interruptedError(ctx)
+			}
+			return report, fmt.Errorf("发送消息到AI失败: %w", err)
+		}
+		s.appendMessage(llm.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+
+		if len(resp.ToolCalls) == 0 {
+			report.Final = s.redactor.Restore(resp.Content)
+			report.StopReason = AgentStopCompleted
+			break
+		}
+
+		step := AgentStep{Index: len(report.Steps) + 1, Thought: s.redactor.Restore(resp.Content)}
+		toolMessages, stepRepeats, n, err := a.executeStep(ctx, resp.ToolCalls, seenCalls, &step, report)
+		redactedCount += n
+		repeated += stepRepeats
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, interruptedError(ctx)
+			}
+			return report, fmt.Errorf("执行工具失败: %w", err)
+		}
+		for _, msg := range toolMessages {
+			s.appendMessage(msg)
+		}
+
+		// 失败后要求模型重新规划
+		if step.Outcome == StepFailed {
+			step.Replanned = true
+			s.appendInternalMessage(llm.Message{
+				Role:    "user",
+				Content: fmt.Sprintf(agentReplanPrompt, step.Index, failedCallsSummary(step.ToolCalls)),
+			})
+		}
+
+		report.Steps = append(report.Steps, step)
+		s.emit(Event{Type: EventAgentStep, Step: &step})
+		if a.hooks.OnStep != nil {
+			a.hooks.OnStep(step)
+		}
+	}
+
+	// 因预算或循环停止时，要求模型根据已有信息总结
+	if report.StopReason != AgentStopCompleted {
+		s.appendInternalMessage(llm.Message{
+			Role:    "user",
+			Content: fmt.Sprintf(agentSummaryPrompt, agentStopReasonLabel(report.StopReason)),
+		})
+		resp, err := a.send(ctx, s.messages, llm.ToolChoice{Mode: llm.ToolChoiceNone}, report)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, interruptedError(ctx)
+			}
+			return report, fmt.Errorf("生成总结失败: %w", err)
+		}
+		s.appendMessage(llm.Message{Role: "assistant", Content: resp.Content})
+		report.Final = s.redactor.Restore(resp.Content)
+	}
+
+	util.Infow("智能体任务结束", map[string]any{
+		"steps":        len(report.Steps),
+		"tool_calls":   report.ToolCalls,
+		"stop_reason":  report.StopReason,
+		"total_tokens": report.Usage.TotalTokens,
+	})
+
+	s.consolidateHistory(roundStartIndex)
+	s.recordTurnUsage(report.Usage)
+	s.lastTurnDone = true
+	return report, nil
+}
+
+// send 发送一次模型请求并累计用量
+func (a *AgentRunner) send(ctx context.Context, messages []llm.Message, choice llm.ToolChoice, report *AgentReport) (*llm.Response, error) {
+	s := a.session
+	resp, err := s.sendRequest(ctx, messages, choice)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Usage.PromptTokens += resp.Usage.PromptTokens
+	report.Usage.CompletionTokens += resp.Usage.CompletionTokens
+	report.Usage.TotalTokens += resp.Usage.TotalTokens
+	s.lastTurn.Usage = report.Usage
+	return resp, nil
+}
+
+// exhausted 检查预算，返回停止原因，未耗尽时返回空
+func (a *AgentRunner) exhausted(report *AgentReport, repeated int) string {
+	switch {
+	case repeated >= maxRepeatedToolCalls:
+		return AgentStopLoop
+	case len(report.Steps) >= a.budget.MaxSteps:
+		return AgentStopMaxSteps
+	case report.ToolCalls >= a.budget.MaxToolCalls:
+		return AgentStopMaxToolCalls
+	case report.Usage.TotalTokens >= a.budget.MaxTokens:
+		return AgentStopMaxTokens
+	default:
+		return ""
+	}
+}
+
+// executeStep 执行一个步骤中的工具调用。
+// 与之前完全相同的调用和超出预算的调用不会执行，而是以工具结果的形式告知模型。
+// 返回按调用顺序排列的工具结果消息、被跳过的重复调用数和脱敏次数。
+func (a *AgentRunner) executeStep(ctx context.Context, calls []llm.ToolCall, seenCalls map[string]int, step *AgentStep, report *AgentReport) ([]llm.Message, int, int, error) {
+	s := a.session
+	skipped := make(map[string]string) // 调用ID -> 跳过原因
+	var toRun []llm.ToolCall
+	repeats := 0
+
+	for _, tc := range calls {
+		signature := toolCallSignature(tc)
+		if first, ok := seenCalls[signature]; ok {
+			repeats++
+			skipped[tc.ID] = fmt.Sprintf("该调用与第 %d 步中的调用完全相同，已跳过。请直接使用之前的结果，或调整参数与计划。", first)
+			continue
+		}
+		if report.ToolCalls+len(toRun) >= a.budget.MaxToolCalls {
+			skipped[tc.ID] = "已达到工具调用次数上限，未执行。"
+			continue
+		}
+		seenCalls[signature] = step.Index
+		toRun = append(toRun, tc)
+	}
+
+	traceStart := len(s.lastTurn.ToolCalls)
+	results, redactedCount, err := s.executeTools(ctx, toRun)
+	if err != nil {
+		return nil, repeats, redactedCount, err
+	}
+	report.ToolCalls += len(toRun)
+
+	executed := make(map[string]llm.Message, len(results))
+	for _, msg := range results {
+		executed[msg.ToolCallID] = msg
+	}
+	traces := make(map[string]ToolCallTrace, len(calls))
+	for _, trace := range s.lastTurn.ToolCalls[traceStart:] {
+		traces[trace.ID] = trace
+	}
+
+	messages := make([]llm.Message, 0, len(calls))
+	failed := false
+	for _, tc := range calls {
+		if reason, ok := skipped[tc.ID]; ok {
+			messages = append(messages, llm.Message{Role: "tool", Content: reason, ToolCallID: tc.ID, Name: tc.Name})
+			trace := ToolCallTrace{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments, Error: reason}
+			s.lastTurn.ToolCalls = append(s.lastTurn.ToolCalls, trace)
+			step.ToolCalls = append(step.ToolCalls, trace)
+			continue
+		}
+		messages = append(messages, executed[tc.ID])
+		trace := traces[tc.ID]
+		if trace.Error != "" {
+			failed = true
+		}
+		step.ToolCalls = append(step.ToolCalls, trace)
+	}
+
+	switch {
+	case len(toRun) == 0:
+		step.Outcome = StepSkipped
+	case failed:
+		step.Outcome = StepFailed
+	default:
+		step.Outcome = StepSucceeded
+	}
+	return messages, repeats, redactedCount, nil
+}
+
+// toolCallSignature 生成用于识别重复调用的签名，参数按键排序序列化
+func toolCallSignature(tc llm.ToolCall) string {
+	args, _ := json.Marshal(tc.Arguments)
+	return tc.Name + ":" + string(args)
+}
+
+// failedCallsSummary 汇总步骤中失败的调用
+func failedCallsSummary(traces []ToolCallTrace) string {
+	var parts []string
+	for _, trace := range traces {
+		if trace.Error != "" {
+			parts = append(parts, fmt.Sprintf("%s（%s）", trace.Name, util.OneLine(trace.Error, 200)))
+		}
+	}
+	return strings.Join(parts, "；")
+}
+
+// agentStopReasonLabel 停止原因的中文说明
+func agentStopReasonLabel(reason string) string {
+	switch reason {
+	case AgentStopCompleted:
+		return "任务完成"
+	case AgentStopMaxSteps:
+		return "达到步骤数上限"
+	case AgentStopMaxToolCalls:
+		return "达到工具调用次数上限"
+	case AgentStopMaxTokens:
+		return "达到令牌数上限"
+	case AgentStopLoop:
+		return "检测到重复的工具调用"
+	default:
+		return reason
+	}
+}
+
+// stepOutcomeIcon 步骤结果的图标
+func stepOutcomeIcon(outcome string) string {
+	switch outcome {
+	case StepSucceeded:
+		return "✅"
+	case StepFailed:
+		return "❌"
+	default:
+		return "⏭️"
+	}
+}
+
+// FormatStep 将步骤格式化为一行或多行摘要
+func FormatStep(step AgentStep) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s 第 %d 步", stepOutcomeIcon(step.Outcome), step.Index)
+	for _, trace := range step.ToolCalls {
+		args, _ := json.Marshal(trace.Arguments)
+		fmt.Fprintf(&b, "\n   - %s(%s)", trace.Name, util.OneLine(string(args), 80))
+		if trace.Error != "" {
+			fmt.Fprintf(&b, " → %s", util.OneLine(trace.Error, 80))
+		} else {
+			fmt.Fprintf(&b, " → 成功（%dms）", trace.DurationMs)
+		}
+	}
+	if step.Replanned {
+		b.WriteString("\n   ↻ 已要求模型重新规划")
+	}
+	return b.String()
+}
+
+// Markdown 将执行报告渲染为 Markdown
+func (r *AgentReport) Markdown() string {
+	var b strings.Builder
+	b.WriteString("### 📋 执行计划\n\n")
+	b.WriteString(strings.TrimSpace(r.Plan))
+
+	b.WriteString("\n\n### 🪜 执行步骤\n\n")
+	if len(r.Steps) == 0 {
+		b.WriteString("无需调用工具\n")
+	}
+	for _, step := range r.Steps {
+		b.WriteString(FormatStep(step))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n### 📝 结论\n\n")
+	b.WriteString(strings.TrimSpace(r.Final))
+	fmt.Fprintf(&b, "\n\n> 共 %d 步，%d 次工具调用，%d 令牌；停止原因: %s",
+		len(r.Steps), r.ToolCalls, r.Usage.TotalTokens, agentStopReasonLabel(r.StopReason))
+	return b.String()
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"ai-ops/internal/tools"
+)
+
+// 有风险工具调用的确认方式
+const (
+	ApprovalModePrompt = "prompt" // 人工确认（默认）
+	ApprovalModeAuto   = "auto"   // 自动批准
+	ApprovalModeDeny   = "deny"   // 一律拒绝
+)
+
+// ApprovalDecision 人工确认的结果
+type ApprovalDecision string
+
+const (
+	ApprovalApproved     ApprovalDecision = "approved"      // 批准执行
+	ApprovalEdited       ApprovalDecision = "edited"        // 修改参数后执行
+	ApprovalDenied       ApprovalDecision = "denied"        // 拒绝执行
+	ApprovalAutoApproved ApprovalDecision = "auto_approved" // 按配置自动批准
+)
+
+// ApprovalRequest 待确认的工具调用
+type ApprovalRequest struct {
+	CallID    string
+	ToolName  string
+	Risk      tools.RiskLevel
+	Arguments map[string]any // 已还原脱敏占位符的实际参数
+	Title     string         // 提示标题（为空时按模型请求的工具调用展示）
+}
+
+// ApprovalResponse 确认结果
+type ApprovalResponse struct {
+	Decision  ApprovalDecision
+	Arguments map[string]any // Decision 为 ApprovalEdited 时使用的新参数
+	Reason    string         // 拒绝原因（可选）
+}
+
+// ToolApprover 在执行有风险的工具调用前请求人工确认
+type ToolApprover interface {
+	// Approve 阻塞等待确认结果，ctx 取消时返回错误
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalResponse, error)
+}
+
+// formatApprovalRequest 生成展示给用户的确认提示
+func formatApprovalRequest(req ApprovalRequest) string {
+	args, err := json.MarshalIndent(req.Arguments, "", "  ")
+	if err != nil {
+		args = []byte(fmt.Sprintf("%v", req.Arguments))
+	}
+	title := fmt.Sprintf("模型请求执行%s操作: %s", req.Risk.Label(), req.ToolName)
+	if req.Title != "" {
+		title = req.Title
+	}
+	return fmt.Sprintf("⚠️ %s\n参数:\n%s", title, args)
+}
+
+// TerminalApprover 通过终端读写进行确认，用于非 TUI 场景
+type TerminalApprover struct {
+	mu     sync.Mutex
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+// NewTerminalApprover 创建终端确认器，in 通常为 /dev/tty
+func NewTerminalApprover(in io.Reader, out io.Writer) *TerminalApprover {
+	return &TerminalApprover{reader: bufio.NewReader(in), out: out}
+}
+
+// Approve 在终端中提示用户批准、拒绝或编辑参数
+func (a *TerminalApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	fmt.Fprintf(a.out, "\n%s\n", formatApprovalRequest(req))
+	for {
+		if err := ctx.Err(); err != nil {
+			return ApprovalResponse{}, err
+		}
+
+		answer, err := a.prompt("[y] 批准  [n] 拒绝  [e] 编辑参数: ")
+		if err != nil {
+			return ApprovalResponse{}, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return ApprovalResponse{Decision: ApprovalApproved}, nil
+		case "n", "no":
+			reason, err := a.prompt("拒绝原因（可选）: ")
+			if err != nil {
+				return ApprovalResponse{}, err
+			}
+			return ApprovalResponse{Decision: ApprovalDenied, Reason: reason}, nil
+		case "e", "edit":
+			line, err := a.prompt("输入新的参数（单行 JSON）: ")
+			if err != nil {
+				return ApprovalResponse{}, err
+			}
+			var args map[string]any
+			if err := json.Unmarshal([]byte(line), &args); err != nil {
+				fmt.Fprintf(a.out, "参数不是有效的 JSON 对象: %v\n", err)
+				continue
+			}
+			return ApprovalResponse{Decision: ApprovalEdited, Arguments: args}, nil
+		}
+	}
+}
+
+// prompt 输出提示并读取一行输入
+func (a *TerminalApprover) prompt(text string) (string, error) {
+	fmt.Fprint(a.out, text)
+	line, err := a.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("读取确认输入失败: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// approvalRequestMsg 将确认请求发送到聊天界面
+type approvalRequestMsg struct {
+	request ApprovalRequest
+	reply   chan ApprovalResponse
+}
+
+// tuiApprover 在 Bubble Tea 界面中确认工具调用
+type tuiApprover struct {
+	send func(tea.Msg) // 程序启动后设置为 tea.Program.Send
+}
+
+// Approve 将请求发送到界面并等待用户操作
+func (a *tuiApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalResponse, error) {
+	if a.send == nil {
+		return ApprovalResponse{Decision: ApprovalDenied, Reason: "当前环境无法进行人工确认"}, nil
+	}
+
+	reply := make(chan ApprovalResponse, 1)
+	a.send(approvalRequestMsg{request: req, reply: reply})
+	select {
+	case response := <-reply:
+		return response, nil
+	case <-ctx.Done():
+		return ApprovalResponse{}, ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/redact"
+	"ai-ops/internal/tools"
+)
+
+// fakeApprover 记录确认请求并返回预设的结果
+type fakeApprover struct {
+	response ApprovalResponse
+	err      error
+	requests []ApprovalRequest
+}
+
+func (a *fakeApprover) Approve(_ context.Context, req ApprovalRequest) (ApprovalResponse, error) {
+	a.requests = append(a.requests, req)
+	return a.response, a.err
+}
+
+func TestApproveToolCall(t *testing.T) {
+	approve := ApprovalResponse{Decision: ApprovalApproved}
+	tests := []struct {
+		name     string
+		tool     *fakeTool
+		config   SessionConfig
+		disable  bool             // 在会话中禁用该工具
+		approver *fakeApprover    // nil 表示不设置 Approver
+		risk     tools.RiskLevel  // 期望的风险等级
+		decision ApprovalDecision // 期望的确认结果
+		asked    bool             // 是否请求人工确认
+		wantErr  bool
+	}{
+		{name: "未声明风险等级按变更处理并请求确认", tool: &fakeTool{name: "t"}, approver: &fakeApprover{response: approve},
+			risk: tools.RiskMutating, decision: ApprovalApproved, asked: true},
+		{name: "只读工具无需确认", tool: &fakeTool{name: "t", risk: tools.RiskReadOnly}, approver: &fakeApprover{response: approve},
+			risk: tools.RiskReadOnly},
+		{name: "确认时拒绝", tool: &fakeTool{name: "t", risk: tools.RiskDestructive}, approver: &fakeApprover{response: ApprovalResponse{Decision: ApprovalDenied}},
+			risk: tools.RiskDestructive, decision: ApprovalDenied, asked: true},
+		{name: "deny 模式直接拒绝", tool: &fakeTool{name: "t"}, config: SessionConfig{ApprovalMode: ApprovalModeDeny}, approver: &fakeApprover{response: approve},
+			risk: tools.RiskMutating, decision: ApprovalDenied},
+		{name: "auto 模式自动批准", tool: &fakeTool{name: "t"}, config: SessionConfig{ApprovalMode: ApprovalModeAuto}, approver: &fakeApprover{response: approve},
+			risk: tools.RiskMutating, decision: ApprovalAutoApproved},
+		{name: "没有 Approver 时拒绝", tool: &fakeTool{name: "t"},
+			risk: tools.RiskMutating, decision: ApprovalDenied},
+		{name: "本会话禁用的工具被拒绝", tool: &fakeTool{name: "t", risk: tools.RiskReadOnly}, disable: true, approver: &fakeApprover{response: approve},
+			risk: tools.RiskReadOnly, decision: ApprovalDenied},
+		{name: "禁用全部工具时被拒绝", tool: &fakeTool{name: "t"}, config: SessionConfig{DisableTools: true, ApprovalMode: ApprovalModeAuto},
+			risk: tools.RiskMutating, decision: ApprovalDenied},
+		{name: "确认出错", tool: &fakeTool{name: "t"}, approver: &fakeApprover{err: context.Canceled},
+			risk: tools.RiskMutating, asked: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if tt.approver != nil {
+				config.Approver = tt.approver
+			}
+			session := NewSession(&fakeAdapter{}, newFakeToolManager(tt.tool), config)
+			if tt.disable {
+				if err := session.SetToolEnabled(tt.tool.name, false); err != nil {
+					t.Fatalf("SetToolEnabled: %v", err)
+				}
+			}
+
+			call := llm.ToolCall{ID: "call_1", Name: tt.tool.name, Arguments: map[string]any{"path": "/tmp"}}
+			risk, response, err := session.approveToolCall(context.Background(), call, call.Arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("approveToolCall() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if risk != tt.risk {
+				t.Errorf("risk = %q, want %q", risk, tt.risk)
+			}
+			if !tt.wantErr && response.Decision != tt.decision {
+				t.Errorf("Decision = %q, want %q", response.Decision, tt.decision)
+			}
+			if asked := tt.approver != nil && len(tt.approver.requests) > 0; asked != tt.asked {
+				t.Errorf("请求人工确认 = %v, want %v", asked, tt.asked)
+			}
+			if tt.asked && tt.approver.requests[0].Risk != tt.risk {
+				t.Errorf("确认请求中的风险等级 = %q, want %q", tt.approver.requests[0].Risk, tt.risk)
+			}
+		})
+	}
+}
+
+func TestDeniedToolIsNotExecuted(t *testing.T) {
+	tool := &fakeTool{name: "restart"}
+	adapter := &fakeAdapter{replies: []fakeReply{
+		callTool("call_1", "restart", map[string]any{"service": "nginx"}),
+		answer("没有执行"),
+	}}
+	session := NewSession(adapter, newFakeToolManager(tool), SessionConfig{Mode: "chat", ApprovalMode: ApprovalModeDeny})
+	if _, err := session.ProcessMessage(context.Background(), "重启 nginx"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if len(tool.calls) != 0 {
+		t.Errorf("被拒绝的工具不应执行: %v", tool.calls)
+	}
+	if trace := session.LastTurn().ToolCalls; len(trace) != 1 || trace[0].Approval != string(ApprovalDenied) {
+		t.Errorf("工具调用记录 = %+v", trace)
+	}
+}
+
+func TestEditedArgumentsAreRedacted(t *testing.T) {
+	redactor, err := redact.NewFromConfig(config.RedactionConfig{Enable: true})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	tool := &fakeTool{name: "run"}
+	approver := &fakeApprover{response: ApprovalResponse{
+		Decision:  ApprovalEdited,
+		Arguments: map[string]any{"command": "mysql password=hunter22"},
+	}}
+	adapter := &fakeAdapter{replies: []fakeReply{
+		callTool("call_1", "run", map[string]any{"command": "mysql"}),
+		answer("已执行"),
+	}}
+	session := NewSession(adapter, newFakeToolManager(tool), SessionConfig{Mode: "chat", Approver: approver, Redactor: redactor})
+	if _, err := session.ProcessMessage(context.Background(), "连接数据库"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	if len(tool.calls) != 1 || tool.calls[0]["command"] != "mysql password=hunter22" {
+		t.Fatalf("工具应使用修改后的参数执行: %v", tool.calls)
+	}
+	if len(adapter.requests) != 2 {
+		t.Fatalf("模型请求次数 = %d, want 2", len(adapter.requests))
+	}
+	sent := fmt.Sprint(adapter.requests[1])
+	if strings.Contains(sent, "hunter22") {
+		t.Errorf("修改后的参数应脱敏后再发送给模型: %s", sent)
+	}
+	if !strings.Contains(sent, "[REDACTED_PASSWORD_1]") {
+		t.Errorf("发送给模型的工具结果中应包含占位符: %s", sent)
+	}
+}
@@ -2,13 +2,17 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"ai-ops/internal/config"
 	"ai-ops/internal/llm"
 	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -29,8 +33,10 @@ func min(a, b int) int {
 type Message struct {
 	Content   string
 	IsUser    bool
+	IsSystem  bool // 系统通知（配置重载等）
 	Timestamp time.Time
-	Thinking  string // AI的思考过程
+	Thinking  string             // AI的思考过程
+	Tool      *toolTimelineEntry // 工具调用时间线条目（nil 表示普通消息）
 }
 
 // BubbleTeaModel 是新的聊天界面模型
@@ -44,24 +50,42 @@ type BubbleTeaModel struct {
 	ready      bool
 	quitting   bool
 	processing bool
+	cancelling bool // 已请求取消，等待后台对话退出
 	width      int
 	height     int
+	lastInput  string // 最近一次发送的用户输入，用于 /retry
+
+	pendingApproval *approvalRequestMsg // 等待用户确认的工具调用
+	editingApproval bool                // 是否正在编辑待确认调用的参数
+
+	runningTools map[string]*toolTimelineEntry // 正在执行的工具调用，按调用ID索引
+	modelRound   int                           // 本轮对话中正在进行的模型请求序号
+	status       SessionStatus                 // 状态栏展示的模型与用量
+	expandTools  bool                          // 是否展开工具调用的参数与结果
+
+	// cancelTurn 取消正在进行的对话，空闲时为 nil
+	cancelTurn context.CancelFunc
 
 	// AI相关
 	client      llm.ModelAdapter
 	toolManager tools.ToolManager
 	session     *Session
 
+	// send 向界面发送消息，用于后台任务实时展示进度（程序启动后设置）
+	send func(tea.Msg)
+
 	// 渲染器
 	renderer *glamour.TermRenderer
 
 	// 样式
-	userStyle     lipgloss.Style
-	aiStyle       lipgloss.Style
-	thinkingStyle lipgloss.Style
-	systemStyle   lipgloss.Style
-	inputStyle    lipgloss.Style
-	helpStyle     lipgloss.Style
+	userStyle       lipgloss.Style
+	aiStyle         lipgloss.Style
+	thinkingStyle   lipgloss.Style
+	systemStyle     lipgloss.Style
+	inputStyle      lipgloss.Style
+	helpStyle       lipgloss.Style
+	toolDetailStyle lipgloss.Style
+	statusBarStyle  lipgloss.Style
 }
 
 // chatProcessingMsg 表示正在处理AI响应
@@ -69,15 +93,27 @@ type chatProcessingMsg struct{}
 
 // chatResponseMsg 包含AI的响应
 type chatResponseMsg struct {
-	response string
-	err      error
+	response  string
+	err       error
+	cancelled bool     // 用户取消了本轮对话
+	notices   []string // 本轮对话产生的系统通知
+}
+
+// sessionEventMsg 会话执行过程中产生的事件
+type sessionEventMsg struct {
+	event Event
+}
+
+// configReloadMsg 表示配置文件已热重载
+type configReloadMsg struct {
+	result llm.ReloadResult
 }
 
 // NewBubbleTeaModel 创建新的Bubble Tea聊天模型
 func NewBubbleTeaModel(client llm.ModelAdapter, toolManager tools.ToolManager, config SessionConfig) (*BubbleTeaModel, error) {
 	// 创建textarea
 	ta := textarea.New()
-	ta.Placeholder = "输入您的消息... (Ctrl+S 发送，Ctrl+C 退出)"
+	ta.Placeholder = "输入您的消息... (Ctrl+S 发送，/help 查看命令，Esc 取消请求，Ctrl+C 退出)"
 	ta.Focus()
 	ta.Prompt = "┃ "
 	ta.CharLimit = 2000
@@ -129,28 +165,91 @@ func NewBubbleTeaModel(client llm.ModelAdapter, toolManager tools.ToolManager, c
 		Foreground(lipgloss.Color("#626262")).
 		MarginLeft(1)
 
+	toolDetailStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#888888")).
+		MarginLeft(4)
+
+	statusBarStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#c0c0c0")).
+		Background(lipgloss.Color("#303030")).
+		Padding(0, 1).
+		MarginLeft(1)
+
 	m := &BubbleTeaModel{
-		viewport:      vp,
-		textarea:      ta,
-		messages:      make([]Message, 0),
-		client:        client,
-		toolManager:   toolManager,
-		session:       NewSession(client, toolManager, config),
-		renderer:      renderer,
-		userStyle:     userStyle,
-		aiStyle:       aiStyle,
-		thinkingStyle: thinkingStyle,
-		systemStyle:   systemStyle,
-		inputStyle:    inputStyle,
-		helpStyle:     helpStyle,
+		viewport:        vp,
+		textarea:        ta,
+		messages:        make([]Message, 0),
+		client:          client,
+		toolManager:     toolManager,
+		runningTools:    make(map[string]*toolTimelineEntry),
+		renderer:        renderer,
+		userStyle:       userStyle,
+		aiStyle:         aiStyle,
+		thinkingStyle:   thinkingStyle,
+		systemStyle:     systemStyle,
+		inputStyle:      inputStyle,
+		helpStyle:       helpStyle,
+		toolDetailStyle: toolDetailStyle,
+		statusBarStyle:  statusBarStyle,
 	}
 
+	m.session = NewSession(client, toolManager, config)
+	// 会话事件转发到界面，实时显示工具调用与智能体进度
+	m.session.Subscribe(ObserverFunc(func(event Event) {
+		if m.send != nil {
+			m.send(sessionEventMsg{event: event})
+		}
+	}))
+
+	m.refreshStatus()
+
 	// 添加欢迎消息
 	m.addWelcomeMessage()
 
+	// 恢复历史会话的消息记录
+	if config.Resume != nil {
+		m.restoreScrollback(config.Resume)
+	}
+
 	return m, nil
 }
 
+// restoreScrollback 根据会话记录重建消息历史
+func (m *BubbleTeaModel) restoreScrollback(transcript *Transcript) {
+	toolEntries := restoreToolEntries(transcript)
+	for _, entry := range transcript.Entries {
+		msg := entry.Message
+		if entry.Internal {
+			// 智能体的控制提示不展示
+			continue
+		}
+		switch msg.Role {
+		case "user":
+			m.messages = append(m.messages, Message{Content: msg.Content, IsUser: true, Timestamp: entry.Time})
+		case "assistant":
+			for _, tc := range msg.ToolCalls {
+				m.messages = append(m.messages, Message{
+					Tool:      toolEntries[tc.ID],
+					IsSystem:  true,
+					Timestamp: entry.Time,
+				})
+			}
+			if len(msg.ToolCalls) == 0 && msg.Content != "" {
+				restored := ExtractThinking(msg.Content)
+				m.messages = append(m.messages, Message{
+					Content:   restored.Content,
+					Thinking:  restored.Thinking,
+					Timestamp: entry.Time,
+				})
+			}
+		}
+	}
+
+	summary := transcript.Summary()
+	m.addSystemMessage(fmt.Sprintf("📂 已恢复会话 %s（%d 轮对话，最后更新于 %s）",
+		summary.ID, summary.Turns, summary.UpdatedAt.Format("2006-01-02 15:04:05")))
+}
+
 // addWelcomeMessage 添加欢迎消息
 func (m *BubbleTeaModel) addWelcomeMessage() {
 	mode := "普通对话模式"
@@ -165,12 +264,20 @@ func (m *BubbleTeaModel) addWelcomeMessage() {
 	if m.session.config.ShowThinking {
 		welcomeMsg += "\n💭 思考过程显示已开启"
 	}
+	if m.session.recorder != nil {
+		welcomeMsg += fmt.Sprintf("\n💾 会话ID: %s（可通过 ai-ops chat --resume 恢复）", m.session.ID())
+	}
 	welcomeMsg += "\n\n💡 快捷键提示：\n" +
 		"  • Ctrl+S - 发送消息\n" +
 		"  • Enter - 换行\n" +
-		"  • Ctrl+C - 退出程序\n" +
+		"  • Esc - 取消正在进行的请求\n" +
+		"  • Ctrl+C - 退出程序（处理中先取消请求，再按一次退出）\n" +
 		"  • Ctrl+L - 清空历史\n" +
-		"  • Ctrl+U/Ctrl+D - 滚动消息历史"
+		"  • Ctrl+T - 切换工具调用模式（auto/required/none/指定工具）\n" +
+		"  • Ctrl+O - 导出会话（Markdown/HTML/JSON，见 /export）\n" +
+		"  • Ctrl+E - 展开/折叠工具调用的参数与结果\n" +
+		"  • Ctrl+U/Ctrl+D - 滚动消息历史\n" +
+		"  • /help - 查看斜杠命令（Tab 补全，Enter 执行）"
 
 	m.messages = append(m.messages, Message{
 		Content:   welcomeMsg,
@@ -202,7 +309,11 @@ func (m *BubbleTeaModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		headerHeight := 1
 		helpHeight := 3
 		inputHeight := 5
-		viewportHeight := m.height - headerHeight - helpHeight - inputHeight - 2
+		statusBarHeight := 0
+		if m.hasStatusBar() {
+			statusBarHeight = 1
+		}
+		viewportHeight := m.height - headerHeight - helpHeight - inputHeight - statusBarHeight - 2
 
 		m.viewport.Width = m.width - 2
 		m.viewport.Height = viewportHeight
@@ -217,8 +328,20 @@ func (m *BubbleTeaModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateViewport()
 
 	case tea.KeyMsg:
+		// 处理中按 Esc 或首次按 Ctrl+C 取消请求，编辑确认参数时 Esc 仅返回
+		if m.processing && !m.cancelling && (msg.Type == tea.KeyCtrlC || (msg.Type == tea.KeyEsc && !m.editingApproval)) {
+			m.cancelProcessing()
+			return m, nil
+		}
+
+		if m.pendingApproval != nil && msg.Type != tea.KeyCtrlC {
+			return m.handleApprovalKey(msg)
+		}
+
 		switch {
 		case msg.Type == tea.KeyCtrlC:
+			// 退出前拒绝待确认的调用，避免后台对话阻塞
+			m.resolveApproval(ApprovalResponse{Decision: ApprovalDenied, Reason: "用户退出了对话"})
 			m.quitting = true
 			return m, tea.Quit
 
@@ -232,6 +355,38 @@ func (m *BubbleTeaModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// 发送消息
 			return m.sendMessage()
 
+		case msg.Type == tea.KeyEnter && m.isCommandInput():
+			// 单行的斜杠命令直接执行
+			return m.sendMessage()
+
+		case msg.Type == tea.KeyTab && m.isCompletableInput():
+			// 补全斜杠命令或文件引用
+			m.completeInput()
+			return m, nil
+
+		case msg.Type == tea.KeyCtrlO:
+			// 按配置的默认格式导出会话，对话在后台修改会话时不能读取
+			if m.processing {
+				m.addSystemMessage("⏳ 正在处理中，请在本轮对话结束后再导出会话")
+				return m, nil
+			}
+			exportSession(m, "", DefaultExportOptions())
+			return m, nil
+
+		case msg.Type == tea.KeyCtrlE:
+			// 展开或折叠工具调用详情
+			m.toggleToolDetails()
+			return m, nil
+
+		case msg.Type == tea.KeyCtrlT:
+			// 切换工具调用模式，与导出相同，只在空闲时修改会话
+			if m.processing {
+				m.addSystemMessage("⏳ 正在处理中，请在本轮对话结束后再切换工具调用模式")
+				return m, nil
+			}
+			m.cycleToolChoice()
+			return m, nil
+
 		case msg.Type == tea.KeyCtrlU:
 			// 向上滚动
 			m.viewport.LineUp(5)
@@ -252,12 +407,46 @@ func (m *BubbleTeaModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case chatResponseMsg:
 		// 处理AI响应
 		m.processing = false
-		if msg.err != nil {
+		m.cancelling = false
+		m.modelRound = 0
+		m.refreshStatus()
+		if m.cancelTurn != nil {
+			m.cancelTurn()
+			m.cancelTurn = nil
+		}
+		// 等待确认时超时的调用已失效
+		m.pendingApproval = nil
+		m.editingApproval = false
+		for _, notice := range msg.notices {
+			m.addSystemMessage(notice)
+		}
+		if msg.cancelled {
+			m.addSystemMessage("⏹️ 已取消，本轮对话未保留在历史记录中（/retry 可重新发送）")
+		} else if msg.err != nil {
 			m.addErrorMessage(fmt.Sprintf("错误: %v", msg.err))
 		} else {
 			m.addAIMessage(msg.response)
 		}
 		return m, nil
+
+	case sessionEventMsg:
+		m.status = msg.event.Status
+		m.handleSessionEvent(msg.event)
+		return m, nil
+
+	case approvalRequestMsg:
+		// 工具调用需要确认，暂停输入等待用户操作
+		m.pendingApproval = &msg
+		m.editingApproval = false
+		m.addSystemMessage(formatApprovalRequest(msg.request) + "\n按 Y 批准，N 拒绝，E 编辑参数")
+		return m, nil
+
+	case configReloadMsg:
+		// 显示配置热重载结果，会话将在下一轮对话切换适配器
+		if msg.result.HasChanges() {
+			m.addSystemMessage("🔄 配置已重新加载 - " + msg.result.Summary())
+		}
+		return m, nil
 	}
 
 	// 更新子组件
@@ -285,8 +474,16 @@ func (m *BubbleTeaModel) View() string {
 
 	// 处理状态
 	var statusLine string
-	if m.processing {
-		statusLine = m.systemStyle.Render("🤔 AI正在思考...")
+	if m.pendingApproval != nil {
+		if m.editingApproval {
+			statusLine = m.systemStyle.Render("✏️ 编辑参数（JSON），Ctrl+S 提交并执行，Esc 返回")
+		} else {
+			statusLine = m.systemStyle.Render(fmt.Sprintf("⚠️ 等待确认 %s: Y 批准 / N 拒绝 / E 编辑参数", m.pendingApproval.request.ToolName))
+		}
+	} else if m.cancelling {
+		statusLine = m.systemStyle.Render("⏹️ 正在取消...")
+	} else if m.processing {
+		statusLine = m.systemStyle.Render("🤔 AI正在思考...（Esc 取消）")
 	} else {
 		statusLine = ""
 	}
@@ -294,8 +491,12 @@ func (m *BubbleTeaModel) View() string {
 	// 输入区域
 	inputArea := m.inputStyle.Render(m.textarea.View())
 
+	// 斜杠命令提示
+	suggestion := m.commandSuggestion()
+
 	// 帮助信息
-	help := m.helpStyle.Render("Ctrl+S: 发送 | Ctrl+C: 退出 | Ctrl+L: 清空 | Ctrl+U/D: 滚动")
+	help := m.helpStyle.Render(fmt.Sprintf("Ctrl+S: 发送 | Esc: 取消 | Ctrl+C: 退出 | Ctrl+L: 清空 | Ctrl+O: 导出 | Ctrl+E: 工具详情 | Ctrl+T: 工具[%s] | Ctrl+U/D: 滚动 | /help: 命令",
+		m.session.GetToolChoice()))
 
 	// 组合所有部分
 	var sections []string
@@ -305,11 +506,145 @@ func (m *BubbleTeaModel) View() string {
 		sections = append(sections, statusLine)
 	}
 	sections = append(sections, inputArea)
+	if suggestion != "" {
+		sections = append(sections, suggestion)
+	}
+	if m.hasStatusBar() {
+		sections = append(sections, m.renderStatusBar())
+	}
 	sections = append(sections, help)
 
 	return strings.Join(sections, "\n")
 }
 
+// handleSessionEvent 在界面中展示会话事件，对话结果由 chatResponseMsg 处理
+func (m *BubbleTeaModel) handleSessionEvent(event Event) {
+	switch event.Type {
+	case EventModelRequest:
+		m.modelRound = event.Round
+	case EventToolStarted, EventToolFinished, EventToolFailed:
+		m.handleToolEvent(event)
+	case EventAgentPlan:
+		m.addSystemMessage("📋 执行计划:\n" + event.Plan)
+	case EventAgentStep:
+		m.addSystemMessage(FormatStep(*event.Step))
+	}
+}
+
+// handleApprovalKey 处理工具调用确认期间的按键
+func (m *BubbleTeaModel) handleApprovalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editingApproval {
+		switch msg.Type {
+		case tea.KeyCtrlS:
+			var args map[string]any
+			if err := json.Unmarshal([]byte(m.textarea.Value()), &args); err != nil {
+				m.addErrorMessage(fmt.Sprintf("参数不是有效的 JSON 对象: %v", err))
+				return m, nil
+			}
+			m.textarea.Reset()
+			m.addSystemMessage(fmt.Sprintf("✏️ 已修改参数并批准执行 %s", m.pendingApproval.request.ToolName))
+			m.resolveApproval(ApprovalResponse{Decision: ApprovalEdited, Arguments: args})
+			return m, nil
+		case tea.KeyEsc:
+			m.editingApproval = false
+			m.textarea.Reset()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.textarea, cmd = m.textarea.Update(msg)
+		return m, cmd
+	}
+
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		m.addSystemMessage(fmt.Sprintf("✅ 已批准执行 %s", m.pendingApproval.request.ToolName))
+		m.resolveApproval(ApprovalResponse{Decision: ApprovalApproved})
+	case "n":
+		m.addSystemMessage(fmt.Sprintf("⛔ 已拒绝执行 %s", m.pendingApproval.request.ToolName))
+		m.resolveApproval(ApprovalResponse{Decision: ApprovalDenied})
+	case "e":
+		args, _ := json.MarshalIndent(m.pendingApproval.request.Arguments, "", "  ")
+		m.editingApproval = true
+		m.textarea.SetValue(string(args))
+	}
+	return m, nil
+}
+
+// resolveApproval 将确认结果返回给等待中的会话
+func (m *BubbleTeaModel) resolveApproval(response ApprovalResponse) {
+	if m.pendingApproval == nil {
+		return
+	}
+	m.pendingApproval.reply <- response
+	m.pendingApproval = nil
+	m.editingApproval = false
+}
+
+// cycleToolChoice 依次切换工具调用模式：auto → required → none → 各内置工具
+func (m *BubbleTeaModel) cycleToolChoice() {
+	choices := []llm.ToolChoice{
+		{Mode: llm.ToolChoiceAuto},
+		{Mode: llm.ToolChoiceRequired},
+		{Mode: llm.ToolChoiceNone},
+	}
+
+	// MCP 工具数量可能很多，只将内置工具加入轮换
+	var names []string
+	for _, tool := range m.toolManager.GetTools() {
+		if tool.Type() != "mcp" {
+			names = append(names, tool.ID())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		choices = append(choices, llm.ToolChoice{Mode: llm.ToolChoiceTool, Name: name})
+	}
+
+	current := m.session.GetToolChoice()
+	next := choices[0]
+	for i, choice := range choices {
+		if choice.String() == current.String() {
+			next = choices[(i+1)%len(choices)]
+			break
+		}
+	}
+
+	m.session.SetToolChoice(next)
+	m.addSystemMessage(fmt.Sprintf("🔧 工具调用模式: %s", next))
+}
+
+// Session 返回当前会话
+func (m *BubbleTeaModel) Session() *Session {
+	return m.session
+}
+
+// Notify 显示系统通知
+func (m *BubbleTeaModel) Notify(text string) {
+	m.addSystemMessage(text)
+}
+
+// NotifyError 显示错误信息
+func (m *BubbleTeaModel) NotifyError(text string) {
+	m.addErrorMessage(text)
+}
+
+// Clear 清空消息记录并重新显示欢迎消息
+func (m *BubbleTeaModel) Clear() {
+	m.lastInput = ""
+	m.messages = make([]Message, 0)
+	m.addWelcomeMessage()
+}
+
+// LastInput 返回上一轮发送的用户输入
+func (m *BubbleTeaModel) LastInput() string {
+	return m.lastInput
+}
+
+// Submit 在后台处理用户输入
+func (m *BubbleTeaModel) Submit(input string) tea.Cmd {
+	return m.startProcessing(input)
+}
+
 // addUserMessage 添加用户消息
 func (m *BubbleTeaModel) addUserMessage(content string) {
 	m.messages = append(m.messages, Message{
@@ -352,6 +687,16 @@ func (m *BubbleTeaModel) addErrorMessage(content string) {
 	m.updateViewport()
 }
 
+// addSystemMessage 添加系统通知
+func (m *BubbleTeaModel) addSystemMessage(content string) {
+	m.messages = append(m.messages, Message{
+		Content:   content,
+		IsSystem:  true,
+		Timestamp: time.Now(),
+	})
+	m.updateViewport()
+}
+
 // updateViewport 更新视口内容
 func (m *BubbleTeaModel) updateViewport() {
 	var content strings.Builder
@@ -364,7 +709,13 @@ func (m *BubbleTeaModel) updateViewport() {
 		// 时间戳
 		timestamp := msg.Timestamp.Format("15:04:05")
 
-		if msg.IsUser {
+		if msg.Tool != nil {
+			// 工具调用时间线条目
+			content.WriteString(m.renderToolEntry(msg) + "\n")
+		} else if msg.IsSystem {
+			// 系统通知 - 单行提示
+			content.WriteString(m.systemStyle.Render(fmt.Sprintf("[%s] %s", timestamp, msg.Content)) + "\n")
+		} else if msg.IsUser {
 			// 用户消息 - 左对齐布局
 			header := m.userStyle.Render(fmt.Sprintf("You [%s]:", timestamp))
 			content.WriteString(header + "\n")
@@ -450,42 +801,188 @@ func (m *BubbleTeaModel) sendMessage() (tea.Model, tea.Cmd) {
 	// 清空输入框
 	m.textarea.Reset()
 
+	// 已注册的斜杠命令交给命令处理，其余输入（如 /etc/hosts 开头的问题）按普通消息发送
+	if cmd, args, ok := parseSlashCommand(input); ok {
+		next := cmd.Run(m, args)
+		m.refreshStatus()
+		return m, next
+	}
+
 	// 添加用户消息
 	m.addUserMessage(input)
+	m.lastInput = input
+
+	return m, m.startProcessing(input)
+}
+
+// startProcessing 开始处理用户输入，每轮对话使用可取消的上下文
+func (m *BubbleTeaModel) startProcessing(input string) tea.Cmd {
+	timeout := time.Duration(config.GetConfig().AI.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
-	// 开始处理
 	m.processing = true
-	return m, tea.Batch(
+	m.cancelling = false
+	m.cancelTurn = cancel
+	return tea.Batch(
 		tea.Cmd(func() tea.Msg { return chatProcessingMsg{} }),
-		m.processUserMessage(input),
+		m.processUserMessage(ctx, input),
 	)
 }
 
-// processUserMessage 处理用户消息
-func (m *BubbleTeaModel) processUserMessage(input string) tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		timeout := time.Duration(config.GetConfig().AI.Timeout) * time.Second
-		if timeout <= 0 {
-			timeout = 2 * time.Minute
+// cancelProcessing 取消正在进行的对话，后台对话回滚本轮消息后返回 chatResponseMsg
+func (m *BubbleTeaModel) cancelProcessing() {
+	if m.cancelTurn == nil {
+		return
+	}
+	m.cancelling = true
+	m.cancelTurn()
+	// 等待确认的调用随上下文取消而失效，输入框中正在编辑的参数一并丢弃
+	if m.editingApproval {
+		m.textarea.Reset()
+	}
+	m.pendingApproval = nil
+	m.editingApproval = false
+	m.addSystemMessage("⏹️ 正在取消当前请求...")
+}
+
+// isCommandInput 输入框中是否为单行的已注册斜杠命令
+func (m *BubbleTeaModel) isCommandInput() bool {
+	input := m.textarea.Value()
+	if strings.Contains(input, "\n") {
+		return false
+	}
+	_, _, ok := parseSlashCommand(strings.TrimSpace(input))
+	return ok
+}
+
+// isCompletableInput 输入框中是否为斜杠命令或以 @文件 引用结尾
+func (m *BubbleTeaModel) isCompletableInput() bool {
+	input := m.textarea.Value()
+	_, isReference := trailingFileReference(input)
+	return strings.HasPrefix(input, "/") || isReference
+}
+
+// completeInput 补全输入框中的斜杠命令或文件引用，唯一候选时直接补全，多个候选时补全公共前缀
+func (m *BubbleTeaModel) completeInput() {
+	input := m.textarea.Value()
+	candidates := completeChatInput(m, input)
+	switch {
+	case len(candidates) == 1 && strings.HasSuffix(candidates[0], "/") && !strings.HasPrefix(input, "/"):
+		// 目录继续补全其中的文件
+		m.textarea.SetValue(candidates[0])
+	case len(candidates) == 1:
+		m.textarea.SetValue(candidates[0] + " ")
+	case len(candidates) > 1:
+		if prefix := commonPrefix(candidates); len(prefix) > len(input) {
+			m.textarea.SetValue(prefix)
 		}
+	}
+}
+
+// commandSuggestion 根据输入框内容生成斜杠命令提示
+func (m *BubbleTeaModel) commandSuggestion() string {
+	input := m.textarea.Value()
+	if prefix, ok := trailingFileReference(input); ok {
+		return m.fileReferenceSuggestion(input, prefix)
+	}
+	if !strings.HasPrefix(input, "/") || strings.Contains(input, "\n") {
+		return ""
+	}
+
+	// 已输入完整命令名称时显示用法
+	if cmd, _, ok := parseSlashCommand(input); ok && !strings.HasSuffix(input, " ") {
+		return m.helpStyle.Render(fmt.Sprintf("%s  %s", cmd.Usage(), cmd.Description))
+	}
+
+	candidates := completeSlashCommand(m, input)
+	if len(candidates) == 0 {
+		return ""
+	}
+	const maxSuggestions = 8
+	if len(candidates) > maxSuggestions {
+		candidates = append(candidates[:maxSuggestions], "...")
+	}
+	return m.helpStyle.Render("Tab 补全: " + strings.Join(candidates, "  "))
+}
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
+// fileReferenceSuggestion 显示 @文件 引用的补全候选
+func (m *BubbleTeaModel) fileReferenceSuggestion(input, prefix string) string {
+	candidates := completeFileReference(input)
+	if len(candidates) == 0 {
+		return ""
+	}
+	dir, _ := filepath.Split(prefix)
+	names := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		names = append(names, strings.TrimPrefix(candidate[strings.LastIndex(candidate, "@")+1:], dir))
+	}
+	const maxSuggestions = 8
+	if len(names) > maxSuggestions {
+		names = append(names[:maxSuggestions], "...")
+	}
+	return m.helpStyle.Render("Tab 补全: " + strings.Join(names, "  "))
+}
 
-		response, err := m.session.ProcessMessage(ctx, input)
-		return chatResponseMsg{response: response, err: err}
+// processUserMessage 处理用户消息
+func (m *BubbleTeaModel) processUserMessage(ctx context.Context, input string) tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		var msg chatResponseMsg
+		// 展开 @文件 与 !命令 引用，命令在界面中确认后执行
+		expanded, err := m.session.ExpandReferences(ctx, input)
+		switch {
+		case err != nil:
+			msg = chatResponseMsg{err: err, notices: m.session.TakeNotices()}
+		case m.session.Mode() == "agent":
+			msg = m.runAgent(ctx, expanded)
+		default:
+			response, err := m.session.ProcessMessage(ctx, expanded)
+			msg = chatResponseMsg{response: response, err: err, notices: m.session.TakeNotices()}
+		}
+		// 超时仍按错误显示，只有用户主动取消才标记为已取消
+		msg.cancelled = msg.err != nil && ctx.Err() == context.Canceled
+		return msg
 	})
 }
 
+// runAgent 以智能体方式执行任务，计划和每个步骤通过会话事件实时显示在界面中
+func (m *BubbleTeaModel) runAgent(ctx context.Context, goal string) chatResponseMsg {
+	report, err := m.session.RunAgent(ctx, goal, AgentHooks{})
+	if err != nil {
+		return chatResponseMsg{err: err, notices: m.session.TakeNotices()}
+	}
+	return chatResponseMsg{response: report.Markdown(), notices: m.session.TakeNotices()}
+}
+
 // RunBubbleTeaChat 启动新的Bubble Tea聊天界面
-func RunBubbleTeaChat(client llm.ModelAdapter, toolManager tools.ToolManager, config SessionConfig) error {
-	model, err := NewBubbleTeaModel(client, toolManager, config)
+func RunBubbleTeaChat(client llm.ModelAdapter, toolManager tools.ToolManager, sessionConfig SessionConfig) error {
+	// 有风险的工具调用在界面中确认
+	approver := &tuiApprover{}
+	if sessionConfig.Approver == nil {
+		sessionConfig.Approver = approver
+	}
+
+	model, err := NewBubbleTeaModel(client, toolManager, sessionConfig)
 	if err != nil {
 		return fmt.Errorf("初始化聊天界面失败: %w", err)
 	}
 
 	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	approver.send = p.Send
+	model.send = p.Send
+
+	// 监听配置文件变更，热重载模型适配器
+	stopWatch := watchConfig(func(result llm.ReloadResult) {
+		p.Send(configReloadMsg{result: result})
+	})
+	defer stopWatch()
+
 	_, err = p.Run()
+	if closeErr := model.session.Close(); closeErr != nil {
+		util.Warnw("关闭会话文件失败", map[string]any{"error": closeErr.Error()})
+	}
 	return err
 }
 
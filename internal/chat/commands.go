@@ -0,0 +1,182 @@
+package chat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"ai-ops/internal/util/errors"
+)
+
+// CommandHost 斜杠命令的执行环境，由全屏界面和行模式界面分别实现
+type CommandHost interface {
+	// Session 返回当前会话
+	Session() *Session
+	// Notify 显示系统通知
+	Notify(text string)
+	// NotifyError 显示错误信息
+	NotifyError(text string)
+	// Clear 清空界面中的消息记录和上一轮输入
+	Clear()
+	// LastInput 返回上一轮发送的用户输入
+	LastInput() string
+	// Submit 发送用户输入；全屏界面返回执行对话的 tea.Cmd，行模式同步执行后返回 nil
+	Submit(input string) tea.Cmd
+}
+
+// SlashCommand 聊天界面中以 "/" 开头的命令
+type SlashCommand struct {
+	Name        string // 命令名称，不含 "/"
+	Args        string // 参数说明，如 "<name>"
+	Description string // 命令说明
+	// Complete 返回参数的补全候选（可选），args 为已输入的参数，候选需包含完整参数
+	Complete func(h CommandHost, args string) []string
+	// Run 执行命令，args 为命令名之后的参数；返回的 tea.Cmd 可用于启动异步任务
+	Run func(h CommandHost, args string) tea.Cmd
+}
+
+// Usage 返回命令的用法说明
+func (c SlashCommand) Usage() string {
+	if c.Args == "" {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + c.Args
+}
+
+// slashCommandRegistry 斜杠命令注册表
+type slashCommandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]SlashCommand
+}
+
+var (
+	slashCommands     *slashCommandRegistry
+	slashCommandsOnce sync.Once
+)
+
+// commandRegistry 返回全局命令注册表，首次调用时注册内置命令
+func commandRegistry() *slashCommandRegistry {
+	slashCommandsOnce.Do(func() {
+		slashCommands = &slashCommandRegistry{commands: make(map[string]SlashCommand)}
+		for _, cmd := range builtinSlashCommands() {
+			_ = slashCommands.register(cmd)
+		}
+	})
+	return slashCommands
+}
+
+// RegisterSlashCommand 注册斜杠命令，命令名称不可重复
+func RegisterSlashCommand(cmd SlashCommand) error {
+	return commandRegistry().register(cmd)
+}
+
+func (r *slashCommandRegistry) register(cmd SlashCommand) error {
+	if cmd.Name == "" || cmd.Run == nil {
+		return errors.NewError(errors.ErrCodeInvalidParameters, "命令名称和执行函数不能为空")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.commands[cmd.Name]; exists {
+		return errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "命令已存在", "/"+cmd.Name)
+	}
+	r.commands[cmd.Name] = cmd
+	return nil
+}
+
+// get 按名称查找命令
+func (r *slashCommandRegistry) get(name string) (SlashCommand, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, exists := r.commands[name]
+	return cmd, exists
+}
+
+// list 返回按名称排序的所有命令
+func (r *slashCommandRegistry) list() []SlashCommand {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	commands := make([]SlashCommand, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// parseSlashCommand 将输入解析为已注册的命令和参数，非命令输入返回 false
+func parseSlashCommand(input string) (SlashCommand, string, bool) {
+	if !strings.HasPrefix(input, "/") {
+		return SlashCommand{}, "", false
+	}
+	name, args := input[1:], ""
+	// 参数可以跨多行，如 /system 后的多行提示词
+	if idx := strings.IndexAny(name, " \t\n"); idx >= 0 {
+		name, args = name[:idx], name[idx+1:]
+	}
+	cmd, exists := commandRegistry().get(name)
+	if !exists {
+		return SlashCommand{}, "", false
+	}
+	return cmd, strings.TrimSpace(args), true
+}
+
+// completeSlashCommand 返回输入的补全候选，每个候选都是完整的输入内容
+func completeSlashCommand(h CommandHost, input string) []string {
+	if !strings.HasPrefix(input, "/") || strings.Contains(input, "\n") {
+		return nil
+	}
+
+	name, args, hasArgs := strings.Cut(input[1:], " ")
+	var candidates []string
+	if !hasArgs {
+		// 补全命令名称
+		for _, cmd := range commandRegistry().list() {
+			if strings.HasPrefix(cmd.Name, name) {
+				candidates = append(candidates, "/"+cmd.Name)
+			}
+		}
+		return candidates
+	}
+
+	// 补全命令参数
+	cmd, exists := commandRegistry().get(name)
+	if !exists || cmd.Complete == nil {
+		return nil
+	}
+	args = strings.TrimLeft(args, " ")
+	for _, candidate := range cmd.Complete(h, args) {
+		if strings.HasPrefix(candidate, args) {
+			candidates = append(candidates, "/"+name+" "+candidate)
+		}
+	}
+	return candidates
+}
+
+// completeChatInput 补全斜杠命令或输入末尾的 @文件 引用
+func completeChatInput(h CommandHost, input string) []string {
+	if strings.HasPrefix(input, "/") {
+		return completeSlashCommand(h, input)
+	}
+	return completeFileReference(input)
+}
+
+// commonPrefix 返回字符串列表的最长公共前缀
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,328 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+)
+
+// builtinSlashCommands 返回内置的斜杠命令
+func builtinSlashCommands() []SlashCommand {
+	return []SlashCommand{
+		{
+			Name:        "help",
+			Description: "显示可用命令",
+			Run:         runHelpCommand,
+		},
+		{
+			Name:        "model",
+			Args:        "[name]",
+			Description: "查看或切换模型，保留对话历史",
+			Complete: func(h CommandHost, args string) []string {
+				return llm.ListAdapters()
+			},
+			Run: runModelCommand,
+		},
+		{
+			Name:        "mode",
+			Args:        "[chat|agent]",
+			Description: "查看或切换对话模式",
+			Complete: func(h CommandHost, args string) []string {
+				return []string{"chat", "agent"}
+			},
+			Run: runModeCommand,
+		},
+		{
+			Name:        "profile",
+			Args:        "[name]",
+			Description: "查看或切换提示词模板",
+			Complete:    completeProfileCommand,
+			Run:         runProfileCommand,
+		},
+		{
+			Name:        "tools",
+			Args:        "[enable|disable <name>]",
+			Description: "列出工具，或在本会话中启用/禁用工具",
+			Complete:    completeToolsCommand,
+			Run:         runToolsCommand,
+		},
+		{
+			Name:        "system",
+			Args:        "[prompt|reset]",
+			Description: "查看或修改系统提示词，reset 恢复默认",
+			Complete: func(h CommandHost, args string) []string {
+				return []string{"reset"}
+			},
+			Run: runSystemCommand,
+		},
+		{
+			Name:        "save",
+			Args:        "[title]",
+			Description: "立即保存会话，可设置会话标题",
+			Run:         runSaveCommand,
+		},
+		{
+			Name:        "export",
+			Args:        "[markdown|html|json|path]",
+			Description: "导出会话（含工具调用与用量），格式按扩展名推断",
+			Complete: func(h CommandHost, args string) []string {
+				return ExportFormats()
+			},
+			Run: runExportCommand,
+		},
+		{
+			Name:        "clear",
+			Description: "清空对话历史和屏幕",
+			Run:         runClearCommand,
+		},
+		{
+			Name:        "retry",
+			Description: "重新生成上一轮回答",
+			Run:         runRetryCommand,
+		},
+	}
+}
+
+// runHelpCommand 显示所有已注册的命令
+func runHelpCommand(h CommandHost, args string) tea.Cmd {
+	var b strings.Builder
+	b.WriteString("📖 可用命令（Tab 补全）:")
+	for _, cmd := range commandRegistry().list() {
+		fmt.Fprintf(&b, "\n  • %-28s %s", cmd.Usage(), cmd.Description)
+	}
+	h.Notify(b.String())
+	return nil
+}
+
+// runModelCommand 切换模型适配器
+func runModelCommand(h CommandHost, args string) tea.Cmd {
+	if args == "" {
+		var b strings.Builder
+		fmt.Fprintf(&b, "🧠 当前模型: %s (%s)\n可用模型:", h.Session().ModelName(), h.Session().client.GetModelInfo().Name)
+		for _, name := range llm.ListAdapters() {
+			marker := " "
+			if name == h.Session().ModelName() {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, "\n  %s %s", marker, name)
+		}
+		h.Notify(b.String())
+		return nil
+	}
+
+	if err := h.Session().SwitchModel(args); err != nil {
+		h.NotifyError(fmt.Sprintf("切换模型失败: %v", err))
+		return nil
+	}
+	h.Notify(fmt.Sprintf("🧠 已切换到模型 %s (%s)，对话历史已保留", args, h.Session().client.GetModelInfo().Name))
+	return nil
+}
+
+// runModeCommand 切换对话模式
+func runModeCommand(h CommandHost, args string) tea.Cmd {
+	if args == "" {
+		h.Notify(fmt.Sprintf("🎛️ 当前模式: %s", h.Session().Mode()))
+		return nil
+	}
+
+	if err := h.Session().SetMode(args); err != nil {
+		h.NotifyError(fmt.Sprintf("切换模式失败: %v", err))
+		return nil
+	}
+	notice := fmt.Sprintf("🎛️ 已切换到 %s 模式", args)
+	if h.Session().HasCustomSystemPrompt() {
+		notice += "（当前使用自定义系统提示词，可通过 /system reset 恢复默认）"
+	}
+	h.Notify(notice)
+	return nil
+}
+
+// completeProfileCommand 补全提示词模板名称
+func completeProfileCommand(h CommandHost, args string) []string {
+	profiles, err := h.Session().Profiles()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// runProfileCommand 查看或切换提示词模板
+func runProfileCommand(h CommandHost, args string) tea.Cmd {
+	if args == "" {
+		profiles, err := h.Session().Profiles()
+		if err != nil {
+			h.NotifyError(fmt.Sprintf("读取提示词模板失败: %v", err))
+			return nil
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "🎭 当前提示词模板: %s\n可用模板:", h.Session().Profile())
+		for _, profile := range profiles {
+			marker := " "
+			if profile.Name == h.Session().Profile() {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, "\n  %s %s (%s)", marker, profile.Name, profile.Source)
+		}
+		h.Notify(b.String())
+		return nil
+	}
+
+	if err := h.Session().SetProfile(args); err != nil {
+		h.NotifyError(fmt.Sprintf("切换提示词模板失败: %v", err))
+		return nil
+	}
+	notice := fmt.Sprintf("🎭 已切换到提示词模板 %s", args)
+	if h.Session().HasCustomSystemPrompt() {
+		notice += "（当前使用自定义系统提示词，可通过 /system reset 恢复为模板生成的提示词）"
+	}
+	h.Notify(notice)
+	return nil
+}
+
+// completeToolsCommand 补全 /tools 的子命令和工具名称
+func completeToolsCommand(h CommandHost, args string) []string {
+	action, _, hasName := strings.Cut(args, " ")
+	if !hasName {
+		return []string{"enable", "disable"}
+	}
+
+	var candidates []string
+	for _, status := range h.Session().Tools() {
+		// 只提示状态会发生变化的工具
+		if (action == "enable") != status.Enabled {
+			candidates = append(candidates, action+" "+status.Name)
+		}
+	}
+	return candidates
+}
+
+// runToolsCommand 列出工具或修改工具启用状态
+func runToolsCommand(h CommandHost, args string) tea.Cmd {
+	if args == "" {
+		statuses := h.Session().Tools()
+		if len(statuses) == 0 {
+			h.Notify("🔧 没有可用的工具")
+			return nil
+		}
+		var b strings.Builder
+		b.WriteString("🔧 工具列表（✅ 启用 / ⛔ 禁用，[变更]/[破坏性] 工具执行前需要确认）:")
+		for _, status := range statuses {
+			mark := "✅"
+			if !status.Enabled {
+				mark = "⛔"
+			}
+			fmt.Fprintf(&b, "\n  %s %s [%s] - %s", mark, status.Name, status.Risk.Label(), util.OneLine(status.Description, 60))
+		}
+		h.Notify(b.String())
+		return nil
+	}
+
+	action, name, _ := strings.Cut(args, " ")
+	name = strings.TrimSpace(name)
+	if (action != "enable" && action != "disable") || name == "" {
+		h.NotifyError("用法: /tools [enable|disable <name>]")
+		return nil
+	}
+
+	enabled := action == "enable"
+	if err := h.Session().SetToolEnabled(name, enabled); err != nil {
+		h.NotifyError(fmt.Sprintf("修改工具状态失败: %v", err))
+		return nil
+	}
+	if enabled {
+		h.Notify(fmt.Sprintf("🔧 已启用工具 %s", name))
+	} else {
+		h.Notify(fmt.Sprintf("🔧 已在本会话中禁用工具 %s", name))
+	}
+	return nil
+}
+
+// runSystemCommand 查看或修改系统提示词
+func runSystemCommand(h CommandHost, args string) tea.Cmd {
+	switch args {
+	case "":
+		source := "模板 " + h.Session().Profile()
+		if h.Session().HasCustomSystemPrompt() {
+			source = "自定义"
+		}
+		h.Notify(fmt.Sprintf("📝 当前系统提示词（%s）:\n%s", source, h.Session().SystemPrompt()))
+	case "reset":
+		h.Session().SetSystemPrompt("")
+		h.Notify("📝 系统提示词已恢复默认")
+	default:
+		h.Session().SetSystemPrompt(args)
+		h.Notify("📝 系统提示词已更新，将在下一轮对话生效")
+	}
+	return nil
+}
+
+// runSaveCommand 立即保存会话
+func runSaveCommand(h CommandHost, args string) tea.Cmd {
+	path, err := h.Session().Save(args)
+	if err != nil {
+		h.NotifyError(fmt.Sprintf("保存会话失败: %v", err))
+		return nil
+	}
+	notice := fmt.Sprintf("💾 会话 %s 已保存到 %s", h.Session().ID(), path)
+	if args != "" {
+		notice += fmt.Sprintf("，标题: %s", args)
+	}
+	h.Notify(notice)
+	return nil
+}
+
+// runExportCommand 导出会话记录，参数可以是导出格式或文件路径
+func runExportCommand(h CommandHost, args string) tea.Cmd {
+	opts := DefaultExportOptions()
+	path := args
+	if format, err := ParseExportFormat(args); err == nil {
+		opts.Format = format
+		path = ""
+	} else if format, ok := ExportFormatFromPath(args); ok {
+		opts.Format = format
+	}
+	exportSession(h, path, opts)
+	return nil
+}
+
+// exportSession 导出当前会话，path 为空时导出到当前目录下的 ai-ops-<会话ID> 文件
+func exportSession(h CommandHost, path string, opts ExportOptions) {
+	if path == "" {
+		path = fmt.Sprintf("ai-ops-%s%s", h.Session().ID(), opts.Format.Extension())
+	}
+	if err := ExportToFile(path, h.Session().Transcript(), opts); err != nil {
+		h.NotifyError(fmt.Sprintf("导出会话失败: %v", err))
+		return
+	}
+	h.Notify(fmt.Sprintf("📤 会话已导出到 %s（%s）", path, opts.Format))
+}
+
+// runClearCommand 清空对话历史和屏幕
+func runClearCommand(h CommandHost, args string) tea.Cmd {
+	h.Session().Reset()
+	h.Clear()
+	h.Notify("🧹 对话历史已清空")
+	return nil
+}
+
+// runRetryCommand 重新发送上一轮的用户输入
+func runRetryCommand(h CommandHost, args string) tea.Cmd {
+	input := h.LastInput()
+	if input == "" {
+		h.NotifyError("没有可以重试的对话")
+		return nil
+	}
+
+	// 上一轮成功时先移除其问答，失败的对话不会保留在历史记录中
+	h.Session().RollbackLastTurn()
+	h.Notify("🔁 正在重新生成回答...")
+	return h.Submit(input)
+}
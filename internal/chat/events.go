@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+)
+
+// 工具结果预览保留的字符数
+const toolEventPreviewRunes = 500
+
+// EventType 会话事件类型
+type EventType string
+
+const (
+	EventTurnStarted      EventType = "turn_started"      // 开始处理一轮对话或智能体任务
+	EventTurnFinished     EventType = "turn_finished"     // 本轮对话完成并保留在历史记录中
+	EventModelRequest     EventType = "model_request"     // 向模型发送请求
+	EventModelResponse    EventType = "model_response"    // 收到模型响应
+	EventToolStarted      EventType = "tool_started"      // 工具开始执行
+	EventToolFinished     EventType = "tool_finished"     // 工具执行成功
+	EventToolFailed       EventType = "tool_failed"       // 工具执行失败或未获批准
+	EventHistoryCompacted EventType = "history_compacted" // 历史记录被修剪或整合
+	EventAgentPlan        EventType = "agent_plan"        // 智能体制定了执行计划
+	EventAgentStep        EventType = "agent_step"        // 智能体完成一个步骤
+	EventError            EventType = "error"             // 本轮对话失败或被取消
+)
+
+// Event 会话执行过程中产生的事件，各字段只在对应类型的事件中有效。
+// 发送给模型的内容（模型响应、工具参数与结果）均为脱敏后的文本，可以直接展示或写入日志。
+type Event struct {
+	Type      EventType
+	SessionID string
+	Time      time.Time
+
+	Mode       string             // turn_started：chat 或 agent
+	Input      string             // turn_started：用户输入
+	Round      int                // model_request、model_response：本轮第几次请求模型
+	Messages   int                // model_request：发送的消息条数
+	Response   *llm.Response      // model_response：模型响应
+	Reply      string             // turn_finished：返回给用户的回答
+	Usage      llm.TokenUsage     // turn_finished：本轮的令牌用量
+	Duration   time.Duration      // model_response、turn_finished：耗时
+	Tool       *ToolEvent         // tool_*：工具调用详情
+	Compaction *HistoryCompaction // history_compacted：修剪详情
+	Plan       string             // agent_plan：执行计划
+	Step       *AgentStep         // agent_step：完成的步骤
+	Err        error              // error：失败原因
+	Status     SessionStatus      // 所有事件：发送事件时会话使用的模型与用量
+}
+
+// ToolEvent 工具调用事件的详情
+type ToolEvent struct {
+	CallID     string
+	ToolName   string
+	Arguments  map[string]any
+	Risk       string
+	Result     string        // 结果预览，超过 500 字符时截断
+	ResultSize int           // 工具原始结果的字节数
+	Error      string        // 失败原因
+	Duration   time.Duration // 执行耗时，仅结束事件有效
+}
+
+// HistoryCompaction 历史记录修剪详情
+type HistoryCompaction struct {
+	Reason    string // trim：超出历史条数上限；consolidate：整合本轮的工具调用过程
+	Removed   int    // 移除的消息条数
+	Remaining int    // 修剪后的消息条数
+}
+
+// Observer 接收会话事件。事件在执行对话的 goroutine 中同步发送，观察者不应阻塞。
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// ObserverFunc 将函数适配为 Observer
+type ObserverFunc func(event Event)
+
+// OnEvent 调用函数本身
+func (f ObserverFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// observerSet 会话的观察者列表，对话在后台执行时可以并发订阅或取消订阅
+type observerSet struct {
+	mu        sync.Mutex
+	nextID    int
+	observers map[int]Observer
+}
+
+// add 添加观察者，返回用于取消订阅的编号
+func (o *observerSet) add(observer Observer) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.observers == nil {
+		o.observers = make(map[int]Observer)
+	}
+	o.nextID++
+	o.observers[o.nextID] = observer
+	return o.nextID
+}
+
+// remove 取消订阅
+func (o *observerSet) remove(id int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.observers, id)
+}
+
+// snapshot 按订阅顺序返回当前的观察者
+func (o *observerSet) snapshot() []Observer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	observers := make([]Observer, 0, len(o.observers))
+	for id := 1; id <= o.nextID; id++ {
+		if observer, ok := o.observers[id]; ok {
+			observers = append(observers, observer)
+		}
+	}
+	return observers
+}
+
+// Subscribe 订阅会话事件，返回取消订阅的函数
+func (s *Session) Subscribe(observer Observer) (unsubscribe func()) {
+	id := s.observers.add(observer)
+	return func() { s.observers.remove(id) }
+}
+
+// emit 记录事件并通知观察者
+func (s *Session) emit(event Event) {
+	event.SessionID = s.id
+	event.Time = time.Now()
+	event.Status = s.Status()
+	logEvent(event)
+
+	for _, observer := range s.observers.snapshot() {
+		observer.OnEvent(event)
+	}
+}
+
+// emitTool 发送工具调用事件
+func (s *Session) emitTool(eventType EventType, tool ToolEvent) {
+	s.emit(Event{Type: eventType, Tool: &tool})
+}
+
+// logEvent 将工具调用与历史修剪事件写入日志，其余事件由调用方记录
+func logEvent(event Event) {
+	fields := map[string]any{"session_id": event.SessionID}
+	switch event.Type {
+	case EventToolStarted, EventToolFinished, EventToolFailed:
+		fields["call_id"] = event.Tool.CallID
+		fields["tool_name"] = event.Tool.ToolName
+	}
+
+	switch event.Type {
+	case EventToolStarted:
+		args, _ := json.Marshal(event.Tool.Arguments)
+		fields["arguments"] = string(args)
+		util.Infow("工具调用开始", fields)
+	case EventToolFinished:
+		fields["duration_ms"] = event.Tool.Duration.Milliseconds()
+		fields["result_size"] = event.Tool.ResultSize
+		util.Infow("工具调用完成", fields)
+	case EventToolFailed:
+		fields["duration_ms"] = event.Tool.Duration.Milliseconds()
+		fields["error"] = event.Tool.Error
+		util.Warnw("工具调用失败", fields)
+	case EventHistoryCompacted:
+		fields["reason"] = event.Compaction.Reason
+		fields["removed"] = event.Compaction.Removed
+		fields["history_size"] = event.Compaction.Remaining
+		util.Debugw("历史记录已修剪", fields)
+	}
+}
+
+// toolResultPreview 生成工具结果预览
+func toolResultPreview(content string) string {
+	preview, truncated := util.TruncateRunes(unquoteToolResult(content), toolEventPreviewRunes)
+	if truncated {
+		preview += "..."
+	}
+	return preview
+}
@@ -0,0 +1,437 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	gmutil "github.com/yuin/goldmark/util"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// ExportFormat 会话导出格式
+type ExportFormat string
+
+const (
+	ExportMarkdown ExportFormat = "markdown"
+	ExportHTML     ExportFormat = "html"
+	ExportJSON     ExportFormat = "json"
+)
+
+// DefaultExportMaxResult 导出时工具结果默认保留的最大字符数
+const DefaultExportMaxResult = 2000
+
+// ExportFormats 返回支持的导出格式
+func ExportFormats() []string {
+	return []string{string(ExportMarkdown), string(ExportHTML), string(ExportJSON)}
+}
+
+// ParseExportFormat 解析导出格式，支持 md、htm 等简写
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "markdown", "md":
+		return ExportMarkdown, nil
+	case "html", "htm":
+		return ExportHTML, nil
+	case "json":
+		return ExportJSON, nil
+	default:
+		return "", errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+			"不支持的导出格式", value+"（可选 "+strings.Join(ExportFormats(), "、")+"）")
+	}
+}
+
+// ExportFormatFromPath 根据文件扩展名推断导出格式
+func ExportFormatFromPath(path string) (ExportFormat, bool) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", false
+	}
+	format, err := ParseExportFormat(ext)
+	return format, err == nil
+}
+
+// Extension 导出文件的扩展名
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportHTML:
+		return ".html"
+	case ExportJSON:
+		return ".json"
+	default:
+		return ".md"
+	}
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	Format ExportFormat
+	// MaxResultLength 工具结果最多保留的字符数，0 表示不截断
+	MaxResultLength int
+}
+
+// ExportDocument 导出的会话文档，JSON 格式直接输出该结构
+type ExportDocument struct {
+	Session    SessionMeta     `json:"session"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Usage      llm.TokenUsage  `json:"usage"`
+	Messages   []ExportMessage `json:"messages"`
+}
+
+// ExportMessage 导出的一条用户或 AI 消息
+type ExportMessage struct {
+	Role      string           `json:"role"` // "user" 或 "assistant"
+	Time      time.Time        `json:"time"`
+	Content   string           `json:"content,omitempty"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ExportToolCall `json:"tool_calls,omitempty"`
+	Usage     *TurnUsage       `json:"usage,omitempty"` // 一轮对话的最终回答上记录的用量
+}
+
+// ExportToolCall 导出的工具调用及其结果
+type ExportToolCall struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"` // 结果是否被截断
+}
+
+// DefaultExportOptions 根据 [sessions] 配置生成导出选项
+func DefaultExportOptions() ExportOptions {
+	opts := ExportOptions{Format: ExportMarkdown, MaxResultLength: DefaultExportMaxResult}
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return opts
+	}
+	if format, err := ParseExportFormat(cfg.Sessions.ExportFormat); err == nil {
+		opts.Format = format
+	}
+	switch {
+	case cfg.Sessions.ExportMaxResult < 0:
+		opts.MaxResultLength = 0
+	case cfg.Sessions.ExportMaxResult > 0:
+		opts.MaxResultLength = cfg.Sessions.ExportMaxResult
+	}
+	return opts
+}
+
+// NewExportDocument 将会话记录整理为导出文档，工具结果合并到对应的工具调用中
+func NewExportDocument(transcript *Transcript, maxResultLength int) *ExportDocument {
+	doc := &ExportDocument{
+		Session:    transcript.Meta,
+		ExportedAt: time.Now(),
+		Usage:      transcript.TotalUsage(),
+		Messages:   []ExportMessage{},
+	}
+
+	type callRef struct{ message, call int }
+	calls := make(map[string]callRef)
+
+	for _, entry := range transcript.Entries {
+		msg := entry.Message
+		if entry.Internal {
+			continue
+		}
+		switch msg.Role {
+		case "user":
+			doc.Messages = append(doc.Messages, ExportMessage{Role: "user", Time: entry.Time, Content: msg.Content})
+		case "assistant":
+			content := ExtractThinking(msg.Content)
+			exported := ExportMessage{
+				Role:     "assistant",
+				Time:     entry.Time,
+				Content:  content.Content,
+				Thinking: content.Thinking,
+				Usage:    entry.Usage,
+			}
+			for i, tc := range msg.ToolCalls {
+				exported.ToolCalls = append(exported.ToolCalls, ExportToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+				calls[tc.ID] = callRef{message: len(doc.Messages), call: i}
+			}
+			doc.Messages = append(doc.Messages, exported)
+		case "tool":
+			ref, ok := calls[msg.ToolCallID]
+			if !ok {
+				continue
+			}
+			call := &doc.Messages[ref.message].ToolCalls[ref.call]
+			call.Result, call.Truncated = util.TruncateRunes(unquoteToolResult(msg.Content), maxResultLength)
+		}
+	}
+	return doc
+}
+
+// Export 按指定格式导出会话记录
+func Export(w io.Writer, transcript *Transcript, opts ExportOptions) error {
+	doc := NewExportDocument(transcript, opts.MaxResultLength)
+	switch opts.Format {
+	case ExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case ExportHTML:
+		return renderExportHTML(w, doc)
+	default:
+		_, err := io.WriteString(w, renderExportMarkdown(doc))
+		return err
+	}
+}
+
+// ExportToFile 导出会话记录到文件，会话可能包含敏感信息，文件仅对当前用户可读写
+func ExportToFile(path string, transcript *Transcript, opts ExportOptions) error {
+	var buf bytes.Buffer
+	if err := Export(&buf, transcript, opts); err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "生成导出内容失败", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "写入导出文件失败", err)
+	}
+	return nil
+}
+
+// unquoteToolResult 工具结果以 JSON 字符串形式发送给模型，导出时还原为原始文本
+func unquoteToolResult(content string) string {
+	var text string
+	if err := json.Unmarshal([]byte(content), &text); err == nil {
+		return text
+	}
+	return content
+}
+
+// formatUsage 格式化令牌用量
+func formatUsage(usage llm.TokenUsage) string {
+	return fmt.Sprintf("输入 %d / 输出 %d / 合计 %d", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+}
+
+// formatArguments 将工具参数格式化为缩进的 JSON
+func formatArguments(args map[string]any) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(args); err != nil {
+		return fmt.Sprintf("%v", args)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// codeFence 生成比内容中最长的反引号序列更长的代码块围栏
+func codeFence(content string) string {
+	longest, current := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// writeCodeBlock 写入 Markdown 代码块
+func writeCodeBlock(b *strings.Builder, lang, content string) {
+	fence := codeFence(content)
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, lang, content, fence)
+}
+
+// renderExportMarkdown 将导出文档渲染为 Markdown
+func renderExportMarkdown(doc *ExportDocument) string {
+	var b strings.Builder
+	meta := doc.Session
+	fmt.Fprintf(&b, "# AI-OPS 会话记录 %s\n\n", meta.ID)
+	if meta.Title != "" {
+		fmt.Fprintf(&b, "- 标题: %s\n", meta.Title)
+	}
+	fmt.Fprintf(&b, "- 模型: %s (%s)\n", meta.Adapter, meta.Model)
+	fmt.Fprintf(&b, "- 模式: %s\n", meta.Mode)
+	fmt.Fprintf(&b, "- 创建时间: %s\n", meta.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 导出时间: %s\n", doc.ExportedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 令牌用量: %s\n", formatUsage(doc.Usage))
+
+	for _, msg := range doc.Messages {
+		timestamp := msg.Time.Format("2006-01-02 15:04:05")
+		if msg.Role == "user" {
+			fmt.Fprintf(&b, "\n## 👤 用户 [%s]\n\n%s\n", timestamp, msg.Content)
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n## 🤖 AI [%s]\n\n", timestamp)
+		if msg.Thinking != "" {
+			fmt.Fprintf(&b, "<details>\n<summary>思考过程</summary>\n\n%s\n\n</details>\n\n", msg.Thinking)
+		}
+		if msg.Content != "" {
+			fmt.Fprintf(&b, "%s\n", msg.Content)
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&b, "\n**🔧 调用工具: %s**\n\n", call.Name)
+			writeCodeBlock(&b, "json", formatArguments(call.Arguments))
+			summary := "结果"
+			if call.Truncated {
+				summary = "结果（已截断）"
+			}
+			fmt.Fprintf(&b, "\n<details>\n<summary>%s</summary>\n\n", summary)
+			writeCodeBlock(&b, "", call.Result)
+			b.WriteString("\n</details>\n")
+		}
+		if msg.Usage != nil {
+			fmt.Fprintf(&b, "\n> 模型: %s · 令牌: %s\n", msg.Usage.Model, formatUsage(msg.Usage.Usage))
+		}
+	}
+	return b.String()
+}
+
+// exportHTMLTemplate 自包含的 HTML 模板，样式内联，不依赖外部资源
+var exportHTMLTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"markdown":  renderMarkdownHTML,
+	"arguments": formatArguments,
+	"usage":     formatUsage,
+	"timestamp": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>AI-OPS 会话记录 {{.Session.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #24292f; line-height: 1.6; }
+h1 { font-size: 1.5em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+.meta { color: #57606a; font-size: .9em; }
+.message { border: 1px solid #d0d7de; border-radius: 6px; margin: 1em 0; padding: .5em 1em; }
+.user { background: #f6f8fa; }
+.role { font-weight: 600; }
+.time, .usage { color: #57606a; font-size: .85em; }
+.thinking { color: #57606a; font-style: italic; }
+.tool { border-left: 3px solid #0969da; margin: .8em 0; padding-left: .8em; }
+pre { background: #f6f8fa; padding: .8em; overflow-x: auto; white-space: pre-wrap; word-break: break-all; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: .9em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #d0d7de; padding: .3em .6em; }
+</style>
+</head>
+<body>
+<h1>AI-OPS 会话记录 {{.Session.ID}}</h1>
+<ul class="meta">
+{{- if .Session.Title}}
+<li>标题: {{.Session.Title}}</li>
+{{- end}}
+<li>模型: {{.Session.Adapter}} ({{.Session.Model}})</li>
+<li>模式: {{.Session.Mode}}</li>
+<li>创建时间: {{timestamp .Session.CreatedAt}}</li>
+<li>导出时间: {{timestamp .ExportedAt}}</li>
+<li>令牌用量: {{usage .Usage}}</li>
+</ul>
+{{- range .Messages}}
+{{- if eq .Role "user"}}
+<div class="message user">
+<div><span class="role">👤 用户</span> <span class="time">{{timestamp .Time}}</span></div>
+{{markdown .Content}}
+</div>
+{{- else}}
+<div class="message assistant">
+<div><span class="role">🤖 AI</span> <span class="time">{{timestamp .Time}}</span></div>
+{{- if .Thinking}}
+<details class="thinking"><summary>思考过程</summary>
+{{markdown .Thinking}}
+</details>
+{{- end}}
+{{- if .Content}}
+{{markdown .Content}}
+{{- end}}
+{{- range .ToolCalls}}
+<div class="tool">
+<div>🔧 调用工具: <code>{{.Name}}</code></div>
+<pre><code>{{arguments .Arguments}}</code></pre>
+<details><summary>结果{{if .Truncated}}（已截断）{{end}}</summary>
+<pre><code>{{.Result}}</code></pre>
+</details>
+</div>
+{{- end}}
+{{- if .Usage}}
+<div class="usage">模型: {{.Usage.Model}} · 令牌: {{usage .Usage.Usage}}</div>
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// exportMarkdownRenderer 将消息中的 Markdown 转换为 HTML，原始 HTML 转义后按文本输出
+var exportMarkdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(renderer.WithNodeRenderers(gmutil.Prioritized(escapedHTMLRenderer{}, 100))),
+)
+
+// escapedHTMLRenderer 将消息中的原始 HTML 转义输出。
+// 命令输出中常见 <none>、<pending> 等内容，直接丢弃会丢失信息，原样输出又不安全。
+type escapedHTMLRenderer struct{}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (escapedHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindRawHTML, renderEscapedRawHTML)
+	reg.Register(ast.KindHTMLBlock, renderEscapedHTMLBlock)
+}
+
+// renderEscapedRawHTML 转义行内 HTML
+func renderEscapedRawHTML(w gmutil.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+	segments := node.(*ast.RawHTML).Segments
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		_, _ = w.WriteString(template.HTMLEscapeString(string(segment.Value(source))))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// renderEscapedHTMLBlock 将 HTML 块转义后放入预格式化文本
+func renderEscapedHTMLBlock(w gmutil.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.(*ast.HTMLBlock)
+	_, _ = w.WriteString("<pre>")
+	for i := 0; i < block.Lines().Len(); i++ {
+		line := block.Lines().At(i)
+		_, _ = w.WriteString(template.HTMLEscapeString(string(line.Value(source))))
+	}
+	if block.HasClosure() {
+		_, _ = w.WriteString(template.HTMLEscapeString(string(block.ClosureLine.Value(source))))
+	}
+	_, _ = w.WriteString("</pre>\n")
+	return ast.WalkContinue, nil
+}
+
+// renderMarkdownHTML 将 Markdown 渲染为 HTML，失败时按纯文本输出
+func renderMarkdownHTML(content string) template.HTML {
+	var buf bytes.Buffer
+	if err := exportMarkdownRenderer.Convert([]byte(content), &buf); err != nil {
+		return template.HTML("<pre>" + template.HTMLEscapeString(content) + "</pre>")
+	}
+	return template.HTML(buf.String())
+}
+
+// renderExportHTML 将导出文档渲染为自包含的 HTML
+func renderExportHTML(w io.Writer, doc *ExportDocument) error {
+	return exportHTMLTemplate.Execute(w, doc)
+}
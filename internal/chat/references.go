@@ -0,0 +1,419 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+)
+
+// 引用限制的默认值
+const (
+	defaultReferenceMaxFileSize    = 64 * 1024
+	defaultReferenceMaxTotalSize   = 256 * 1024
+	defaultReferenceMaxFiles       = 20
+	defaultReferenceCommandTimeout = 30 * time.Second
+)
+
+// 引用类型
+const (
+	referenceFile    = "file"    // @path，可包含通配符
+	referenceCommand = "command" // !command，整行为命令
+)
+
+// 人工确认本地命令时使用的名称
+const referenceCommandName = "本地命令"
+
+// ReferenceLimits 输入中 @文件 与 !命令 引用的限制
+type ReferenceLimits struct {
+	MaxFileSize    int           // 单个文件或命令输出最多附加的字节数，超出部分截断
+	MaxTotalSize   int           // 一条消息附加内容的总字节数，超出后忽略其余引用
+	MaxFiles       int           // 一个通配符最多匹配的文件数
+	CommandTimeout time.Duration // 命令执行超时
+}
+
+// withDefaults 为未设置的限制填充默认值
+func (l ReferenceLimits) withDefaults() ReferenceLimits {
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = defaultReferenceMaxFileSize
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = defaultReferenceMaxTotalSize
+	}
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = defaultReferenceMaxFiles
+	}
+	if l.CommandTimeout <= 0 {
+		l.CommandTimeout = defaultReferenceCommandTimeout
+	}
+	return l
+}
+
+// reference 输入中的一个引用
+type reference struct {
+	kind  string
+	value string // 文件路径或命令
+}
+
+// parseReferences 解析输入中的引用：以 ! 开头的行是命令引用，其余行中以 @ 开头的单词是文件引用
+func parseReferences(input string) []reference {
+	var refs []reference
+	for _, line := range strings.Split(input, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if command, ok := strings.CutPrefix(trimmed, "!"); ok {
+			if command = strings.TrimSpace(command); command != "" {
+				refs = append(refs, reference{kind: referenceCommand, value: command})
+			}
+			continue
+		}
+		for _, word := range strings.Fields(line) {
+			path, ok := strings.CutPrefix(word, "@")
+			// 去掉紧跟在路径后的标点，如 "@nginx.conf，"
+			path = strings.TrimRight(path, ",;:!?)，。；：！？）")
+			if ok && path != "" {
+				refs = append(refs, reference{kind: referenceFile, value: path})
+			}
+		}
+	}
+	return refs
+}
+
+// referenceExpander 展开一条消息中的引用
+type referenceExpander struct {
+	session  *Session
+	limits   ReferenceLimits
+	budget   int // 剩余可附加的字节数
+	body     strings.Builder
+	files    int
+	commands int
+}
+
+// ExpandReferences 将输入中的 @文件 与 !命令 引用展开为附加在消息后的上下文。
+// 文件支持通配符，超出大小限制时截断；命令须经人工确认后在本地执行。
+// 无法展开的引用按普通文本发送，原因通过系统通知告知用户。
+func (s *Session) ExpandReferences(ctx context.Context, input string) (string, error) {
+	refs := parseReferences(input)
+	if len(refs) == 0 {
+		return input, nil
+	}
+
+	limits := s.config.References.withDefaults()
+	e := &referenceExpander{session: s, limits: limits, budget: limits.MaxTotalSize}
+	for i, ref := range refs {
+		if e.budget <= 0 {
+			s.notices = append(s.notices, fmt.Sprintf("附加内容已达到 %s 上限，忽略其余 %d 个引用", formatSize(limits.MaxTotalSize), len(refs)-i))
+			break
+		}
+		switch ref.kind {
+		case referenceFile:
+			e.attachFiles(ref.value)
+		case referenceCommand:
+			if err := e.attachCommand(ctx, ref.value); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if e.files == 0 && e.commands == 0 {
+		return input, nil
+	}
+	var parts []string
+	if e.files > 0 {
+		parts = append(parts, fmt.Sprintf("%d 个文件", e.files))
+	}
+	if e.commands > 0 {
+		parts = append(parts, fmt.Sprintf("%d 条命令输出", e.commands))
+	}
+	s.notices = append(s.notices, fmt.Sprintf("📎 已附加 %s（共 %s）", strings.Join(parts, "、"), formatSize(limits.MaxTotalSize-e.budget)))
+	return input + "\n" + e.body.String(), nil
+}
+
+// attachFiles 附加路径或通配符匹配的文件
+func (e *referenceExpander) attachFiles(pattern string) {
+	s := e.session
+	paths, err := matchReferencePaths(pattern)
+	if err != nil || len(paths) == 0 {
+		s.notices = append(s.notices, fmt.Sprintf("未找到文件 @%s，按普通文本发送", pattern))
+		return
+	}
+	if len(paths) > e.limits.MaxFiles {
+		s.notices = append(s.notices, fmt.Sprintf("@%s 匹配了 %d 个文件，只附加前 %d 个", pattern, len(paths), e.limits.MaxFiles))
+		paths = paths[:e.limits.MaxFiles]
+	}
+
+	for _, path := range paths {
+		if e.budget <= 0 {
+			return
+		}
+		content, size, err := readReferenceFile(path, min(e.limits.MaxFileSize, e.budget))
+		if err != nil {
+			s.notices = append(s.notices, fmt.Sprintf("无法附加 %s: %v", path, err))
+			continue
+		}
+
+		header := fmt.Sprintf("[附件 @%s（%s）]", path, formatSize(size))
+		if len(content) < size {
+			header = fmt.Sprintf("[附件 @%s（%s，只附加了前 %s）]", path, formatSize(size), formatSize(len(content)))
+		}
+		e.write(header, strings.TrimPrefix(filepath.Ext(path), "."), content)
+		e.files++
+	}
+}
+
+// attachCommand 经确认后执行命令并附加输出
+func (e *referenceExpander) attachCommand(ctx context.Context, command string) error {
+	s := e.session
+	command, approved, err := s.approveReferenceCommand(ctx, command)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return nil
+	}
+
+	output, exitCode, duration, runErr := runReferenceCommand(ctx, command, e.limits.CommandTimeout)
+	if ctx.Err() != nil {
+		return interruptedError(ctx)
+	}
+	util.Infow("执行输入中引用的本地命令", map[string]any{
+		"session_id":  s.id,
+		"command":     command,
+		"exit_code":   exitCode,
+		"duration_ms": duration.Milliseconds(),
+		"output_size": len(output),
+	})
+	if runErr != nil {
+		s.notices = append(s.notices, fmt.Sprintf("命令 %s 执行失败: %v", command, runErr))
+		if len(output) == 0 {
+			return nil
+		}
+	}
+
+	size := len(output)
+	content := truncateUTF8(output, min(e.limits.MaxFileSize, e.budget))
+	header := fmt.Sprintf("[命令 `%s` 的输出（退出码 %d）]", command, exitCode)
+	if len(content) < size {
+		header = fmt.Sprintf("[命令 `%s` 的输出（退出码 %d，%s，只附加了前 %s）]", command, exitCode, formatSize(size), formatSize(len(content)))
+	}
+	e.write(header, "", content)
+	e.commands++
+	return nil
+}
+
+// write 以代码块形式写入一段附加内容
+func (e *referenceExpander) write(header, lang, content string) {
+	e.budget -= len(content)
+	e.body.WriteString("\n" + header + "\n")
+	writeCodeBlock(&e.body, lang, strings.TrimRight(content, "\n"))
+}
+
+// approveReferenceCommand 请求人工确认输入中引用的命令，返回实际执行的命令（确认时可能被修改）
+func (s *Session) approveReferenceCommand(ctx context.Context, command string) (string, bool, error) {
+	if s.config.ApprovalMode == ApprovalModeDeny {
+		s.notices = append(s.notices, fmt.Sprintf("配置禁止执行有风险的操作，未执行命令: %s", command))
+		return command, false, nil
+	}
+	if s.config.Approver == nil {
+		s.notices = append(s.notices, fmt.Sprintf("当前环境无法进行人工确认，未执行命令: %s", command))
+		return command, false, nil
+	}
+
+	// 输入中的命令由用户自己填写，但执行前仍需单独确认，自动批准的配置不适用
+	response, err := s.config.Approver.Approve(ctx, ApprovalRequest{
+		ToolName:  referenceCommandName,
+		Risk:      tools.RiskDestructive,
+		Arguments: map[string]any{"command": command},
+		Title:     "确认在本地执行输入中引用的命令",
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return command, false, interruptedError(ctx)
+		}
+		return command, false, fmt.Errorf("等待确认命令 %s 失败: %w", command, err)
+	}
+
+	switch response.Decision {
+	case ApprovalApproved:
+		return command, true, nil
+	case ApprovalEdited:
+		if edited, ok := response.Arguments["command"].(string); ok && strings.TrimSpace(edited) != "" {
+			return strings.TrimSpace(edited), true, nil
+		}
+		s.notices = append(s.notices, "修改后的参数缺少 command，未执行命令")
+		return command, false, nil
+	default:
+		s.notices = append(s.notices, fmt.Sprintf("已拒绝执行命令: %s", command))
+		return command, false, nil
+	}
+}
+
+// matchReferencePaths 展开 ~ 与通配符，返回按名称排序的文件列表（不含目录）
+func matchReferencePaths(pattern string) ([]string, error) {
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.Join(home, rest)
+		}
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readReferenceFile 读取文件的前 maxBytes 字节，返回内容与文件大小，二进制文件返回错误
+func readReferenceFile(path string, maxBytes int) (string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	readSize := info.Size()
+	if readSize > int64(maxBytes) {
+		readSize = int64(maxBytes)
+	}
+	buf := make([]byte, readSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", 0, err
+	}
+	buf = buf[:n]
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return "", 0, fmt.Errorf("二进制文件不能作为附件")
+	}
+	return truncateUTF8(string(buf), maxBytes), int(info.Size()), nil
+}
+
+// runReferenceCommand 通过系统 shell 执行命令，返回合并的标准输出与错误输出
+func runReferenceCommand(ctx context.Context, command string, timeout time.Duration) (string, int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	startTime := time.Now()
+	output, err := cmd.CombinedOutput()
+	duration := time.Since(startTime)
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	// 非零退出码的输出同样有参考价值，按正常结果附加
+	if _, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+		err = nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("执行超过 %s 被终止", timeout)
+	}
+	return string(output), exitCode, duration, err
+}
+
+// truncateUTF8 将文本截断到不超过 maxBytes 字节，不截断多字节字符
+func truncateUTF8(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// maxReferenceCandidates 文件补全最多返回的候选数
+const maxReferenceCandidates = 50
+
+// completeFileReference 补全输入末尾的 @文件 引用，每个候选都是完整的输入内容，目录以 / 结尾
+func completeFileReference(input string) []string {
+	prefix, ok := trailingFileReference(input)
+	if !ok {
+		return nil
+	}
+	idx := len(input) - len(prefix) - 2
+
+	pattern := prefix
+	if rest, ok := strings.CutPrefix(prefix, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.Join(home, rest)
+			if rest == "" {
+				pattern += string(filepath.Separator)
+			}
+		}
+	}
+	matches, err := filepath.Glob(escapeGlob(pattern) + "*")
+	if err != nil {
+		return nil
+	}
+
+	dir, _ := filepath.Split(prefix)
+	base := filepath.Base(pattern)
+	if strings.HasSuffix(pattern, string(filepath.Separator)) {
+		base = ""
+	}
+	var candidates []string
+	for _, match := range matches {
+		name := filepath.Base(match)
+		// 未输入 . 时不提示隐藏文件
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, input[:idx+1]+"@"+dir+name)
+		if len(candidates) >= maxReferenceCandidates {
+			break
+		}
+	}
+	return candidates
+}
+
+// trailingFileReference 返回输入末尾正在输入的 @文件 引用（不含 @）
+func trailingFileReference(input string) (string, bool) {
+	word := input[strings.LastIndexAny(input, " \t\n")+1:]
+	return strings.CutPrefix(word, "@")
+}
+
+// escapeGlob 转义路径中的通配符，补全时按字面匹配已输入的部分
+func escapeGlob(path string) string {
+	if runtime.GOOS == "windows" {
+		return path
+	}
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,382 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+)
+
+// 多行输入的起止标记，单独占一行
+const multilineDelimiter = `"""`
+
+// 行模式的输入提示符
+const (
+	replPrompt             = "> "
+	replContinuationPrompt = "... "
+)
+
+// lineReader 逐行读取用户输入
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// terminalLineReader 在终端中读取输入，支持光标移动、行内编辑、上下键历史和 Tab 补全。
+// 只在读取输入期间切换到原始模式，处理对话时终端保持正常模式，Ctrl+C 可以取消请求。
+type terminalLineReader struct {
+	fd       int
+	out      io.Writer
+	terminal *term.Terminal
+}
+
+// newTerminalLineReader 创建终端输入读取器，complete 返回输入的补全候选
+func newTerminalLineReader(in *os.File, out io.Writer, complete func(input string) []string) *terminalLineReader {
+	r := &terminalLineReader{
+		fd:  int(in.Fd()),
+		out: out,
+		terminal: term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{in, out}, replPrompt),
+	}
+	r.terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' || pos != len(line) {
+			return "", 0, false
+		}
+		candidates := complete(line)
+		switch {
+		case len(candidates) == 1 && strings.HasSuffix(candidates[0], "/") && !strings.HasPrefix(line, "/"):
+			// 目录继续补全其中的文件
+			line = candidates[0]
+		case len(candidates) == 1:
+			line = candidates[0] + " "
+		case len(candidates) > 1:
+			line = commonPrefix(candidates)
+		default:
+			return "", 0, false
+		}
+		return line, len(line), true
+	}
+	return r
+}
+
+// ReadLine 读取一行输入，Ctrl+C 或 Ctrl+D 返回 io.EOF
+func (r *terminalLineReader) ReadLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", fmt.Errorf("切换终端模式失败: %w", err)
+	}
+	defer term.Restore(r.fd, state)
+
+	// 串口等终端可能报告宽度为 0，此时沿用默认宽度
+	if width, height, err := term.GetSize(r.fd); err == nil && width > 0 {
+		_ = r.terminal.SetSize(width, height)
+	}
+	r.terminal.SetPrompt(prompt)
+	line, err := r.terminal.ReadLine()
+	switch err {
+	case term.ErrPasteIndicator:
+		// 粘贴的内容按普通输入处理
+		err = nil
+	case io.EOF:
+		// 光标停留在提示符之后，换行后再输出其他内容
+		fmt.Fprint(r.out, "\r\n")
+	}
+	return line, err
+}
+
+// plainLineReader 从管道或文件中读取输入，并回显到输出中，便于记录完整的对话
+type plainLineReader struct {
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+// ReadLine 读取一行输入
+func (r *plainLineReader) ReadLine(prompt string) (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	fmt.Fprintf(r.out, "%s%s\n", prompt, line)
+	return line, nil
+}
+
+// PlainREPL 行模式的对话界面，不使用全屏界面，适用于串口、部分 SSH 终端、script 录制以及非终端的输入输出
+type PlainREPL struct {
+	session   *Session
+	out       io.Writer
+	reader    lineReader
+	lastInput string
+
+	// 配置热重载的结果在后台协程中产生，读取输入时终端处于原始模式，等到下一次输出时再显示
+	mu      sync.Mutex
+	reloads []string
+}
+
+// NewPlainREPL 创建行模式对话界面，in 为终端时支持行编辑与历史记录
+func NewPlainREPL(client llm.ModelAdapter, toolManager tools.ToolManager, config SessionConfig, in *os.File, out io.Writer) *PlainREPL {
+	r := &PlainREPL{out: out}
+	r.session = NewSession(client, toolManager, config)
+	r.session.Subscribe(ObserverFunc(r.printEvent))
+
+	if term.IsTerminal(int(in.Fd())) {
+		r.reader = newTerminalLineReader(in, out, func(input string) []string {
+			return completeChatInput(r, input)
+		})
+	} else {
+		r.reader = &plainLineReader{reader: bufio.NewReader(in), out: out}
+	}
+	return r
+}
+
+// Session 返回当前会话
+func (r *PlainREPL) Session() *Session {
+	return r.session
+}
+
+// Notify 输出系统通知
+func (r *PlainREPL) Notify(text string) {
+	r.printLine(text)
+}
+
+// NotifyError 输出错误信息
+func (r *PlainREPL) NotifyError(text string) {
+	r.printLine("❌ " + text)
+}
+
+// Clear 清空上一轮输入，行模式不清除已输出的内容
+func (r *PlainREPL) Clear() {
+	r.lastInput = ""
+}
+
+// LastInput 返回上一轮发送的用户输入
+func (r *PlainREPL) LastInput() string {
+	return r.lastInput
+}
+
+// Submit 同步处理用户输入并输出回答
+func (r *PlainREPL) Submit(input string) tea.Cmd {
+	r.processInput(input)
+	return nil
+}
+
+// Run 循环读取输入直到 Ctrl+D、Ctrl+C 或输入结束
+func (r *PlainREPL) Run() error {
+	r.printWelcome()
+
+	for {
+		r.printReloads()
+		input, err := r.readInput()
+		if err == io.EOF {
+			fmt.Fprintln(r.out, "再见!")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		r.printReloads()
+
+		// 已注册的斜杠命令交给命令处理，其余输入按普通消息发送
+		if cmd, args, ok := parseSlashCommand(input); ok {
+			cmd.Run(r, args)
+			continue
+		}
+
+		r.lastInput = input
+		r.processInput(input)
+	}
+}
+
+// readInput 读取一条输入，以 """ 单独成行开始的多行输入读取到下一个 """ 为止
+func (r *PlainREPL) readInput() (string, error) {
+	line, err := r.reader.ReadLine(replPrompt)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(line) != multilineDelimiter {
+		return line, nil
+	}
+
+	var lines []string
+	for {
+		line, err := r.reader.ReadLine(replContinuationPrompt)
+		if err == io.EOF {
+			// 输入结束时发送已读取的内容
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(line) == multilineDelimiter {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// processInput 处理一轮对话，处理期间按 Ctrl+C 取消请求
+func (r *PlainREPL) processInput(input string) {
+	timeout := time.Duration(config.GetConfig().AI.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	r.printLine("🤔 AI正在思考...（Ctrl+C 取消）")
+	// 展开 @文件 与 !命令 引用，命令经终端确认后执行
+	expanded, err := r.session.ExpandReferences(ctx, input)
+	var response string
+	switch {
+	case err != nil:
+	case r.session.Mode() == "agent":
+		response, err = r.runAgent(ctx, expanded)
+	default:
+		response, err = r.session.ProcessMessage(ctx, expanded)
+	}
+
+	for _, notice := range r.session.TakeNotices() {
+		r.printLine(notice)
+	}
+	switch {
+	case err != nil && ctx.Err() == context.Canceled:
+		r.printLine("⏹️ 已取消，本轮对话未保留在历史记录中（/retry 可重新发送）")
+	case err != nil:
+		r.NotifyError(fmt.Sprintf("错误: %v", err))
+	default:
+		r.printResponse(response)
+	}
+}
+
+// configReloaded 记录配置热重载的结果，在下一次读取或处理输入前输出
+func (r *PlainREPL) configReloaded(result llm.ReloadResult) {
+	if !result.HasChanges() {
+		return
+	}
+	r.mu.Lock()
+	r.reloads = append(r.reloads, "🔄 配置已重新加载 - "+result.Summary())
+	r.mu.Unlock()
+}
+
+// printReloads 输出尚未显示的配置热重载结果
+func (r *PlainREPL) printReloads() {
+	r.mu.Lock()
+	reloads := r.reloads
+	r.reloads = nil
+	r.mu.Unlock()
+	for _, text := range reloads {
+		r.printLine(text)
+	}
+}
+
+// printEvent 逐行输出工具调用结果与智能体的计划和步骤
+func (r *PlainREPL) printEvent(event Event) {
+	switch event.Type {
+	case EventToolStarted, EventToolFinished, EventToolFailed:
+		entry := newToolTimelineEntry(event.Tool)
+		entry.update(event)
+		r.printLine(entry.summary())
+	case EventAgentPlan:
+		r.printLine("📋 执行计划:\n" + event.Plan)
+	case EventAgentStep:
+		r.printLine(FormatStep(*event.Step))
+	}
+}
+
+// runAgent 以智能体方式执行任务，计划和每个步骤通过会话事件实时输出
+func (r *PlainREPL) runAgent(ctx context.Context, goal string) (string, error) {
+	report, err := r.session.RunAgent(ctx, goal, AgentHooks{})
+	if err != nil {
+		return "", err
+	}
+	return report.Markdown(), nil
+}
+
+// printWelcome 输出欢迎信息
+func (r *PlainREPL) printWelcome() {
+	mode := "普通对话模式"
+	if r.session.Mode() == "agent" {
+		mode = "智能体模式"
+	}
+	fmt.Fprintf(r.out, "🤖 AI-OPS 智能运维助手 - %s（行模式）\n", mode)
+	if r.session.recorder != nil {
+		fmt.Fprintf(r.out, "💾 会话ID: %s（可通过 ai-ops chat --resume 恢复）\n", r.session.ID())
+	}
+	if r.session.config.Resume != nil {
+		summary := r.session.config.Resume.Summary()
+		fmt.Fprintf(r.out, "📂 已恢复会话 %s（%d 轮对话，最后更新于 %s）\n",
+			summary.ID, summary.Turns, summary.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(r.out, "💡 /help 查看命令，%s 单独成行开始和结束多行输入，Ctrl+C 取消请求，Ctrl+D 退出\n\n", multilineDelimiter)
+}
+
+// printResponse 输出 AI 回答，开启思考过程显示时先输出思考内容
+func (r *PlainREPL) printResponse(response string) {
+	if r.session.config.ShowThinking {
+		thinking := ExtractThinking(response)
+		if thinking.Thinking != "" {
+			fmt.Fprintf(r.out, "🤔 思考过程:\n%s\n---\n", thinking.Thinking)
+		}
+		response = thinking.Content
+	} else {
+		response = RemoveThinking(response)
+	}
+	fmt.Fprintf(r.out, "AI [%s]:\n%s\n\n", time.Now().Format("15:04:05"), response)
+}
+
+// printLine 输出带时间戳的系统信息
+func (r *PlainREPL) printLine(text string) {
+	fmt.Fprintf(r.out, "[%s] %s\n", time.Now().Format("15:04:05"), text)
+}
+
+// RunPlainChat 启动行模式对话，有风险的工具调用通过 /dev/tty 确认
+func RunPlainChat(client llm.ModelAdapter, toolManager tools.ToolManager, sessionConfig SessionConfig) error {
+	if sessionConfig.Approver == nil {
+		if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+			defer tty.Close()
+			sessionConfig.Approver = NewTerminalApprover(tty, tty)
+		}
+	}
+
+	repl := NewPlainREPL(client, toolManager, sessionConfig, os.Stdin, os.Stdout)
+	// 监听配置文件变更，热重载模型适配器
+	stopWatch := watchConfig(repl.configReloaded)
+	defer stopWatch()
+
+	err := repl.Run()
+	if closeErr := repl.session.Close(); closeErr != nil {
+		util.Warnw("关闭会话文件失败", map[string]any{"error": closeErr.Error()})
+	}
+	return err
+}
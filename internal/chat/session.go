@@ -4,17 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"ai-ops/internal/config"
 	"ai-ops/internal/llm"
+	"ai-ops/internal/prompt"
+	"ai-ops/internal/redact"
 	"ai-ops/internal/tools"
+	"ai-ops/internal/tracing"
 	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
 )
 
 // SessionConfig 会话配置
 type SessionConfig struct {
 	Mode         string // "chat" 或 "agent"
 	ShowThinking bool   // 是否显示思考过程
+	ModelName    string // 使用的模型适配器名称，用于配置热重载后切换实例
+	// MaxToolRounds 单轮对话中允许的工具调用轮数，达到上限后禁用工具以获取最终回答（0 表示使用 AgentBudget 的最多步骤数）
+	MaxToolRounds int
+	ToolChoice    llm.ToolChoice // 初始的工具选择设置
+	// Redactor 发送给模型前对用户输入和工具结果脱敏（nil 表示不脱敏）。映射可以还原为原文，
+	// 不同用户或请求的会话不能共享同一个 Redactor，应通过 Redactor.Fresh 分别创建
+	Redactor *redact.Redactor
+	// Store 会话持久化存储（nil 表示不保存）
+	Store *SessionStore
+	// Resume 要恢复的会话记录（nil 表示新会话）
+	Resume *Transcript
+	// History 预置的对话历史（不含系统提示词），如 API 请求中携带的历史消息
+	History []llm.Message
+	// DisableTools 不向模型提供任何工具
+	DisableTools bool
+	// ApprovalMode 有风险工具调用的确认方式（prompt/auto/deny，空表示 prompt）
+	ApprovalMode string
+	// Approver 人工确认有风险的工具调用（nil 时 prompt 模式下拒绝执行）
+	Approver ToolApprover
+	// AgentBudget 智能体模式的执行预算，未设置的项使用默认值
+	AgentBudget AgentBudget
+	// Prompts 系统提示词模板库（nil 表示只使用内置模板）
+	Prompts *prompt.Library
+	// Profile 使用的提示词模板名称（空表示 default）
+	Profile string
+	// PromptVars 模板中通过 {{.Vars.name}} 引用的自定义变量
+	PromptVars map[string]string
+	// References 输入中 @文件 与 !命令 引用的限制，未设置的项使用默认值
+	References ReferenceLimits
+	// StatusBar 对话界面状态栏的显示设置
+	StatusBar StatusBarOptions
+	// Observer 接收会话事件（nil 表示不通知），创建会话后可通过 Subscribe 添加更多观察者
+	Observer Observer
+}
+
+// ToolCallTrace 一次工具调用的执行记录
+type ToolCallTrace struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Result     string         `json:"result,omitempty"` // 发送给模型的工具结果（已脱敏、截断）
+	Error      string         `json:"error,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	Risk       string         `json:"risk,omitempty"`
+	Approval   string         `json:"approval,omitempty"` // 需要确认的调用的确认结果
+}
+
+// TurnStats 一轮对话的执行统计
+type TurnStats struct {
+	Rounds    int             `json:"rounds"` // 模型请求次数
+	ToolCalls []ToolCallTrace `json:"tool_calls"`
+	Usage     llm.TokenUsage  `json:"usage"`
 }
 
 // Session 管理一个独立的对话会话
@@ -24,18 +83,42 @@ type Session struct {
 	messages    []llm.Message
 	toolDefs    []tools.ToolDefinition
 	config      SessionConfig
-	maxHistory  int // 最大历史记录条数
+	maxHistory  int      // 最大历史记录条数
+	notices     []string // 待展示给用户的系统通知
+
+	toolChoice     llm.ToolChoice  // 会话默认的工具选择
+	onceToolChoice *llm.ToolChoice // 仅作用于下一轮对话的工具选择
+
+	redactor *redact.Redactor // 敏感信息脱敏器，占位符与原文的映射只保存在本地
+
+	customPrompt  string          // 用户自定义的系统提示词（空表示按模式生成）
+	disabledTools map[string]bool // 本会话中禁用的工具
+
+	id         string           // 会话ID
+	recorder   *sessionRecorder // 会话持久化记录器（nil 表示不保存）
+	transcript Transcript       // 完整的会话记录（含工具调用与用量），用于导出
+
+	lastTurn     TurnStats    // 最近一轮对话的执行统计
+	lastTurnDone bool         // 最近一轮对话是否已完成并保留在历史记录中
+	usage        usageTracker // 整个会话的令牌用量与费用
+	observers    observerSet  // 会话事件的观察者
 }
 
 // NewSession 创建一个新的对话会话
 func NewSession(client llm.ModelAdapter, toolManager tools.ToolManager, config SessionConfig) *Session {
 	session := &Session{
-		client:      client,
-		toolManager: toolManager,
-		messages:    make([]llm.Message, 0),
-		toolDefs:    toolManager.GetToolDefinitions(),
-		config:      config,
-		maxHistory:  10, // 默认保留最近10条消息
+		client:        client,
+		toolManager:   toolManager,
+		messages:      make([]llm.Message, 0),
+		toolDefs:      toolManager.GetToolDefinitions(),
+		config:        config,
+		maxHistory:    10, // 默认保留最近10条消息
+		toolChoice:    config.ToolChoice,
+		redactor:      config.Redactor,
+		disabledTools: make(map[string]bool),
+	}
+	if config.DisableTools {
+		session.toolDefs = nil
 	}
 
 	// 根据模式设置系统提示词
@@ -47,47 +130,205 @@ func NewSession(client llm.ModelAdapter, toolManager tools.ToolManager, config S
 		})
 	}
 
+	// 恢复历史会话，系统提示词按当前模式重新生成
+	if config.Resume != nil {
+		session.id = config.Resume.Meta.ID
+		for _, entry := range config.Resume.Entries {
+			session.reservePlaceholders(entry.Message)
+		}
+		session.messages = append(session.messages, config.Resume.History()...)
+		session.trimHistory()
+	} else {
+		session.id = newSessionID()
+	}
+	if len(config.History) > 0 {
+		for _, message := range config.History {
+			session.reservePlaceholders(message)
+		}
+		session.messages = append(session.messages, config.History...)
+		session.trimHistory()
+	}
+
+	meta := SessionMeta{
+		ID:           session.id,
+		Adapter:      config.ModelName,
+		Model:        client.GetModelInfo().Name,
+		Mode:         config.Mode,
+		ShowThinking: config.ShowThinking,
+		Profile:      config.Profile,
+		CreatedAt:    time.Now(),
+	}
+	session.transcript.Meta = meta
+	if config.Resume != nil {
+		meta.Title = config.Resume.Meta.Title
+		meta.CreatedAt = config.Resume.Meta.CreatedAt
+		session.transcript.Meta = meta
+		session.transcript.Entries = append([]TranscriptEntry{}, config.Resume.Entries...)
+		// 恢复的历史轮次计入会话用量，费用按会话原模型的价格估算
+		session.usage.total = config.Resume.TotalUsage()
+		session.usage.cost = estimateCost(config.Resume.Meta.Adapter, session.usage.total)
+	}
+	if config.Store != nil {
+		session.recorder = config.Store.newRecorder(meta, config.Resume != nil)
+	}
+	if config.Observer != nil {
+		session.Subscribe(config.Observer)
+	}
+
 	return session
 }
 
+// LastTurn 返回最近一轮对话的执行统计
+func (s *Session) LastTurn() TurnStats {
+	return s.lastTurn
+}
+
+// ID 返回会话ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Close 关闭会话持久化文件
+func (s *Session) Close() error {
+	return s.recorder.close()
+}
+
+// appendMessage 添加消息到历史记录并持久化
+func (s *Session) appendMessage(msg llm.Message) {
+	s.messages = append(s.messages, msg)
+	s.recordEntry(TranscriptEntry{Time: time.Now(), Message: msg})
+}
+
+// appendInternalMessage 添加由程序发送的控制提示，会话记录中与用户输入区分
+func (s *Session) appendInternalMessage(msg llm.Message) {
+	s.messages = append(s.messages, msg)
+	s.recordEntry(TranscriptEntry{Time: time.Now(), Message: msg, Internal: true})
+}
+
+// recordEntry 将消息写入会话记录
+func (s *Session) recordEntry(entry TranscriptEntry) {
+	s.transcript.Entries = append(s.transcript.Entries, entry)
+	s.recorder.record(entry)
+}
+
+// recordTurnUsage 在本轮的最终回答上记录模型与令牌用量
+func (s *Session) recordTurnUsage(usage llm.TokenUsage) {
+	turn := TurnUsage{Model: s.client.GetModelInfo().Name, Usage: usage}
+	if n := len(s.transcript.Entries); n > 0 {
+		s.transcript.Entries[n-1].Usage = &turn
+	}
+	s.recorder.recordUsage(turn)
+}
+
+// updateMeta 修改会话元信息并持久化
+func (s *Session) updateMeta(update func(meta *SessionMeta)) {
+	update(&s.transcript.Meta)
+	if s.recorder != nil {
+		s.recorder.updateMeta(update)
+	}
+}
+
+// Transcript 返回完整的会话记录副本，包含工具调用、工具结果与每轮用量
+func (s *Session) Transcript() *Transcript {
+	return &Transcript{
+		Meta:    s.transcript.Meta,
+		Entries: append([]TranscriptEntry{}, s.transcript.Entries...),
+	}
+}
+
 // ProcessMessage 处理用户输入并返回最终的 AI 响应
-func (s *Session) ProcessMessage(ctx context.Context, userInput string) (string, error) {
-	// 标记本轮对话的起始位置
+func (s *Session) ProcessMessage(ctx context.Context, userInput string) (reply string, err error) {
+	// 配置热重载后切换到最新的适配器实例
+	s.syncClient()
+
+	modelInfo := s.client.GetModelInfo()
+	startTime := s.beginTurn("chat", userInput)
+	defer func() { s.endTurn(startTime, reply, s.lastTurn.Usage, err) }()
+	ctx, span := tracing.Start(ctx, "chat.process_message", tracing.SpanKindInternal)
+	defer func() {
+		span.RecordError(err)
+		span.SetAttributes(map[string]any{
+			"chat.rounds":            s.lastTurn.Rounds,
+			"chat.tool_calls":        len(s.lastTurn.ToolCalls),
+			"llm.usage.total_tokens": s.lastTurn.Usage.TotalTokens,
+		})
+		span.End()
+	}()
+	span.SetAttributes(map[string]any{
+		"chat.mode":    s.config.Mode,
+		"llm.provider": modelInfo.Type,
+		"llm.model":    modelInfo.Name,
+		"chat.input":   len(userInput),
+	})
+
+	// 先修剪历史再标记本轮对话的起始位置，本轮进行中不再修剪，回滚与整合时索引保持有效
+	s.trimHistory()
 	roundStartIndex := len(s.messages)
-	// 将用户输入添加到消息历史
-	s.messages = append(s.messages, llm.Message{Role: "user", Content: userInput})
+	// 将脱敏后的用户输入添加到消息历史
+	redactedInput, redactedCount := s.redactor.Redact(userInput)
+	s.appendMessage(llm.Message{Role: "user", Content: redactedInput})
+	defer func() {
+		if redactedCount > 0 {
+			s.notices = append(s.notices, fmt.Sprintf("已脱敏 %d 处敏感信息后发送给模型", redactedCount))
+		}
+	}()
+
+	turnChoice := s.toolChoice
+	if s.onceToolChoice != nil {
+		turnChoice = *s.onceToolChoice
+		s.onceToolChoice = nil
+	}
 
-	for {
-		s.trimHistory()
+	for round := 0; ; round++ {
 		// 发送消息到 AI
-		resp, err := s.client.SendMessage(ctx, s.messages, s.toolDefs)
+		roundChoice := s.toolChoiceForRound(turnChoice, round)
+		resp, err := s.sendRequest(ctx, s.messages, roundChoice)
 		if err != nil {
-			// 如果出错，从历史中移除最后一条消息，以备重试
-			s.messages = s.messages[:len(s.messages)-1]
+			// 如果出错，从历史中移除本轮的全部消息，以备重试
+			s.rollbackRound(roundStartIndex)
+			if ctx.Err() != nil {
+				return "", interruptedError(ctx)
+			}
 			return "", fmt.Errorf("发送消息到AI失败: %w", err)
 		}
 
-		// 调试：打印完整的 AI 响应
-		respBytes, _ := json.Marshal(resp)
-		util.Debugw("收到 AI 响应", map[string]any{"response": string(respBytes)})
-
 		// 将 AI 的响应（不含工具调用）添加到历史记录
 		aiResponseMsg := llm.Message{
 			Role:      "assistant",
 			Content:   resp.Content,
 			ToolCalls: resp.ToolCalls,
 		}
-		s.messages = append(s.messages, aiResponseMsg)
+		s.appendMessage(aiResponseMsg)
+		s.lastTurn.Usage.PromptTokens += resp.Usage.PromptTokens
+		s.lastTurn.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		s.lastTurn.Usage.TotalTokens += resp.Usage.TotalTokens
 
 		// 检查是否有工具调用需要执行
 		if len(resp.ToolCalls) > 0 {
+			// 已禁用工具的请求仍返回工具调用时不再执行，避免无限循环
+			if roundChoice.Mode == llm.ToolChoiceNone {
+				s.rollbackRound(roundStartIndex)
+				return "", errors.NewErrorWithDetails(errors.ErrCodeAIResponseInvalid, "模型在禁用工具后仍请求调用工具",
+					fmt.Sprintf("已执行 %d 轮工具调用", round))
+			}
 			// 需要调用工具
-			toolResults, err := s.executeTools(ctx, resp.ToolCalls)
+			toolResults, toolRedacted, err := s.executeTools(ctx, resp.ToolCalls)
+			redactedCount += toolRedacted
+			if err == nil {
+				// 工具执行期间被取消时，结果只是取消错误，不再继续请求模型
+				err = ctx.Err()
+			}
 			if err != nil {
+				s.rollbackRound(roundStartIndex)
+				if ctx.Err() != nil {
+					return "", interruptedError(ctx)
+				}
 				return "", fmt.Errorf("执行工具失败: %w", err)
 			}
 			// 将工具结果添加到历史记录中，然后继续循环
-			s.messages = append(s.messages, toolResults...)
+			for _, toolResult := range toolResults {
+				s.appendMessage(toolResult)
+			}
 			continue // 继续循环以获取最终的 AI 响应
 		}
 
@@ -96,23 +337,421 @@ func (s *Session) ProcessMessage(ctx context.Context, userInput string) (string,
 		case "stop":
 			// 对话完成，整合历史记录并返回最终内容
 			s.consolidateHistory(roundStartIndex)
-			return resp.Content, nil
+			s.recordTurnUsage(s.lastTurn.Usage)
+			s.lastTurnDone = true
+			return s.redactor.Restore(resp.Content), nil
 		case "tool_calls":
 			// 这种情况不应该发生，因为我们已经处理了工具调用
-			// 但为了健壮性，我们返回一个错误
+			// 但为了健壮性，我们回滚本轮并返回一个错误
+			s.rollbackRound(roundStartIndex)
 			return "", fmt.Errorf("unexpected state: finish_reason is 'tool_calls' but no tool calls were found")
 		default:
 			// 对于 Gemini，"STOP" 是一个有效的完成原因，即使没有工具调用
 			if s.client.GetModelInfo().Type == "gemini" && resp.FinishReason == "STOP" {
 				s.consolidateHistory(roundStartIndex)
-				return resp.Content, nil
+				s.recordTurnUsage(s.lastTurn.Usage)
+				s.lastTurnDone = true
+				return s.redactor.Restore(resp.Content), nil
 			}
 			// 其他未知的 finish_reason
+			s.rollbackRound(roundStartIndex)
 			return "", fmt.Errorf("unexpected finish_reason: %s", resp.FinishReason)
 		}
 	}
 }
 
+// beginTurn 重置本轮统计并通知观察者，返回开始时间
+func (s *Session) beginTurn(mode, input string) time.Time {
+	s.lastTurn = TurnStats{ToolCalls: []ToolCallTrace{}}
+	s.lastTurnDone = false
+	s.usage.startTurn()
+	s.emit(Event{Type: EventTurnStarted, Mode: mode, Input: input})
+	return time.Now()
+}
+
+// endTurn 通知观察者本轮对话完成或失败
+func (s *Session) endTurn(startTime time.Time, reply string, usage llm.TokenUsage, err error) {
+	if err != nil {
+		s.emit(Event{Type: EventError, Err: err, Duration: time.Since(startTime)})
+		return
+	}
+	s.emit(Event{Type: EventTurnFinished, Reply: reply, Usage: usage, Duration: time.Since(startTime)})
+}
+
+// sendRequest 发送一次模型请求，通知观察者并累计会话用量
+func (s *Session) sendRequest(ctx context.Context, messages []llm.Message, choice llm.ToolChoice) (*llm.Response, error) {
+	s.lastTurn.Rounds++
+	round := s.lastTurn.Rounds
+	s.emit(Event{Type: EventModelRequest, Round: round, Messages: len(messages)})
+
+	startTime := time.Now()
+	resp, err := s.client.SendMessage(ctx, messages, s.toolDefs, choice)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调试：打印完整的 AI 响应
+	respBytes, _ := json.Marshal(resp)
+	util.Debugw("收到 AI 响应", map[string]any{"response": string(respBytes)})
+
+	s.addUsage(resp.Usage)
+	s.emit(Event{Type: EventModelResponse, Round: round, Response: resp, Duration: time.Since(startTime)})
+	return resp, nil
+}
+
+// rollbackRound 移除本轮对话产生的消息。
+// 未完成的工具调用不能留在历史记录中，否则下一轮请求会被模型服务拒绝。
+// 本轮进行中不修剪历史，每条新消息都对应一条会话记录，一并撤销并写入回滚记录，
+// 恢复与导出会话时不再包含被取消或失败的一轮。
+func (s *Session) rollbackRound(roundStartIndex int) {
+	if roundStartIndex > len(s.messages) {
+		return
+	}
+	removed := len(s.messages) - roundStartIndex
+	s.messages = s.messages[:roundStartIndex]
+	if removed == 0 {
+		return
+	}
+	s.transcript.Entries = s.transcript.Entries[:max(len(s.transcript.Entries)-removed, 0)]
+	s.recorder.recordRollback(removed)
+}
+
+// interruptedError 本轮对话因取消或超时中断时返回的错误
+func interruptedError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.WrapError(errors.ErrCodeTimeout, "对话超时", ctx.Err())
+	}
+	return errors.WrapError(errors.ErrCodeContextCanceled, "对话已取消", ctx.Err())
+}
+
+// syncClient 检查注册表中的适配器实例，配置热重载后在下一轮对话切换到新实例
+func (s *Session) syncClient() {
+	if s.config.ModelName == "" {
+		return
+	}
+
+	name := s.config.ModelName
+	adapter, exists := llm.GetAdapter(name)
+	if !exists {
+		// 当前模型已从配置中移除，回退到默认模型
+		if cfg := config.GetConfig(); cfg != nil {
+			name = cfg.AI.DefaultModel
+			adapter, exists = llm.GetAdapter(name)
+		}
+		if !exists {
+			return
+		}
+	}
+
+	if adapter == s.client {
+		return
+	}
+
+	s.client = adapter
+	s.config.ModelName = name
+	notice := fmt.Sprintf("模型配置已更新，已切换到适配器 %s (%s)", name, adapter.GetModelInfo().Name)
+	s.notices = append(s.notices, notice)
+	util.Infow("会话已切换模型适配器", map[string]any{
+		"adapter": name,
+		"model":   adapter.GetModelInfo().Name,
+	})
+}
+
+// watchConfig 监听配置文件变更并热重载模型适配器，对话界面通过 onReload 显示重载结果，
+// 会话在下一轮对话时切换到新实例（见 syncClient）。返回停止监听的函数
+func watchConfig(onReload func(result llm.ReloadResult)) (stop func()) {
+	path := config.GetConfigPath()
+	if path == "" {
+		return func() {}
+	}
+	watcher := config.NewWatcher(path, config.DefaultWatchInterval, func(oldCfg, newCfg *config.AppConfig) {
+		onReload(llm.ReloadAdapters(oldCfg.AI.Models, newCfg.AI.Models))
+	})
+	watcher.Start()
+	return watcher.Stop
+}
+
+// SetToolChoice 设置会话默认的工具选择，作用于之后的每一轮对话
+func (s *Session) SetToolChoice(choice llm.ToolChoice) {
+	s.toolChoice = choice
+}
+
+// ForceToolChoiceOnce 设置仅作用于下一轮对话的工具选择
+func (s *Session) ForceToolChoiceOnce(choice llm.ToolChoice) {
+	s.onceToolChoice = &choice
+}
+
+// GetToolChoice 获取会话默认的工具选择
+func (s *Session) GetToolChoice() llm.ToolChoice {
+	return s.toolChoice
+}
+
+// ModelName 返回当前使用的模型适配器名称
+func (s *Session) ModelName() string {
+	return s.config.ModelName
+}
+
+// RunAgent 以智能体方式执行任务，见 AgentRunner
+func (s *Session) RunAgent(ctx context.Context, goal string, hooks AgentHooks) (*AgentReport, error) {
+	return NewAgentRunner(s, s.config.AgentBudget, hooks).Run(ctx, goal)
+}
+
+// Mode 返回当前的对话模式
+func (s *Session) Mode() string {
+	return s.config.Mode
+}
+
+// SwitchModel 切换到指定的模型适配器，对话历史保持不变
+func (s *Session) SwitchModel(name string) error {
+	adapter, exists := llm.GetAdapter(name)
+	if !exists {
+		return errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", name)
+	}
+
+	s.client = adapter
+	s.config.ModelName = name
+	s.updateMeta(func(meta *SessionMeta) {
+		meta.Adapter = name
+		meta.Model = adapter.GetModelInfo().Name
+	})
+	s.refreshSystemPrompt()
+	util.Infow("会话已切换模型适配器", map[string]any{
+		"adapter": name,
+		"model":   adapter.GetModelInfo().Name,
+	})
+	return nil
+}
+
+// SetMode 切换对话模式，未自定义系统提示词时按新模式重新生成
+func (s *Session) SetMode(mode string) error {
+	if mode != "chat" && mode != "agent" {
+		return errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "不支持的对话模式", mode)
+	}
+
+	s.config.Mode = mode
+	s.refreshSystemPrompt()
+	s.updateMeta(func(meta *SessionMeta) { meta.Mode = mode })
+	return nil
+}
+
+// Profile 返回当前使用的提示词模板名称
+func (s *Session) Profile() string {
+	if s.config.Profile == "" {
+		return prompt.DefaultProfile
+	}
+	return s.config.Profile
+}
+
+// Profiles 列出可用的提示词模板
+func (s *Session) Profiles() ([]prompt.Profile, error) {
+	return s.promptLibrary().Profiles()
+}
+
+// SetProfile 切换提示词模板，未自定义系统提示词时立即生效
+func (s *Session) SetProfile(profile string) error {
+	// 先确认模板存在且能解析，避免切换后静默回退到内置模板
+	if _, err := s.promptLibrary().Load(profile, s.config.Mode); err != nil {
+		return err
+	}
+
+	s.config.Profile = profile
+	s.refreshSystemPrompt()
+	s.updateMeta(func(meta *SessionMeta) { meta.Profile = profile })
+	return nil
+}
+
+// SystemPrompt 返回当前生效的系统提示词
+func (s *Session) SystemPrompt() string {
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		return s.messages[0].Content
+	}
+	return ""
+}
+
+// HasCustomSystemPrompt 是否使用了自定义的系统提示词
+func (s *Session) HasCustomSystemPrompt() bool {
+	return s.customPrompt != ""
+}
+
+// SetSystemPrompt 自定义系统提示词，prompt 为空时恢复为按模式生成的提示词
+func (s *Session) SetSystemPrompt(prompt string) {
+	s.customPrompt = prompt
+	if prompt == "" {
+		prompt = s.getSystemPrompt()
+	}
+	s.setSystemMessage(prompt)
+}
+
+// setSystemMessage 替换历史记录中的系统提示词
+func (s *Session) setSystemMessage(prompt string) {
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		s.messages[0].Content = prompt
+		return
+	}
+	s.messages = append([]llm.Message{{Role: "system", Content: prompt}}, s.messages...)
+}
+
+// ToolStatus 工具在会话中的启用状态
+type ToolStatus struct {
+	Name        string
+	Description string
+	Risk        tools.RiskLevel
+	Enabled     bool
+}
+
+// Tools 返回所有工具及其在本会话中的启用状态，按名称排序
+func (s *Session) Tools() []ToolStatus {
+	all := s.toolManager.GetTools()
+	statuses := make([]ToolStatus, 0, len(all))
+	for _, tool := range all {
+		statuses = append(statuses, ToolStatus{
+			Name:        tool.ID(),
+			Description: tool.Description(),
+			Risk:        tools.RiskOf(tool),
+			Enabled:     !s.config.DisableTools && !s.disabledTools[tool.ID()],
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// SetToolEnabled 在本会话中启用或禁用指定工具
+func (s *Session) SetToolEnabled(name string, enabled bool) error {
+	if s.config.DisableTools {
+		return errors.NewError(errors.ErrCodeInvalidParameters, "本会话已禁用工具调用")
+	}
+
+	found := false
+	for _, def := range s.toolManager.GetToolDefinitions() {
+		if def.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.NewErrorWithDetails(errors.ErrCodeToolNotFound, "工具不存在", name)
+	}
+
+	if enabled {
+		delete(s.disabledTools, name)
+	} else {
+		s.disabledTools[name] = true
+	}
+	s.refreshToolDefs()
+	// 系统提示词中的工具列表与启用的工具保持一致
+	s.refreshSystemPrompt()
+	return nil
+}
+
+// refreshToolDefs 根据工具管理器和禁用列表重新生成发送给模型的工具定义
+func (s *Session) refreshToolDefs() {
+	if s.config.DisableTools {
+		s.toolDefs = nil
+		return
+	}
+	defs := s.toolManager.GetToolDefinitions()
+	s.toolDefs = make([]tools.ToolDefinition, 0, len(defs))
+	for _, def := range defs {
+		if !s.disabledTools[def.Name] {
+			s.toolDefs = append(s.toolDefs, def)
+		}
+	}
+}
+
+// Reset 清空对话历史，保留系统提示词
+func (s *Session) Reset() {
+	var history []llm.Message
+	if len(s.messages) > 0 && s.messages[0].Role == "system" {
+		history = append(history, s.messages[0])
+	}
+	s.messages = history
+	s.lastTurnDone = false
+	s.usage.resetContext()
+}
+
+// RollbackLastTurn 从历史记录中移除最近一轮已完成的对话，用于重新生成回答
+func (s *Session) RollbackLastTurn() bool {
+	if !s.lastTurnDone {
+		return false
+	}
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].Role == "user" {
+			s.messages = s.messages[:i]
+			s.lastTurnDone = false
+			return true
+		}
+	}
+	return false
+}
+
+// Save 立即创建会话文件，title 非空时设置会话标题，返回会话文件路径
+func (s *Session) Save(title string) (string, error) {
+	if s.recorder == nil {
+		return "", errors.NewError(errors.ErrCodeInvalidParameters, "会话持久化未启用")
+	}
+	if err := s.recorder.save(title); err != nil {
+		return "", errors.WrapError(errors.ErrCodeInternalErr, "保存会话失败", err)
+	}
+	if title != "" {
+		s.transcript.Meta.Title = title
+	}
+	return s.recorder.path, nil
+}
+
+// toolChoiceForRound 计算本轮对话中第 round 次模型请求使用的工具选择。
+// 强制调用只作用于首次请求，避免模型被反复要求调用工具；达到工具轮数上限后禁用工具。
+func (s *Session) toolChoiceForRound(turnChoice llm.ToolChoice, round int) llm.ToolChoice {
+	if round >= s.maxToolRounds() {
+		return llm.ToolChoice{Mode: llm.ToolChoiceNone}
+	}
+	if round > 0 && turnChoice.IsForced() {
+		return llm.ToolChoice{Mode: llm.ToolChoiceAuto}
+	}
+	if turnChoice.Mode == llm.ToolChoiceTool && !s.hasTool(turnChoice.Name) {
+		util.Warnw("指定的工具不存在，使用自动工具选择", map[string]any{"tool_name": turnChoice.Name})
+		return llm.ToolChoice{Mode: llm.ToolChoiceAuto}
+	}
+	return turnChoice
+}
+
+// maxToolRounds 单轮对话的工具调用轮数上限，未设置时使用智能体预算的最多步骤数
+func (s *Session) maxToolRounds() int {
+	if s.config.MaxToolRounds > 0 {
+		return s.config.MaxToolRounds
+	}
+	return s.config.AgentBudget.withDefaults().MaxSteps
+}
+
+// hasTool 检查会话中是否存在指定名称的工具
+func (s *Session) hasTool(name string) bool {
+	for _, def := range s.toolDefs {
+		if def.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TakeNotices 取出并清空待展示的系统通知
+func (s *Session) TakeNotices() []string {
+	notices := s.notices
+	s.notices = nil
+	return notices
+}
+
+// reservePlaceholders 登记历史消息中已有的脱敏占位符。恢复的会话使用新的脱敏器，
+// 旧占位符与原文的对应关系不会保存，新的敏感信息不能复用这些占位符
+func (s *Session) reservePlaceholders(message llm.Message) {
+	s.redactor.Reserve(message.Content)
+	for _, tc := range message.ToolCalls {
+		if args, err := json.Marshal(tc.Arguments); err == nil {
+			s.redactor.Reserve(string(args))
+		}
+	}
+}
+
 // trimHistory 修剪历史记录，以防止其无限增长
 func (s *Session) trimHistory() {
 	if len(s.messages) <= s.maxHistory {
@@ -123,9 +762,15 @@ func (s *Session) trimHistory() {
 	firstMessage := s.messages[0]
 	recentMessages := s.messages[len(s.messages)-(s.maxHistory-1):]
 
+	removed := len(s.messages) - s.maxHistory
 	s.messages = make([]llm.Message, 0, s.maxHistory)
 	s.messages = append(s.messages, firstMessage)
 	s.messages = append(s.messages, recentMessages...)
+	s.emit(Event{Type: EventHistoryCompacted, Compaction: &HistoryCompaction{
+		Reason:    "trim",
+		Removed:   removed,
+		Remaining: len(s.messages),
+	}})
 }
 
 // consolidateHistory 整合一轮对话的历史记录。
@@ -153,37 +798,66 @@ func (s *Session) consolidateHistory(roundStartIndex int) {
 	newMessages = append(newMessages, userMessage)
 	newMessages = append(newMessages, finalAssistantMessage)
 
+	removed := len(s.messages) - len(newMessages)
 	s.messages = newMessages
-	util.Debugw("历史记录已整合", map[string]any{"history_size": len(s.messages)})
+	s.emit(Event{Type: EventHistoryCompacted, Compaction: &HistoryCompaction{
+		Reason:    "consolidate",
+		Removed:   removed,
+		Remaining: len(s.messages),
+	}})
 }
 
 // executeTools 执行工具调用并返回结果消息
-func (s *Session) executeTools(ctx context.Context, toolCalls []llm.ToolCall) ([]llm.Message, error) {
+func (s *Session) executeTools(ctx context.Context, toolCalls []llm.ToolCall) ([]llm.Message, int, error) {
 	var toolMessages []llm.Message
+	redactedCount := 0
 
 	for _, tc := range toolCalls {
-		result, err := s.toolManager.ExecuteToolCall(ctx, tools.ToolCall{
+		// 已取消时不再执行剩余的工具调用
+		if err := ctx.Err(); err != nil {
+			return nil, redactedCount, err
+		}
+
+		// 模型看到的是占位符，执行前在本地还原为原文
+		args := s.redactor.RestoreArguments(tc.Arguments)
+		trace := ToolCallTrace{
 			ID:        tc.ID,
 			Name:      tc.Name,
 			Arguments: tc.Arguments,
-		})
+		}
 
-		var content string
+		// 有风险的调用需要先确认
+		risk, approval, err := s.approveToolCall(ctx, tc, args)
 		if err != nil {
-			// 将错误信息作为工具的返回结果
-			content = fmt.Sprintf("Error executing tool %s: %v", tc.Name, err)
+			return nil, redactedCount, err
+		}
+		trace.Risk = string(risk)
+		trace.Approval = string(approval.Decision)
+
+		var content string
+		if approval.Decision == ApprovalDenied {
+			reason, n := s.redactor.Redact(approval.Reason)
+			redactedCount += n
+			content = deniedToolResult(tc.Name, risk, reason)
+			trace.Error = "未获批准，没有执行"
+			s.emitTool(EventToolFailed, ToolEvent{
+				CallID:    tc.ID,
+				ToolName:  tc.Name,
+				Arguments: tc.Arguments,
+				Risk:      trace.Risk,
+				Error:     trace.Error,
+			})
 		} else {
-			// 尝试将结果序列化为 JSON 字符串
-			resultBytes, jsonErr := json.Marshal(result)
-			if jsonErr != nil {
-				content = fmt.Sprintf("Failed to serialize result for tool %s: %v", tc.Name, jsonErr)
-			} else {
-				content = string(resultBytes)
-				// 对工具响应内容进行长度限制，防止消息过长导致API调用失败
-				content = s.truncateToolResponse(content, tc.Name)
-			}
+			var n int
+			content, n = s.runToolCall(ctx, tc, args, approval, &trace)
+			redactedCount += n
 		}
 
+		if trace.Error == "" {
+			trace.Result = content
+		}
+		s.lastTurn.ToolCalls = append(s.lastTurn.ToolCalls, trace)
+
 		// 创建工具结果消息
 		toolMessage := llm.Message{
 			Role:       "tool",
@@ -194,76 +868,179 @@ func (s *Session) executeTools(ctx context.Context, toolCalls []llm.ToolCall) ([
 		toolMessages = append(toolMessages, toolMessage)
 	}
 
-	return toolMessages, nil
+	return toolMessages, redactedCount, nil
 }
 
-// getSystemPrompt 根据模式生成系统提示词
-func (s *Session) getSystemPrompt() string {
-	// toolDescriptions := s.buildToolDescriptions()
-	toolDescriptions := ""
+// runToolCall 执行已确认的工具调用，返回发送给模型的工具结果和脱敏次数
+func (s *Session) runToolCall(ctx context.Context, tc llm.ToolCall, args map[string]any, approval ApprovalResponse, trace *ToolCallTrace) (string, int) {
+	if approval.Decision == ApprovalEdited {
+		args = approval.Arguments
+	}
 
-	switch s.config.Mode {
-	case "agent":
-		return s.getAgentSystemPrompt(toolDescriptions)
-	default: // "chat"
-		return s.getChatSystemPrompt(toolDescriptions)
+	event := ToolEvent{
+		CallID:    tc.ID,
+		ToolName:  tc.Name,
+		Arguments: tc.Arguments,
+		Risk:      trace.Risk,
 	}
-}
+	s.emitTool(EventToolStarted, event)
 
-// buildToolDescriptions 构建工具描述
-// func (s *Session) buildToolDescriptions() string {
-// 	var descriptions []string
-// 	for _, tool := range s.toolDefs {
-// 		descriptions = append(descriptions, fmt.Sprintf("- %s: %s", tool.Name, tool.Description))
-// 	}
-// 	return strings.Join(descriptions, "\n")
-// }
+	startTime := time.Now()
+	result, err := s.toolManager.ExecuteToolCall(ctx, tools.ToolCall{
+		ID:        tc.ID,
+		Name:      tc.Name,
+		Arguments: args,
+	})
+	event.Duration = time.Since(startTime)
+	trace.DurationMs = event.Duration.Milliseconds()
 
-// getChatSystemPrompt 普通对话模式的系统提示词
-func (s *Session) getChatSystemPrompt(toolDescriptions string) string {
-	thinkingPrompt := ""
-	if s.config.ShowThinking {
-		thinkingPrompt = `
+	var content string
+	redactedCount := 0
+	eventType := EventToolFinished
+	if err != nil {
+		// 将错误信息作为工具的返回结果
+		errMsg, n := s.redactor.Redact(err.Error())
+		redactedCount += n
+		content = fmt.Sprintf("Error executing tool %s: %s", tc.Name, errMsg)
+		trace.Error = errMsg
+		eventType = EventToolFailed
+		event.Error = errMsg
+	} else {
+		event.ResultSize = len(result)
+		// 工具结果可能包含密码、令牌等敏感信息，发送给模型前脱敏
+		var n int
+		result, n = s.redactor.Redact(result)
+		redactedCount += n
 
-重要：你必须在每次回答时都展示思考过程。请严格按照以下格式：
+		// 尝试将结果序列化为 JSON 字符串
+		resultBytes, jsonErr := json.Marshal(result)
+		if jsonErr != nil {
+			content = fmt.Sprintf("Failed to serialize result for tool %s: %v", tc.Name, jsonErr)
+		} else {
+			content = string(resultBytes)
+			// 对工具响应内容进行长度限制，防止消息过长导致API调用失败
+			content = s.truncateToolResponse(content, tc.Name)
+		}
+		event.Result = toolResultPreview(content)
+	}
+	s.emitTool(eventType, event)
 
-**思考过程开始**
-1. 问题分析：用户问的是...
-2. 思考方向：我需要考虑...
-3. 解决方案：我的回答策略是...
-**思考过程结束**
+	if approval.Decision == ApprovalEdited {
+		// 告知模型实际执行时使用的参数
+		editedArgs, _ := json.Marshal(args)
+		redactedArgs, n := s.redactor.Redact(string(editedArgs))
+		redactedCount += n
+		content = fmt.Sprintf("注意: 用户在执行前将参数修改为 %s\n%s", redactedArgs, content)
+	}
+	return content, redactedCount
+}
 
-然后给出清晰的正式回答。`
+// approveToolCall 对需要确认的工具调用请求人工确认，返回工具风险等级和确认结果
+func (s *Session) approveToolCall(ctx context.Context, tc llm.ToolCall, args map[string]any) (tools.RiskLevel, ApprovalResponse, error) {
+	tool, err := s.toolManager.GetTool(tc.Name)
+	if err != nil {
+		// 工具不存在时交由执行阶段报错
+		return "", ApprovalResponse{}, nil
+	}
+	risk := tools.RiskOf(tool)
+	// 已禁用的工具没有提供给模型，模型仍然调用时直接拒绝
+	if s.config.DisableTools || s.disabledTools[tc.Name] {
+		return risk, ApprovalResponse{Decision: ApprovalDenied, Reason: "该工具在本会话中已禁用"}, nil
+	}
+	if !risk.RequiresApproval() {
+		return risk, ApprovalResponse{}, nil
+	}
+
+	var response ApprovalResponse
+	policyDenied := false
+	switch s.config.ApprovalMode {
+	case ApprovalModeAuto:
+		response = ApprovalResponse{Decision: ApprovalAutoApproved}
+	case ApprovalModeDeny:
+		response = ApprovalResponse{Decision: ApprovalDenied, Reason: "配置禁止执行有风险的工具调用"}
+		policyDenied = true
+	default:
+		if s.config.Approver == nil {
+			response = ApprovalResponse{Decision: ApprovalDenied, Reason: "当前环境无法进行人工确认"}
+			policyDenied = true
+			break
+		}
+		response, err = s.config.Approver.Approve(ctx, ApprovalRequest{
+			CallID:    tc.ID,
+			ToolName:  tc.Name,
+			Risk:      risk,
+			Arguments: args,
+		})
+		if err != nil {
+			return risk, response, fmt.Errorf("等待确认工具调用 %s 失败: %w", tc.Name, err)
+		}
 	}
 
-	return fmt.Sprintf(`你是一个智能的AI助手，专注于帮助用户解决问题和提供信息。
+	util.Infow("工具调用确认结果", map[string]any{
+		"tool_name": tc.Name,
+		"call_id":   tc.ID,
+		"risk":      risk,
+		"decision":  response.Decision,
+	})
+	// 用户在确认界面中已看到结果，只需提示按配置拒绝的调用
+	if policyDenied {
+		s.notices = append(s.notices, fmt.Sprintf("已拒绝执行%s操作 %s: %s", risk.Label(), tc.Name, response.Reason))
+	}
+	return risk, response, nil
+}
 
-可用工具:
-%s
+// deniedToolResult 生成拒绝执行时返回给模型的工具结果
+func deniedToolResult(toolName string, risk tools.RiskLevel, reason string) string {
+	content := fmt.Sprintf("工具 %s（风险等级: %s）未获批准，没有执行", toolName, risk)
+	if reason != "" {
+		content += "，原因: " + reason
+	}
+	return content + "。请不要重复相同的调用，可向用户说明需要执行的操作，或改用只读方式继续。"
+}
 
-工作特点:
-- 友好、耐心、准确地回答用户问题
-- 主动使用工具获取实时信息和执行操作
-- 提供清晰、结构化的回答
-- 在需要时展示思考过程
+// getSystemPrompt 按提示词模板和模式生成系统提示词，模板无法使用时回退到内置模板
+func (s *Session) getSystemPrompt() string {
+	toolInfos := make([]prompt.Tool, 0, len(s.toolDefs))
+	for _, def := range s.toolDefs {
+		toolInfos = append(toolInfos, prompt.Tool{Name: def.Name, Description: def.Description})
+	}
+	data := prompt.NewData(s.config.Mode, s.client.GetModelInfo().Name, toolInfos, s.config.PromptVars)
 
-工具使用指导:
-- 使用参数过滤减少不必要的数据量
-- 系统会自动截断过长的工具响应
-- 专注于用户关心的核心信息
+	text, err := s.promptLibrary().Render(s.config.Profile, s.config.Mode, data)
+	if err != nil {
+		util.Warnw("渲染提示词模板失败，使用内置模板", map[string]any{
+			"profile": s.config.Profile,
+			"error":   err.Error(),
+		})
+		text, _ = prompt.Builtin().Render(prompt.DefaultProfile, s.config.Mode, data)
+	}
 
-回答风格:
-- 简洁明了，直接回答用户问题
-- 适当使用markdown格式提升可读性
-- 必要时提供代码示例和解决方案
-- 如果需要思考，可以说明推理过程%s`, toolDescriptions, thinkingPrompt)
+	// 思考过程的标记格式由界面解析，不随模板变化
+	if s.config.ShowThinking {
+		text += thinkingInstructions(s.config.Mode)
+	}
+	return text
 }
 
-// getAgentSystemPrompt 智能体模式的系统提示词
-func (s *Session) getAgentSystemPrompt(toolDescriptions string) string {
-	thinkingPrompt := ""
-	if s.config.ShowThinking {
-		thinkingPrompt = `
+// promptLibrary 返回会话使用的提示词模板库
+func (s *Session) promptLibrary() *prompt.Library {
+	if s.config.Prompts != nil {
+		return s.config.Prompts
+	}
+	return prompt.Builtin()
+}
+
+// refreshSystemPrompt 模式、模型、工具或模板变化后重新生成系统提示词，自定义的提示词保持不变
+func (s *Session) refreshSystemPrompt() {
+	if s.customPrompt == "" {
+		s.setSystemMessage(s.getSystemPrompt())
+	}
+}
+
+// thinkingInstructions 要求模型按固定格式展示思考过程的说明
+func thinkingInstructions(mode string) string {
+	if mode == "agent" {
+		return `
 
 重要：你必须在每次回答时都展示思考过程。请严格按照以下格式：
 
@@ -277,34 +1054,17 @@ func (s *Session) getAgentSystemPrompt(toolDescriptions string) string {
 然后给出用户友好的正式回答。`
 	}
 
-	return fmt.Sprintf(`你是一个自主的智能体，能够分析复杂任务并制定执行计划。
-
-可用工具:
-%s
-
-工作模式:
-1. 任务分析: 理解用户需求，识别关键要素
-2. 计划制定: 将复杂任务分解为可执行的步骤
-3. 自主执行: 主动调用工具，收集信息，执行操作
-4. 结果整合: 综合各步骤结果，提供完整解决方案
+	return `
 
-执行特点:
-- 具备强烈的目标导向性
-- 主动探索和尝试不同方法
-- 在遇到障碍时自主调整策略
-- 持续优化执行效率
-- 详细记录执行过程和思考逻辑
+重要：你必须在每次回答时都展示思考过程。请严格按照以下格式：
 
-工具使用原则:
-- 优先使用参数过滤来减少数据量（如使用match参数筛选指标）
-- 当工具返回大量数据时，系统会自动截断过长内容
-- 关注最重要的数据，避免一次性获取全部数据
-- 根据任务需求选择合适的查询参数
+**思考过程开始**
+1. 问题分析：用户问的是...
+2. 思考方向：我需要考虑...
+3. 解决方案：我的回答策略是...
+**思考过程结束**
 
-输出要求:
-- 清晰说明每个步骤的目的和方法
-- 展示完整的问题解决过程
-- 提供可操作的建议和下一步行动%s`, toolDescriptions, thinkingPrompt)
+然后给出清晰的正式回答。`
 }
 
 // ThinkingContent 思考内容结构
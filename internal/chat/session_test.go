@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util/errors"
+)
+
+// fakeAdapter 按顺序返回预设的响应，err 非 nil 的项作为请求错误返回
+type fakeAdapter struct {
+	replies  []fakeReply
+	requests [][]llm.Message
+}
+
+type fakeReply struct {
+	resp *llm.Response
+	err  error
+}
+
+func (a *fakeAdapter) SendMessage(_ context.Context, messages []llm.Message, _ []tools.ToolDefinition, _ llm.ToolChoice) (*llm.Response, error) {
+	a.requests = append(a.requests, append([]llm.Message{}, messages...))
+	if len(a.replies) == 0 {
+		return nil, fmt.Errorf("没有预设的响应")
+	}
+	reply := a.replies[0]
+	a.replies = a.replies[1:]
+	return reply.resp, reply.err
+}
+
+func (a *fakeAdapter) GetModelInfo() llm.ModelInfo       { return llm.ModelInfo{Name: "fake", Type: "fake"} }
+func (a *fakeAdapter) GetAdapterInfo() llm.AdapterInfo   { return llm.AdapterInfo{Name: "fake"} }
+func (a *fakeAdapter) HealthCheck(context.Context) error { return nil }
+func (a *fakeAdapter) ValidateConfig(interface{}) error  { return nil }
+func (a *fakeAdapter) GetMetrics() llm.AdapterMetrics    { return llm.AdapterMetrics{} }
+func (a *fakeAdapter) Close() error                      { return nil }
+
+// answer 返回不含工具调用的最终回答
+func answer(content string) fakeReply {
+	return fakeReply{resp: &llm.Response{Content: content, FinishReason: "stop"}}
+}
+
+// callTool 返回一次工具调用
+func callTool(id, name string, args map[string]any) fakeReply {
+	return fakeReply{resp: &llm.Response{
+		ToolCalls:    []llm.ToolCall{{ID: id, Name: name, Arguments: args}},
+		FinishReason: "tool_calls",
+	}}
+}
+
+// fakeTool 记录执行参数的工具
+type fakeTool struct {
+	name  string
+	risk  tools.RiskLevel // 为空时不声明风险等级
+	calls []map[string]any
+}
+
+func (t *fakeTool) ID() string                 { return t.name }
+func (t *fakeTool) Name() string               { return t.name }
+func (t *fakeTool) Type() string               { return "test" }
+func (t *fakeTool) Description() string        { return "测试工具 " + t.name }
+func (t *fakeTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (t *fakeTool) Execute(_ context.Context, args map[string]any) (string, error) {
+	t.calls = append(t.calls, args)
+	return fmt.Sprintf("%s 执行完成: %v", t.name, args), nil
+}
+
+// riskyTool 声明了风险等级的工具
+type riskyTool struct{ *fakeTool }
+
+func (t riskyTool) RiskLevel() tools.RiskLevel { return t.risk }
+
+// fakeToolManager 只包含指定工具的工具管理器
+type fakeToolManager struct {
+	tools []tools.Tool
+}
+
+func newFakeToolManager(list ...*fakeTool) *fakeToolManager {
+	m := &fakeToolManager{}
+	for _, tool := range list {
+		if tool.risk != "" {
+			m.tools = append(m.tools, riskyTool{tool})
+		} else {
+			m.tools = append(m.tools, tool)
+		}
+	}
+	return m
+}
+
+func (m *fakeToolManager) RegisterTool(tools.Tool) error                   { return nil }
+func (m *fakeToolManager) RegisterToolFactory(string, tools.PluginFactory) {}
+func (m *fakeToolManager) InitializePlugins()                              {}
+func (m *fakeToolManager) GetTools() []tools.Tool                          { return m.tools }
+
+func (m *fakeToolManager) GetTool(name string) (tools.Tool, error) {
+	for _, tool := range m.tools {
+		if tool.ID() == name {
+			return tool, nil
+		}
+	}
+	return nil, errors.NewErrorWithDetails(errors.ErrCodeToolNotFound, "工具不存在", name)
+}
+
+func (m *fakeToolManager) ExecuteToolCall(ctx context.Context, call tools.ToolCall) (string, error) {
+	tool, err := m.GetTool(call.Name)
+	if err != nil {
+		return "", err
+	}
+	return tool.Execute(ctx, call.Arguments)
+}
+
+func (m *fakeToolManager) GetToolDefinitions() []tools.ToolDefinition {
+	defs := make([]tools.ToolDefinition, 0, len(m.tools))
+	for _, tool := range m.tools {
+		defs = append(defs, tools.ToolDefinition{Name: tool.ID(), Description: tool.Description(), Parameters: tool.Parameters()})
+	}
+	return defs
+}
+
+func TestRollbackRoundIsNotPersisted(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	adapter := &fakeAdapter{replies: []fakeReply{
+		answer("第一轮回答"),
+		callTool("call_1", "check", nil),
+		{err: context.Canceled},
+		answer("第三轮回答"),
+	}}
+	session := NewSession(adapter, newFakeToolManager(&fakeTool{name: "check", risk: tools.RiskReadOnly}), SessionConfig{Mode: "chat", Store: store})
+	defer session.Close()
+
+	ctx := context.Background()
+	if _, err := session.ProcessMessage(ctx, "第一轮"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if _, err := session.ProcessMessage(ctx, "被取消的一轮"); err == nil {
+		t.Fatal("第二轮应失败")
+	}
+	if _, err := session.ProcessMessage(ctx, "第三轮"); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	want := []string{"第一轮", "第一轮回答", "第三轮", "第三轮回答"}
+	loaded, err := store.Load(session.ID())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for name, transcript := range map[string]*Transcript{"内存中的记录": session.Transcript(), "会话文件": loaded} {
+		var got []string
+		for _, entry := range transcript.Entries {
+			got = append(got, entry.Message.Content)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+		if history := transcript.History(); len(history) != 4 {
+			t.Errorf("%s 的 History() 有 %d 条消息, want 4", name, len(history))
+		}
+	}
+	if summary := loaded.Summary(); summary.Turns != 2 {
+		t.Errorf("Turns = %d, want 2", summary.Turns)
+	}
+}
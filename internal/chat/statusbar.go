@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 状态栏可以显示的项目
+const (
+	statusItemModel   = "model"   // 模型适配器与模型名称
+	statusItemTokens  = "tokens"  // 会话与最近一轮的令牌用量
+	statusItemCost    = "cost"    // 估算费用，模型未配置价格时不显示
+	statusItemContext = "context" // 上下文窗口占用
+	statusItemMCP     = "mcp"     // 已连接的 MCP 服务器数量
+	statusItemPhase   = "phase"   // 当前处理阶段
+)
+
+// 未配置时状态栏显示的项目
+var defaultStatusItems = []string{
+	statusItemModel, statusItemTokens, statusItemCost, statusItemContext, statusItemMCP, statusItemPhase,
+}
+
+// 上下文占用超过该百分比时提示
+const contextWarnPercent = 80
+
+// StatusBarOptions 对话界面状态栏的显示设置
+type StatusBarOptions struct {
+	// Items 显示的项目，nil 显示全部项目，空列表隐藏状态栏
+	Items []string
+	// Currency 费用的货币符号（空表示 $）
+	Currency string
+	// MCPStatus 返回 MCP 服务器的连接状态（nil 表示未启用 MCP）
+	MCPStatus func() map[string]bool
+}
+
+// items 返回要显示的项目
+func (o StatusBarOptions) items() []string {
+	if o.Items == nil {
+		return defaultStatusItems
+	}
+	return o.Items
+}
+
+// hasStatusBar 是否显示状态栏
+func (m *BubbleTeaModel) hasStatusBar() bool {
+	return len(m.session.config.StatusBar.items()) > 0
+}
+
+// renderStatusBar 渲染状态栏，超出界面宽度的部分截断
+func (m *BubbleTeaModel) renderStatusBar() string {
+	options := m.session.config.StatusBar
+	usage := m.status.Usage
+
+	var parts []string
+	for _, item := range options.items() {
+		var part string
+		switch item {
+		case statusItemModel:
+			part = m.status.Model
+			if m.status.ModelName != "" {
+				part = m.status.ModelName + " · " + part
+			}
+		case statusItemTokens:
+			part = fmt.Sprintf("令牌 %s（本轮 %s）",
+				formatTokenCount(usage.Session.TotalTokens), formatTokenCount(usage.LastTurn.TotalTokens))
+		case statusItemCost:
+			if usage.Priced || usage.Cost > 0 {
+				part = "费用 " + FormatCost(usage.Cost, options.Currency)
+			}
+		case statusItemContext:
+			if percent := usage.ContextPercent(); percent >= 0 {
+				part = fmt.Sprintf("上下文 %d%%", percent)
+				if percent >= contextWarnPercent {
+					part = "⚠️ " + part
+				}
+			}
+		case statusItemMCP:
+			if options.MCPStatus != nil {
+				part = formatMCPStatus(options.MCPStatus())
+			}
+		case statusItemPhase:
+			part = m.statusPhase()
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	width := m.width - 2
+	if width <= 0 {
+		width = 80
+	}
+	return m.statusBarStyle.MaxWidth(width).Render(strings.Join(parts, " │ "))
+}
+
+// refreshStatus 没有进行中的对话时从会话读取状态栏信息，对话进行中由会话事件更新
+func (m *BubbleTeaModel) refreshStatus() {
+	if !m.processing {
+		m.status = m.session.Status()
+	}
+}
+
+// statusPhase 返回当前处理阶段
+func (m *BubbleTeaModel) statusPhase() string {
+	switch {
+	case m.pendingApproval != nil:
+		return "⚠️ 等待确认 " + m.pendingApproval.request.ToolName
+	case m.cancelling:
+		return "⏹️ 正在取消"
+	case len(m.runningTools) > 0:
+		names := make([]string, 0, len(m.runningTools))
+		for _, entry := range m.runningTools {
+			names = append(names, entry.toolName)
+		}
+		sort.Strings(names)
+		return "🔧 执行工具 " + strings.Join(names, ", ")
+	case m.modelRound > 1:
+		return fmt.Sprintf("🤔 等待模型响应（第 %d 次请求）", m.modelRound)
+	case m.processing:
+		return "🤔 等待模型响应"
+	default:
+		return "✅ 就绪"
+	}
+}
+
+// formatMCPStatus 格式化 MCP 服务器连接数，未配置服务器时返回空
+func formatMCPStatus(status map[string]bool) string {
+	if len(status) == 0 {
+		return ""
+	}
+	connected := 0
+	for _, ok := range status {
+		if ok {
+			connected++
+		}
+	}
+	return fmt.Sprintf("MCP %d/%d", connected, len(status))
+}
+
+// formatTokenCount 格式化令牌数，如 1.2k、3.4M
+func formatTokenCount(tokens int) string {
+	switch {
+	case tokens < 1000:
+		return fmt.Sprintf("%d", tokens)
+	case tokens < 1000000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1000000)
+	}
+}
+
+// FormatCost 格式化估算费用，不足 1 时保留 4 位小数
+func FormatCost(cost float64, currency string) string {
+	if currency == "" {
+		currency = "$"
+	}
+	if cost < 1 {
+		return fmt.Sprintf("%s%.4f", currency, cost)
+	}
+	return fmt.Sprintf("%s%.2f", currency, cost)
+}
@@ -0,0 +1,436 @@
+package chat
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+const (
+	// 会话文件扩展名
+	sessionFileExt = ".jsonl"
+	// 恢复最近一次会话的特殊标识
+	LastSessionID = "last"
+)
+
+// 会话文件中的记录类型
+const (
+	recordMeta    = "meta"
+	recordMessage = "message"
+	recordUsage   = "usage"
+	// 回滚记录：撤销之前的若干条消息记录，如被取消或失败的一轮对话
+	recordRollback = "rollback"
+)
+
+// SessionMeta 会话元信息
+type SessionMeta struct {
+	ID           string    `json:"id"`
+	Adapter      string    `json:"adapter"` // 模型适配器名称
+	Model        string    `json:"model"`   // 实际模型名称
+	Mode         string    `json:"mode"`
+	ShowThinking bool      `json:"show_thinking,omitempty"`
+	Profile      string    `json:"profile,omitempty"` // 提示词模板名称
+	Title        string    `json:"title,omitempty"`   // 通过 /save 设置的会话标题
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SessionSummary 会话列表中的摘要信息
+type SessionSummary struct {
+	SessionMeta
+	UpdatedAt time.Time
+	Turns     int    // 用户消息数
+	Title     string // 会话标题，未设置时为第一条用户消息
+}
+
+// TurnUsage 一轮对话使用的模型与令牌用量
+type TurnUsage struct {
+	Model string         `json:"model"`
+	Usage llm.TokenUsage `json:"usage"`
+}
+
+// TranscriptEntry 会话记录中的一条消息
+type TranscriptEntry struct {
+	Time    time.Time
+	Message llm.Message
+	// Internal 智能体模式中由程序发送的控制提示，不是用户输入
+	Internal bool
+	// Usage 一轮对话结束时记录在最终回答上的用量
+	Usage *TurnUsage
+}
+
+// Transcript 完整的会话记录
+type Transcript struct {
+	Meta    SessionMeta
+	Entries []TranscriptEntry
+}
+
+// storeRecord 会话文件中的一行
+type storeRecord struct {
+	Type     string       `json:"type"`
+	Time     time.Time    `json:"time"`
+	Meta     *SessionMeta `json:"meta,omitempty"`
+	Message  *llm.Message `json:"message,omitempty"`
+	Internal bool         `json:"internal,omitempty"`
+	Usage    *TurnUsage   `json:"usage,omitempty"`
+	Removed  int          `json:"removed,omitempty"` // 回滚的消息记录数
+}
+
+// SessionStore 以每个会话一个 JSONL 文件的方式持久化会话
+type SessionStore struct {
+	dir string
+}
+
+// NewSessionStore 创建会话存储，dir 为空时使用默认目录
+func NewSessionStore(dir string) *SessionStore {
+	if dir == "" {
+		dir = DefaultSessionDir()
+	}
+	return &SessionStore{dir: dir}
+}
+
+// DefaultSessionDir 默认的会话存储目录 ~/.ai-ops/sessions
+func DefaultSessionDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ai-ops", "sessions")
+	}
+	return filepath.Join(homeDir, ".ai-ops", "sessions")
+}
+
+// Dir 返回会话存储目录
+func (st *SessionStore) Dir() string {
+	return st.dir
+}
+
+// List 列出所有会话，按最后更新时间倒序
+func (st *SessionStore) List() ([]SessionSummary, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取会话目录失败", err)
+	}
+
+	var summaries []SessionSummary
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionFileExt) {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), sessionFileExt)
+		transcript, err := st.Load(id)
+		if err != nil {
+			util.Warnw("跳过无法解析的会话文件", map[string]any{"file": entry.Name(), "error": err.Error()})
+			continue
+		}
+		summaries = append(summaries, transcript.Summary())
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+// Resolve 将会话ID、ID前缀或 "last" 解析为完整的会话ID
+func (st *SessionStore) Resolve(ref string) (string, error) {
+	summaries, err := st.List()
+	if err != nil {
+		return "", err
+	}
+	if len(summaries) == 0 {
+		return "", errors.NewError(errors.ErrCodeNotFound, "没有已保存的会话")
+	}
+	if ref == "" || ref == LastSessionID {
+		return summaries[0].ID, nil
+	}
+
+	var matches []string
+	for _, summary := range summaries {
+		if summary.ID == ref {
+			return ref, nil
+		}
+		if strings.HasPrefix(summary.ID, ref) {
+			matches = append(matches, summary.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", errors.NewErrorWithDetails(errors.ErrCodeNotFound, "会话不存在", "会话ID: "+ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+			"会话ID前缀不唯一", fmt.Sprintf("匹配: %s", strings.Join(matches, ", ")))
+	}
+}
+
+// Load 读取会话记录
+func (st *SessionStore) Load(id string) (*Transcript, error) {
+	file, err := os.Open(st.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeNotFound, "会话不存在", "会话ID: "+id)
+		}
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "打开会话文件失败", err)
+	}
+	defer file.Close()
+
+	transcript := &Transcript{Meta: SessionMeta{ID: id}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record storeRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// 进程异常退出可能留下不完整的最后一行，忽略即可
+			util.Debugw("忽略无法解析的会话记录", map[string]any{"session": id, "error": err.Error()})
+			continue
+		}
+		switch record.Type {
+		case recordMeta:
+			if record.Meta != nil {
+				transcript.Meta = *record.Meta
+			}
+		case recordMessage:
+			if record.Message != nil {
+				transcript.Entries = append(transcript.Entries, TranscriptEntry{
+					Time:     record.Time,
+					Message:  *record.Message,
+					Internal: record.Internal,
+				})
+			}
+		case recordUsage:
+			if record.Usage != nil && len(transcript.Entries) > 0 {
+				transcript.Entries[len(transcript.Entries)-1].Usage = record.Usage
+			}
+		case recordRollback:
+			transcript.Entries = transcript.Entries[:max(len(transcript.Entries)-record.Removed, 0)]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取会话文件失败", err)
+	}
+	return transcript, nil
+}
+
+// Delete 删除会话
+func (st *SessionStore) Delete(id string) error {
+	if err := os.Remove(st.path(id)); err != nil {
+		if os.IsNotExist(err) {
+			return errors.NewErrorWithDetails(errors.ErrCodeNotFound, "会话不存在", "会话ID: "+id)
+		}
+		return errors.WrapError(errors.ErrCodeInternalErr, "删除会话文件失败", err)
+	}
+	return nil
+}
+
+// path 会话文件路径
+func (st *SessionStore) path(id string) string {
+	return filepath.Join(st.dir, id+sessionFileExt)
+}
+
+// Summary 生成会话摘要
+func (t *Transcript) Summary() SessionSummary {
+	summary := SessionSummary{SessionMeta: t.Meta, UpdatedAt: t.Meta.CreatedAt}
+	for _, entry := range t.Entries {
+		if entry.Time.After(summary.UpdatedAt) {
+			summary.UpdatedAt = entry.Time
+		}
+		if entry.Message.Role == "user" && !entry.Internal {
+			summary.Turns++
+			if summary.Title == "" {
+				// 未设置标题时使用第一条用户消息
+				summary.Title = entry.Message.Content
+			}
+		}
+	}
+	return summary
+}
+
+// History 将会话记录整合为发送给模型的历史消息。
+// 与 consolidateHistory 一致，每轮对话只保留用户问题和最终回答（本轮最后一条不含工具调用的回答），
+// 未得到回答的问题不计入。
+func (t *Transcript) History() []llm.Message {
+	var history []llm.Message
+	var question, answer *llm.Message
+	flush := func() {
+		if question != nil && answer != nil {
+			history = append(history, *question, *answer)
+		}
+		question, answer = nil, nil
+	}
+	for _, entry := range t.Entries {
+		msg := entry.Message
+		switch {
+		case entry.Internal:
+			// 智能体的控制提示不计入历史，最终回答与任务目标配对
+		case msg.Role == "user":
+			flush()
+			question = &msg
+		case msg.Role == "assistant" && len(msg.ToolCalls) == 0 && question != nil:
+			answer = &msg
+		}
+	}
+	flush()
+	return history
+}
+
+// TotalUsage 统计整个会话的令牌用量
+func (t *Transcript) TotalUsage() llm.TokenUsage {
+	var total llm.TokenUsage
+	for _, entry := range t.Entries {
+		if entry.Usage != nil {
+			total.PromptTokens += entry.Usage.Usage.PromptTokens
+			total.CompletionTokens += entry.Usage.Usage.CompletionTokens
+			total.TotalTokens += entry.Usage.Usage.TotalTokens
+		}
+	}
+	return total
+}
+
+// sessionRecorder 将会话消息追加写入文件，首次写入时才创建文件
+type sessionRecorder struct {
+	mu   sync.Mutex
+	path string
+	meta SessionMeta
+	file *os.File
+	// 恢复的会话文件已存在，无需再写入元信息
+	resumed bool
+}
+
+// newRecorder 创建会话记录器
+func (st *SessionStore) newRecorder(meta SessionMeta, resumed bool) *sessionRecorder {
+	return &sessionRecorder{path: st.path(meta.ID), meta: meta, resumed: resumed}
+}
+
+// record 追加一条消息，持久化失败不影响对话
+func (r *sessionRecorder) record(entry TranscriptEntry) {
+	r.append(storeRecord{Type: recordMessage, Time: entry.Time, Message: &entry.Message, Internal: entry.Internal})
+}
+
+// recordUsage 追加一轮对话的用量记录
+func (r *sessionRecorder) recordUsage(usage TurnUsage) {
+	r.append(storeRecord{Type: recordUsage, Time: time.Now(), Usage: &usage})
+}
+
+// recordRollback 追加回滚记录，加载时撤销之前的 removed 条消息
+func (r *sessionRecorder) recordRollback(removed int) {
+	r.append(storeRecord{Type: recordRollback, Time: time.Now(), Removed: removed})
+}
+
+// append 追加一条记录，持久化失败不影响对话
+func (r *sessionRecorder) append(record storeRecord) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.open(); err != nil {
+		util.Warnw("会话持久化失败", map[string]any{"session": r.meta.ID, "error": err.Error()})
+		return
+	}
+	if err := r.write(record); err != nil {
+		util.Warnw("会话持久化失败", map[string]any{"session": r.meta.ID, "error": err.Error()})
+	}
+}
+
+// open 打开会话文件，新会话先写入元信息，调用方需持有锁
+func (r *sessionRecorder) open() error {
+	if r.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	if !r.resumed {
+		return r.write(storeRecord{Type: recordMeta, Time: r.meta.CreatedAt, Meta: &r.meta})
+	}
+	return nil
+}
+
+func (r *sessionRecorder) write(record storeRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = r.file.Write(append(line, '\n'))
+	return err
+}
+
+// updateMeta 修改会话元信息，文件已创建时追加一条新的元信息记录
+func (r *sessionRecorder) updateMeta(update func(meta *SessionMeta)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update(&r.meta)
+	if r.file == nil {
+		return
+	}
+	if err := r.write(storeRecord{Type: recordMeta, Time: time.Now(), Meta: &r.meta}); err != nil {
+		util.Warnw("会话持久化失败", map[string]any{"session": r.meta.ID, "error": err.Error()})
+	}
+}
+
+// save 立即创建会话文件并写入元信息，title 非空时更新会话标题
+func (r *sessionRecorder) save(title string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if title != "" {
+		r.meta.Title = title
+	}
+	opened := r.file != nil
+	if err := r.open(); err != nil {
+		return err
+	}
+	// 新会话首次打开时已写入元信息
+	if opened || r.resumed {
+		return r.write(storeRecord{Type: recordMeta, Time: time.Now(), Meta: &r.meta})
+	}
+	return nil
+}
+
+// close 关闭会话文件
+func (r *sessionRecorder) close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// newSessionID 生成按时间排序的会话ID，如 20250101-120000-a1b2
+func newSessionID() string {
+	buf := make([]byte, 2)
+	_, _ = rand.Read(buf)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(buf)
+}
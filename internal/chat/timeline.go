@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-ops/internal/util"
+)
+
+// toolTimelineEntry 时间线中的一次工具调用，随事件更新状态
+type toolTimelineEntry struct {
+	callID     string
+	toolName   string
+	arguments  map[string]any
+	status     EventType
+	result     string
+	resultSize int
+	err        string
+	duration   time.Duration
+}
+
+// handleToolEvent 将工具调用事件合并到时间线，开始事件新增条目，结束事件更新对应条目
+func (m *BubbleTeaModel) handleToolEvent(event Event) {
+	tool := event.Tool
+	entry := m.runningTools[tool.CallID]
+	if entry == nil || event.Type == EventToolStarted {
+		// 未获批准的调用没有开始事件，直接以结束状态加入时间线
+		entry = newToolTimelineEntry(tool)
+		m.messages = append(m.messages, Message{Tool: entry, IsSystem: true, Timestamp: event.Time})
+	}
+
+	entry.update(event)
+	if event.Type == EventToolStarted {
+		m.runningTools[tool.CallID] = entry
+	} else {
+		delete(m.runningTools, tool.CallID)
+	}
+	m.updateViewport()
+}
+
+// newToolTimelineEntry 根据工具调用事件创建时间线条目
+func newToolTimelineEntry(tool *ToolEvent) *toolTimelineEntry {
+	return &toolTimelineEntry{callID: tool.CallID, toolName: tool.ToolName, arguments: tool.Arguments}
+}
+
+// update 按事件更新条目状态
+func (e *toolTimelineEntry) update(event Event) {
+	e.status = event.Type
+	e.duration = event.Tool.Duration
+	e.result = event.Tool.Result
+	e.resultSize = event.Tool.ResultSize
+	e.err = event.Tool.Error
+}
+
+// summary 返回单行的状态摘要
+func (e *toolTimelineEntry) summary() string {
+	var summary string
+	switch e.status {
+	case EventToolStarted:
+		summary = fmt.Sprintf("⏳ %s 执行中...", e.toolName)
+	case EventToolFinished:
+		summary = fmt.Sprintf("✅ %s 完成", e.toolName)
+		if e.duration > 0 {
+			summary += " · " + formatToolDuration(e.duration)
+		}
+		summary += " · " + formatSize(e.resultSize)
+	default:
+		summary = fmt.Sprintf("❌ %s 失败", e.toolName)
+		if e.duration > 0 {
+			summary += " · " + formatToolDuration(e.duration)
+		}
+		summary += ": " + summarizeLine(e.err, 80)
+	}
+	return summary
+}
+
+// toggleToolDetails 展开或折叠所有工具调用的参数与结果
+func (m *BubbleTeaModel) toggleToolDetails() {
+	m.expandTools = !m.expandTools
+	m.updateViewport()
+}
+
+// renderToolEntry 渲染时间线条目，折叠时只显示状态摘要
+func (m *BubbleTeaModel) renderToolEntry(msg Message) string {
+	entry := msg.Tool
+	marker := "▸"
+	if m.expandTools {
+		marker = "▾"
+	}
+
+	text := fmt.Sprintf("[%s] %s %s", msg.Timestamp.Format("15:04:05"), marker, entry.summary())
+	if !m.expandTools {
+		return m.systemStyle.Render(text)
+	}
+
+	var details strings.Builder
+	details.WriteString("参数:\n" + formatArguments(entry.arguments))
+	switch {
+	case entry.err != "":
+		details.WriteString("\n错误:\n" + entry.err)
+	case entry.status == EventToolFinished:
+		details.WriteString("\n结果预览:\n" + entry.result)
+	}
+	width := m.width - 8
+	if width <= 0 {
+		width = 80
+	}
+	return m.systemStyle.Render(text) + "\n" + m.toolDetailStyle.Width(width).Render(details.String())
+}
+
+// restoreToolEntries 根据会话记录中的工具调用与结果重建时间线条目，恢复的条目没有耗时
+func restoreToolEntries(transcript *Transcript) map[string]*toolTimelineEntry {
+	entries := make(map[string]*toolTimelineEntry)
+	for _, item := range transcript.Entries {
+		msg := item.Message
+		switch msg.Role {
+		case "assistant":
+			for _, tc := range msg.ToolCalls {
+				entries[tc.ID] = &toolTimelineEntry{
+					callID:    tc.ID,
+					toolName:  tc.Name,
+					arguments: tc.Arguments,
+					status:    EventToolFinished,
+				}
+			}
+		case "tool":
+			entry := entries[msg.ToolCallID]
+			if entry == nil {
+				continue
+			}
+			errPrefix := fmt.Sprintf("Error executing tool %s: ", entry.toolName)
+			if strings.HasPrefix(msg.Content, errPrefix) {
+				entry.status = EventToolFailed
+				entry.err = strings.TrimPrefix(msg.Content, errPrefix)
+				continue
+			}
+			entry.result = toolResultPreview(msg.Content)
+			entry.resultSize = len(unquoteToolResult(msg.Content))
+		}
+	}
+	return entries
+}
+
+// formatToolDuration 格式化工具执行耗时
+func formatToolDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// formatSize 格式化结果大小
+func formatSize(size int) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+	}
+}
+
+// summarizeLine 取文本的第一行并截断到指定字符数
+func summarizeLine(text string, maxRunes int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if short, truncated := util.TruncateRunes(line, maxRunes); truncated {
+		return short + "..."
+	}
+	return line
+}
@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"sync"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+)
+
+// UsageStats 会话的令牌用量与费用统计
+type UsageStats struct {
+	Session       llm.TokenUsage // 整个会话累计的用量，包含恢复的历史轮次
+	LastTurn      llm.TokenUsage // 最近一轮（或正在进行的一轮）对话的用量
+	Cost          float64        // 按模型价格估算的累计费用
+	Priced        bool           // 当前模型是否配置了价格
+	ContextTokens int            // 最近一次模型请求占用的上下文令牌数
+	ContextWindow int            // 当前模型的上下文窗口大小
+}
+
+// ContextPercent 返回上下文窗口的占用百分比，窗口大小未知时返回 -1
+func (u UsageStats) ContextPercent() int {
+	if u.ContextWindow <= 0 {
+		return -1
+	}
+	return u.ContextTokens * 100 / u.ContextWindow
+}
+
+// usageTracker 累计模型请求的用量，对话在后台执行时界面可以并发读取
+type usageTracker struct {
+	mu            sync.Mutex
+	total         llm.TokenUsage
+	turn          llm.TokenUsage
+	cost          float64
+	contextTokens int
+}
+
+// startTurn 开始新一轮对话的统计
+func (t *usageTracker) startTurn() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.turn = llm.TokenUsage{}
+}
+
+// add 累计一次模型请求的用量与费用
+func (t *usageTracker) add(usage llm.TokenUsage, cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	addTokenUsage(&t.total, usage)
+	addTokenUsage(&t.turn, usage)
+	t.cost += cost
+	// 部分提供商不返回用量，此时保留上一次的上下文占用
+	if usage.TotalTokens > 0 {
+		t.contextTokens = usage.TotalTokens
+	}
+}
+
+// resetContext 清空对话历史后上下文不再占用令牌
+func (t *usageTracker) resetContext() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.contextTokens = 0
+}
+
+// addTokenUsage 将 usage 累加到 total
+func addTokenUsage(total *llm.TokenUsage, usage llm.TokenUsage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
+
+// addUsage 累计一次模型请求的用量，费用按当前模型适配器配置的价格估算
+func (s *Session) addUsage(usage llm.TokenUsage) {
+	s.usage.add(usage, estimateCost(s.config.ModelName, usage))
+}
+
+// SessionStatus 会话使用的模型与用量。对话在后台执行时界面不能直接读取会话，
+// 通过事件中的快照展示
+type SessionStatus struct {
+	ModelName string // 模型适配器名称
+	Model     string // 模型名称
+	Usage     UsageStats
+}
+
+// Status 返回会话状态，只能在执行对话的 goroutine 中或没有进行中的对话时调用
+func (s *Session) Status() SessionStatus {
+	return SessionStatus{
+		ModelName: s.config.ModelName,
+		Model:     s.client.GetModelInfo().Name,
+		Usage:     s.Usage(),
+	}
+}
+
+// Usage 返回会话的用量统计，调用限制与 Status 相同
+func (s *Session) Usage() UsageStats {
+	s.usage.mu.Lock()
+	stats := UsageStats{
+		Session:       s.usage.total,
+		LastTurn:      s.usage.turn,
+		Cost:          s.usage.cost,
+		ContextTokens: s.usage.contextTokens,
+	}
+	s.usage.mu.Unlock()
+
+	var modelConfig config.ModelConfig
+	if s.config.ModelName != "" {
+		modelConfig, _ = config.GetModelConfig(s.config.ModelName)
+	}
+	stats.Priced = modelConfig.InputPrice > 0 || modelConfig.OutputPrice > 0
+	stats.ContextWindow = modelConfig.ContextWindow
+	if stats.ContextWindow <= 0 {
+		stats.ContextWindow = s.client.GetModelInfo().MaxTokens
+	}
+	return stats
+}
+
+// estimateCost 按模型适配器配置的每百万令牌价格估算费用，未配置价格时返回 0
+func estimateCost(modelName string, usage llm.TokenUsage) float64 {
+	if modelName == "" {
+		return 0
+	}
+	modelConfig, err := config.GetModelConfig(modelName)
+	if err != nil {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*modelConfig.InputPrice +
+		float64(usage.CompletionTokens)*modelConfig.OutputPrice) / 1e6
+}
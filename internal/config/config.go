@@ -4,23 +4,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync/atomic"
 
 	util "ai-ops/internal/util"
 
 	"github.com/BurntSushi/toml"
 )
 
-// 全局配置实例
-var Config *AppConfig
+// 全局配置实例，热重载时在后台协程中整体替换，通过 GetConfig 读取
+var current atomic.Pointer[AppConfig]
+
+// 当前加载的配置文件路径
+var configFilePath string
 
 // 应用配置结构
 type AppConfig struct {
-	AI      AIConfig      `toml:"ai"`
-	Logging LoggingConfig `toml:"logging"`
-	Weather WeatherConfig `toml:"weather"`
-	RAG     RAGConfig     `toml:"rag"`
-	Tools   ToolsConfig   `toml:"tools"`
+	AI         AIConfig         `toml:"ai"`
+	Logging    LoggingConfig    `toml:"logging"`
+	Weather    WeatherConfig    `toml:"weather"`
+	RAG        RAGConfig        `toml:"rag"`
+	Tools      ToolsConfig      `toml:"tools"`
+	Metrics    MetricsConfig    `toml:"metrics"`
+	Tracing    TracingConfig    `toml:"tracing"`
+	Redaction  RedactionConfig  `toml:"redaction"`
+	Sessions   SessionsConfig   `toml:"sessions"`
+	Agent      AgentConfig      `toml:"agent"`
+	Prompts    PromptsConfig    `toml:"prompts"`
+	References ReferencesConfig `toml:"references"`
+	UI         UIConfig         `toml:"ui"`
+	Tasks      TasksConfig      `toml:"tasks"`
+	Alerts     AlertsConfig     `toml:"alerts"`
+	Serve      ServeConfig      `toml:"serve"`
+	Notify     NotifyConfig     `toml:"notify"`
+	Bot        BotConfig        `toml:"bot"`
 }
 
 // AI配置
@@ -37,6 +55,10 @@ type ModelConfig struct {
 	BaseURL string `toml:"base_url"`
 	Model   string `toml:"model"`
 	Style   string `toml:"style" json:"style,omitempty"`
+
+	ContextWindow int     `toml:"context_window"` // 上下文窗口大小，0 表示按模型名称推断
+	InputPrice    float64 `toml:"input_price"`    // 每百万输入令牌的价格，用于估算费用
+	OutputPrice   float64 `toml:"output_price"`   // 每百万输出令牌的价格
 }
 
 // 日志配置
@@ -67,6 +89,186 @@ type ToolsConfig struct {
 	Sysinfo bool `toml:"sysinfo"` // 系统信息工具
 	Weather bool `toml:"weather"` // 天气工具
 	RAG     bool `toml:"rag"`     // RAG工具
+	Notify  bool `toml:"notify"`  // 通知工具，需要配置 [notify.channels]
+	// Approval 有风险（mutating/destructive）工具调用的确认方式：prompt 人工确认（默认）、auto 自动批准、deny 一律拒绝
+	Approval string `toml:"approval"`
+}
+
+// 指标配置
+type MetricsConfig struct {
+	Enable bool   `toml:"enable"` // 是否启用 Prometheus 指标监听器
+	Listen string `toml:"listen"` // 监听地址，如 127.0.0.1:9464
+	Path   string `toml:"path"`   // 指标路径，默认 /metrics
+}
+
+// 链路追踪配置
+type TracingConfig struct {
+	Enable      bool              `toml:"enable"`       // 是否启用链路追踪
+	Exporter    string            `toml:"exporter"`     // otlp 或 file
+	Endpoint    string            `toml:"endpoint"`     // OTLP/HTTP 收集器地址，如 http://localhost:4318
+	Headers     map[string]string `toml:"headers"`      // OTLP 请求附加头（如鉴权）
+	File        string            `toml:"file"`         // file 导出器的输出文件
+	ServiceName string            `toml:"service_name"` // 上报的服务名，默认 ai-ops
+}
+
+// 会话持久化配置
+type SessionsConfig struct {
+	Dir             string `toml:"dir"`               // 会话存储目录，默认 ~/.ai-ops/sessions
+	ExportFormat    string `toml:"export_format"`     // 默认导出格式：markdown、html、json
+	ExportMaxResult int    `toml:"export_max_result"` // 导出时工具结果最多保留的字符数，0 使用默认值，-1 表示不截断
+}
+
+// 智能体模式配置，各项为 0 时使用默认值
+type AgentConfig struct {
+	MaxSteps     int `toml:"max_steps"`      // 最多执行的步骤数（模型请求次数）
+	MaxToolCalls int `toml:"max_tool_calls"` // 最多执行的工具调用次数
+	MaxTokens    int `toml:"max_tokens"`     // 单个任务最多消耗的令牌数
+}
+
+// 提示词模板配置
+type PromptsConfig struct {
+	Dir     string            `toml:"dir"`     // 模板目录，默认 ~/.ai-ops/prompts
+	Profile string            `toml:"profile"` // 默认使用的模板，default 为内置模板
+	Vars    map[string]string `toml:"vars"`    // 模板中通过 {{.Vars.name}} 引用的自定义变量
+}
+
+// 对话输入中 @文件 与 !命令 引用的配置，各项为 0 时使用默认值
+type ReferencesConfig struct {
+	MaxFileSize    int `toml:"max_file_size"`   // 单个文件或命令输出最多附加的字节数
+	MaxTotalSize   int `toml:"max_total_size"`  // 一条消息附加内容的总字节数
+	MaxFiles       int `toml:"max_files"`       // 一个通配符最多匹配的文件数
+	CommandTimeout int `toml:"command_timeout"` // 命令执行超时（秒）
+}
+
+// 界面配置
+type UIConfig struct {
+	// StatusBar 对话界面状态栏显示的项目：model、tokens、cost、context、mcp、phase。
+	// 未配置时显示全部项目，配置为空列表时隐藏状态栏
+	StatusBar []string `toml:"status_bar"`
+	Currency  string   `toml:"currency"` // 费用的货币符号，默认 $
+}
+
+// 定时任务配置
+type TasksConfig struct {
+	Dir          string `toml:"dir"`           // 任务文件目录，默认 ~/.ai-ops/tasks
+	History      string `toml:"history"`       // 执行记录文件，默认 ~/.ai-ops/tasks/history.jsonl
+	HistoryLimit int    `toml:"history_limit"` // 保留的执行记录条数，0 使用默认值
+	Timeout      int    `toml:"timeout"`       // 任务未配置超时时的执行超时（秒），0 使用默认值
+}
+
+// 告警接收配置
+type AlertsConfig struct {
+	Listen        string `toml:"listen"`         // 监听地址，默认 127.0.0.1:9095
+	Token         string `toml:"token"`          // 请求需携带的 Bearer 令牌，留空不校验，支持 ${ENV}
+	Rules         string `toml:"rules"`          // 路由规则文件，默认 ~/.ai-ops/alerts.toml
+	Model         string `toml:"model"`          // 诊断使用的模型，留空使用 default_model
+	Timeout       int    `toml:"timeout"`        // 单次诊断的超时（秒），0 使用默认值
+	DedupWindow   int    `toml:"dedup_window"`   // 同一告警（指纹与状态）在该时间内（秒）只诊断一次，0 使用默认值
+	RateLimit     int    `toml:"rate_limit"`     // 同一告警在 rate_window 内最多诊断的次数，0 表示不限制
+	RateWindow    int    `toml:"rate_window"`    // 限流的时间窗口（秒），0 使用默认值
+	MaxConcurrent int    `toml:"max_concurrent"` // 同时进行的诊断数
+	QueueSize     int    `toml:"queue_size"`     // 等待中的诊断上限，超出的告警不诊断
+	Resolved      bool   `toml:"resolved"`       // 是否诊断已恢复的告警
+}
+
+// API 服务配置
+type ServeConfig struct {
+	Listen        string   `toml:"listen"`         // 监听地址，默认 127.0.0.1:8088
+	APIKeys       []string `toml:"api_keys"`       // 允许访问的 API 密钥，留空不校验，支持 ${ENV}
+	Model         string   `toml:"model"`          // 请求未指定 model 时使用的模型，留空使用 default_model
+	Mode          string   `toml:"mode"`           // 会话模式：chat（默认）或 agent
+	Profile       string   `toml:"profile"`        // 系统提示词模板，留空使用 [prompts] profile
+	Approval      string   `toml:"approval"`       // 有风险工具调用的确认方式：deny（默认）或 auto
+	Timeout       int      `toml:"timeout"`        // 单个请求的超时（秒），0 使用默认值
+	MaxConcurrent int      `toml:"max_concurrent"` // 同时处理的请求数，超出时返回 429
+	MaxBodyBytes  int64    `toml:"max_body_bytes"` // 请求体上限（字节），0 使用默认值
+	MaxMessages   int      `toml:"max_messages"`   // 单个请求的消息数上限，0 使用默认值
+}
+
+// 通知配置
+type NotifyConfig struct {
+	Timeout    int                      `toml:"timeout"`     // 单次发送的超时（秒），0 使用默认值
+	Retries    int                      `toml:"retries"`     // 发送失败后的重试次数
+	RetryDelay int                      `toml:"retry_delay"` // 首次重试前的等待时间（秒），之后每次加倍，0 使用默认值
+	Channels   map[string]NotifyChannel `toml:"channels"`    // 通知渠道，键为渠道名称
+}
+
+// 通知渠道配置，字段值中的 ${VAR} 替换为环境变量
+type NotifyChannel struct {
+	Type string `toml:"type"` // slack、dingtalk、feishu、webhook 或 email
+	// Template 消息模板（text/template），可使用 .Title、.Content、.Source、.Time，留空直接发送正文
+	Template string            `toml:"template"`
+	URL      string            `toml:"url"`     // webhook 地址（slack、dingtalk、feishu、webhook）
+	Secret   string            `toml:"secret"`  // 钉钉、飞书机器人的签名密钥，留空不签名
+	Headers  map[string]string `toml:"headers"` // webhook 请求附加头
+
+	// 邮件（SMTP）
+	Host     string   `toml:"host"`
+	Port     int      `toml:"port"` // 默认 587（STARTTLS），tls = true 时默认 465
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+	TLS      bool     `toml:"tls"` // 直接使用 TLS 连接，否则服务器支持时使用 STARTTLS
+}
+
+// IM 机器人配置
+type BotConfig struct {
+	Listen  string `toml:"listen"`  // 接收平台事件回调的监听地址
+	Model   string `toml:"model"`   // 使用的模型，留空使用 default_model
+	Mode    string `toml:"mode"`    // 会话模式：chat（默认）或 agent
+	Profile string `toml:"profile"` // 系统提示词模板，留空使用 [prompts] profile
+	// Approval 有风险工具调用的确认方式：prompt 在会话中发送确认按钮（默认）、auto 自动批准、deny 一律拒绝
+	Approval        string `toml:"approval"`
+	Timeout         int    `toml:"timeout"`          // 单条消息的处理超时（秒），包含等待确认的时间
+	ApprovalTimeout int    `toml:"approval_timeout"` // 等待确认的超时（秒），超时按拒绝处理
+	IdleTimeout     int    `toml:"idle_timeout"`     // 会话空闲多久后从内存中释放（秒），之后的消息从会话记录恢复
+	MaxConcurrent   int    `toml:"max_concurrent"`   // 同时处理的消息数
+	State           string `toml:"state"`            // 平台会话与 ai-ops 会话的对应关系文件
+
+	Slack    BotSlackConfig    `toml:"slack"`
+	Feishu   BotFeishuConfig   `toml:"feishu"`
+	DingTalk BotDingTalkConfig `toml:"dingtalk"`
+}
+
+// Slack 机器人配置（Events API 与 Interactivity）
+type BotSlackConfig struct {
+	Enable        bool     `toml:"enable"`
+	SigningSecret string   `toml:"signing_secret"` // 校验回调签名的 Signing Secret
+	BotToken      string   `toml:"bot_token"`      // 发送消息使用的 Bot User OAuth Token
+	AllowedUsers  []string `toml:"allowed_users"`  // 允许使用的用户 ID，如 U012ABCDEF
+	APIBase       string   `toml:"api_base"`       // Web API 地址，默认 https://slack.com/api
+}
+
+// 飞书机器人配置（事件订阅 v2 与卡片回调）
+type BotFeishuConfig struct {
+	Enable            bool     `toml:"enable"`
+	AppID             string   `toml:"app_id"`
+	AppSecret         string   `toml:"app_secret"`
+	VerificationToken string   `toml:"verification_token"` // 事件订阅的 Verification Token
+	EncryptKey        string   `toml:"encrypt_key"`        // 事件订阅的 Encrypt Key，配置后校验签名并解密事件
+	AllowedUsers      []string `toml:"allowed_users"`      // 允许使用的用户 open_id、user_id 或 union_id
+	APIBase           string   `toml:"api_base"`           // 开放平台地址，默认 https://open.feishu.cn，Lark 使用 https://open.larksuite.com
+}
+
+// 钉钉机器人配置（企业内部应用机器人的 HTTP 消息接收）
+type BotDingTalkConfig struct {
+	Enable       bool     `toml:"enable"`
+	AppSecret    string   `toml:"app_secret"`    // 应用的 AppSecret，用于校验回调签名
+	AllowedUsers []string `toml:"allowed_users"` // 允许使用的用户 staffId 或 senderId
+}
+
+// 敏感信息脱敏配置
+type RedactionConfig struct {
+	Enable    bool               `toml:"enable"`    // 是否在发送给模型前脱敏用户输入和工具结果
+	Detectors []string           `toml:"detectors"` // 启用的内置检测器，为空时启用全部
+	Patterns  []RedactionPattern `toml:"patterns"`  // 自定义脱敏规则
+}
+
+// 自定义脱敏规则
+type RedactionPattern struct {
+	Name  string `toml:"name"`  // 规则名称，用于生成占位符
+	Regex string `toml:"regex"` // 正则表达式，可用 (?P<secret>...) 只替换其中的敏感片段
 }
 
 // 加载配置文件
@@ -85,10 +287,23 @@ func LoadConfig(configPath string) error {
 		util.Infow("已创建默认配置文件", map[string]interface{}{"path": configPath})
 	}
 
+	config, err := parseConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	// 设置全局配置
+	current.Store(config)
+	configFilePath = configPath
+	return nil
+}
+
+// 解析、覆盖并验证配置文件，不修改全局配置
+func parseConfigFile(configPath string) (*AppConfig, error) {
 	// 解析TOML配置文件
 	var config AppConfig
 	if _, err := toml.DecodeFile(configPath, &config); err != nil {
-		return fmt.Errorf("解析配置文件失败: %w", err)
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
 
 	// 使用环境变量覆盖配置
@@ -96,12 +311,10 @@ func LoadConfig(configPath string) error {
 
 	// 验证配置
 	if err := validateConfig(&config); err != nil {
-		return fmt.Errorf("配置验证失败: %w", err)
+		return nil, fmt.Errorf("配置验证失败: %w", err)
 	}
 
-	// 设置全局配置
-	Config = &config
-	return nil
+	return &config, nil
 }
 
 // 获取默认配置文件路径
@@ -146,6 +359,9 @@ type = "openai"
 api_key = "${OPENAI_API_KEY}"
 base_url = "https://api.openai.com/v1/chat/completions"
 model = "gpt-4o-mini"
+# context_window = 128000  # 上下文窗口，未配置时按模型名称推断
+# input_price = 0.15        # 每百万输入令牌的价格，配置后状态栏显示估算费用
+# output_price = 0.60       # 每百万输出令牌的价格
 
 [ai.models.glm]
 type = "openai"
@@ -175,6 +391,148 @@ echo = true      # Echo工具（调试用，核心工具）
 sysinfo = false  # 系统信息工具
 weather = false  # 天气工具（需要配置 QWEATHER_API_KEY）
 rag = false      # RAG工具（需要启动RAG服务）
+notify = false   # 通知工具（需要配置 [notify.channels]，发送前需要确认）
+approval = "prompt"  # 有风险的工具调用：prompt 人工确认、auto 自动批准、deny 一律拒绝
+
+[metrics]
+enable = false              # 是否启用 Prometheus 指标监听器
+listen = "127.0.0.1:9464"   # 监听地址
+path = "/metrics"
+
+[tracing]
+enable = false                        # 是否启用链路追踪
+exporter = "otlp"                     # otlp（OTLP/HTTP）或 file（本地 JSON Lines 文件）
+endpoint = "http://localhost:4318"    # OTLP/HTTP 收集器地址
+file = "logs/traces.jsonl"            # file 导出器输出文件
+service_name = "ai-ops"
+# 模型请求（traceparent 请求头）与 MCP 工具调用（_meta.traceparent）携带 W3C 追踪上下文；
+# 内置实现不依赖 OpenTelemetry SDK，不传播 tracestate 与 baggage，也不延续入站请求中的追踪
+
+[redaction]
+enable = true   # 发送给模型前脱敏用户输入和工具结果
+detectors = ["aws_key", "bearer_token", "private_key", "password", "email"]
+# [[redaction.patterns]]
+# name = "internal_ip"
+# regex = '\b(?:10|192\.168|172\.(?:1[6-9]|2[0-9]|3[01]))(?:\.\d{1,3}){2,3}\b'
+
+[sessions]
+dir = ""   # 会话存储目录，留空使用 ~/.ai-ops/sessions
+export_format = "markdown"   # 默认导出格式：markdown、html、json
+export_max_result = 2000     # 导出时工具结果最多保留的字符数，-1 表示不截断
+
+[agent]
+# 智能体模式（-a）的执行预算，0 表示使用默认值；max_steps 同时限制对话模式单轮的工具调用轮数
+max_steps = 10        # 最多执行的步骤数
+max_tool_calls = 20   # 最多执行的工具调用次数
+max_tokens = 200000   # 单个任务最多消耗的令牌数
+
+[prompts]
+dir = ""             # 提示词模板目录，留空使用 ~/.ai-ops/prompts
+profile = "default"  # 默认模板，default 为内置模板，可通过 --profile 覆盖
+
+# 模板中通过 {{.Vars.name}} 引用的自定义变量
+# [prompts.vars]
+# team = "SRE"
+
+[references]
+# 对话输入中 @文件 与 !命令 引用的限制，0 表示使用默认值
+max_file_size = 65536     # 单个文件或命令输出最多附加的字节数
+max_total_size = 262144   # 一条消息附加内容的总字节数
+max_files = 20            # 一个通配符最多匹配的文件数
+command_timeout = 30      # 命令执行超时（秒）
+
+[ui]
+# 对话界面状态栏显示的项目，设为 [] 隐藏状态栏
+status_bar = ["model", "tokens", "cost", "context", "mcp", "phase"]
+currency = "$"   # 费用的货币符号
+
+[tasks]
+dir = ""              # 任务文件目录，留空使用 ~/.ai-ops/tasks
+history = ""          # 执行记录文件，留空使用 ~/.ai-ops/tasks/history.jsonl
+history_limit = 1000  # 保留的执行记录条数
+timeout = 600         # 任务未配置 timeout 时的执行超时（秒）
+
+[alerts]
+listen = "127.0.0.1:9095"  # ai-ops alert-receiver 的监听地址
+token = ""                 # 请求需携带的 Bearer 令牌，留空不校验，支持 ${ENV}
+rules = ""                 # 路由规则文件，留空使用 ~/.ai-ops/alerts.toml
+model = ""                 # 诊断使用的模型，留空使用 default_model
+timeout = 300              # 单次诊断的超时（秒）
+dedup_window = 1800        # 同一告警在该时间内（秒）只诊断一次
+rate_limit = 3             # 同一告警在 rate_window 内最多诊断的次数，0 表示不限制
+rate_window = 3600         # 限流的时间窗口（秒）
+max_concurrent = 2         # 同时进行的诊断数
+queue_size = 100           # 等待中的诊断上限
+resolved = false           # 是否诊断已恢复的告警
+
+[serve]
+listen = "127.0.0.1:8088"  # ai-ops serve 的监听地址
+api_keys = []              # 允许访问的 API 密钥，留空不校验，支持 ${ENV}
+model = ""                 # 请求未指定 model 时使用的模型，留空使用 default_model
+mode = "chat"              # 会话模式：chat 或 agent
+profile = ""               # 系统提示词模板，留空使用 [prompts] profile
+approval = "deny"          # 有风险工具调用的确认方式：deny 或 auto
+timeout = 300              # 单个请求的超时（秒），包含全部工具调用
+max_concurrent = 4         # 同时处理的请求数，超出时返回 429
+max_body_bytes = 1048576   # 请求体上限（字节）
+max_messages = 100         # 单个请求的消息数上限
+
+[notify]
+timeout = 15               # 单次发送的超时（秒）
+retries = 2                # 发送失败后的重试次数
+retry_delay = 2            # 首次重试前的等待时间（秒），之后每次加倍
+
+# 通知渠道，供 notify 工具、ai-ops chat --notify 与任务的 notify 输出使用
+# [notify.channels.ops]
+# type = "slack"           # slack、dingtalk、feishu、webhook 或 email
+# url = "${SLACK_WEBHOOK_URL}"
+#
+# [notify.channels.dingtalk]
+# type = "dingtalk"
+# url = "https://oapi.dingtalk.com/robot/send?access_token=${DINGTALK_TOKEN}"
+# secret = "${DINGTALK_SECRET}"  # 加签密钥，留空不签名
+# template = "{{ .Content }}\n\n> 来源: {{ .Source }}"
+#
+# [notify.channels.mail]
+# type = "email"
+# host = "smtp.example.com"
+# port = 587
+# username = "ops@example.com"
+# password = "${SMTP_PASSWORD}"
+# from = "ops@example.com"
+# to = ["oncall@example.com"]
+
+[bot]
+listen = "127.0.0.1:8089"
+model = ""                 # 使用的模型，留空使用 default_model
+mode = "chat"              # 会话模式：chat 或 agent
+profile = ""               # 系统提示词模板，留空使用 [prompts] profile
+approval = "prompt"        # 有风险工具调用的确认方式：prompt（发送确认按钮）、auto 或 deny
+timeout = 600              # 单条消息的处理超时（秒），包含等待确认的时间
+approval_timeout = 300     # 等待确认的超时（秒），超时按拒绝处理
+idle_timeout = 3600        # 会话空闲多久后从内存中释放（秒），之后的消息从会话记录恢复
+max_concurrent = 4         # 同时处理的消息数
+state = ""                 # 平台会话与 ai-ops 会话的对应关系，留空使用 ~/.ai-ops/bot/conversations.json
+
+[bot.slack]
+enable = false
+signing_secret = "${SLACK_SIGNING_SECRET}"
+bot_token = "${SLACK_BOT_TOKEN}"
+allowed_users = []         # 允许使用的用户 ID，只有白名单中的用户可以对话和确认工具调用
+
+[bot.feishu]
+enable = false
+app_id = "${FEISHU_APP_ID}"
+app_secret = "${FEISHU_APP_SECRET}"
+verification_token = "${FEISHU_VERIFICATION_TOKEN}"
+encrypt_key = ""           # 配置后校验请求签名与时间戳并解密事件，建议配置
+allowed_users = []         # 用户 open_id、user_id 或 union_id
+
+[bot.dingtalk]
+enable = false
+app_secret = "${DINGTALK_APP_SECRET}"
+allowed_users = []         # 用户 staffId 或 senderId
+# 钉钉的签名不覆盖请求体，发送者 ID 无法认证，确认有风险的工具调用建议使用 Slack 或飞书
 `
 
 	return os.WriteFile(configPath, []byte(defaultConfig), 0644)
@@ -254,6 +612,77 @@ func validateConfig(config *AppConfig) error {
 		}
 	}
 
+	// 验证工具配置
+	if err := validateToolsConfig(&config.Tools); err != nil {
+		return fmt.Errorf("工具配置验证失败: %w", err)
+	}
+
+	// 验证会话配置
+	if err := validateSessionsConfig(&config.Sessions); err != nil {
+		return fmt.Errorf("会话配置验证失败: %w", err)
+	}
+
+	// 验证智能体配置
+	if err := validateAgentConfig(&config.Agent); err != nil {
+		return fmt.Errorf("智能体配置验证失败: %w", err)
+	}
+
+	// 验证引用配置
+	if err := validateReferencesConfig(&config.References); err != nil {
+		return fmt.Errorf("引用配置验证失败: %w", err)
+	}
+
+	// 验证界面配置
+	if err := validateUIConfig(&config.UI); err != nil {
+		return fmt.Errorf("界面配置验证失败: %w", err)
+	}
+
+	// 验证定时任务配置
+	if err := validateTasksConfig(&config.Tasks); err != nil {
+		return fmt.Errorf("定时任务配置验证失败: %w", err)
+	}
+
+	// 验证告警接收配置
+	if err := validateAlertsConfig(&config.Alerts); err != nil {
+		return fmt.Errorf("告警接收配置验证失败: %w", err)
+	}
+
+	// 验证 API 服务配置
+	if err := validateServeConfig(&config.Serve); err != nil {
+		return fmt.Errorf("API 服务配置验证失败: %w", err)
+	}
+
+	// 验证通知配置
+	if err := validateNotifyConfig(&config.Notify); err != nil {
+		return fmt.Errorf("通知配置验证失败: %w", err)
+	}
+
+	// 验证 IM 机器人配置
+	if err := validateBotConfig(&config.Bot); err != nil {
+		return fmt.Errorf("IM 机器人配置验证失败: %w", err)
+	}
+
+	// 验证指标配置（如果启用）
+	if config.Metrics.Enable {
+		if err := validateMetricsConfig(&config.Metrics); err != nil {
+			return fmt.Errorf("指标配置验证失败: %w", err)
+		}
+	}
+
+	// 验证脱敏配置（如果启用）
+	if config.Redaction.Enable {
+		if err := validateRedactionConfig(&config.Redaction); err != nil {
+			return fmt.Errorf("脱敏配置验证失败: %w", err)
+		}
+	}
+
+	// 验证链路追踪配置（如果启用）
+	if config.Tracing.Enable {
+		if err := validateTracingConfig(&config.Tracing); err != nil {
+			return fmt.Errorf("链路追踪配置验证失败: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -315,6 +744,11 @@ func validateModelConfig(name string, model *ModelConfig) error {
 		return fmt.Errorf("模型配置'%s'的模型名称不能为空", name)
 	}
 
+	// 验证上下文窗口与价格
+	if model.ContextWindow < 0 || model.InputPrice < 0 || model.OutputPrice < 0 {
+		return fmt.Errorf("模型配置'%s'的上下文窗口与价格不能为负数", name)
+	}
+
 	return nil
 }
 
@@ -411,22 +845,244 @@ func validateRAGConfig(rag *RAGConfig) error {
 	return nil
 }
 
-// 获取当前配置
+// 验证指标配置
+func validateMetricsConfig(metrics *MetricsConfig) error {
+	if metrics.Listen == "" {
+		return fmt.Errorf("指标监听地址未配置")
+	}
+
+	if metrics.Path != "" && !strings.HasPrefix(metrics.Path, "/") {
+		return fmt.Errorf("指标路径格式不正确，必须以/开头: %s", metrics.Path)
+	}
+
+	return nil
+}
+
+// 验证工具配置
+func validateToolsConfig(tools *ToolsConfig) error {
+	switch tools.Approval {
+	case "", "prompt", "auto", "deny":
+	default:
+		return fmt.Errorf("不支持的工具确认方式: %s（可选 prompt、auto、deny）", tools.Approval)
+	}
+
+	return nil
+}
+
+// 验证会话配置
+func validateSessionsConfig(sessions *SessionsConfig) error {
+	switch sessions.ExportFormat {
+	case "", "markdown", "md", "html", "htm", "json":
+	default:
+		return fmt.Errorf("不支持的导出格式: %s（可选 markdown、html、json）", sessions.ExportFormat)
+	}
+	if sessions.ExportMaxResult < -1 {
+		return fmt.Errorf("导出工具结果长度无效: %d（-1 表示不截断）", sessions.ExportMaxResult)
+	}
+
+	return nil
+}
+
+// 验证智能体配置
+func validateAgentConfig(agent *AgentConfig) error {
+	if agent.MaxSteps < 0 || agent.MaxToolCalls < 0 || agent.MaxTokens < 0 {
+		return fmt.Errorf("智能体预算不能为负数")
+	}
+
+	return nil
+}
+
+// 验证引用配置
+func validateReferencesConfig(references *ReferencesConfig) error {
+	if references.MaxFileSize < 0 || references.MaxTotalSize < 0 || references.MaxFiles < 0 || references.CommandTimeout < 0 {
+		return fmt.Errorf("引用限制不能为负数")
+	}
+
+	return nil
+}
+
+// 状态栏可以显示的项目
+var statusBarItems = []string{"model", "tokens", "cost", "context", "mcp", "phase"}
+
+// 验证界面配置
+func validateUIConfig(ui *UIConfig) error {
+	for _, item := range ui.StatusBar {
+		valid := false
+		for _, name := range statusBarItems {
+			if item == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("不支持的状态栏项目: %s（可选 %s）", item, strings.Join(statusBarItems, "、"))
+		}
+	}
+
+	return nil
+}
+
+// 验证定时任务配置
+func validateTasksConfig(tasks *TasksConfig) error {
+	if tasks.HistoryLimit < 0 || tasks.Timeout < 0 {
+		return fmt.Errorf("执行记录条数与超时时间不能为负数")
+	}
+
+	return nil
+}
+
+// 验证告警接收配置
+func validateAlertsConfig(alerts *AlertsConfig) error {
+	if alerts.Timeout < 0 || alerts.DedupWindow < 0 || alerts.RateLimit < 0 || alerts.RateWindow < 0 ||
+		alerts.MaxConcurrent < 0 || alerts.QueueSize < 0 {
+		return fmt.Errorf("超时、去重窗口、限流与并发配置不能为负数")
+	}
+	return nil
+}
+
+// 验证 API 服务配置
+func validateServeConfig(serve *ServeConfig) error {
+	switch serve.Mode {
+	case "", "chat", "agent":
+	default:
+		return fmt.Errorf("不支持的会话模式: %s（可选 chat、agent）", serve.Mode)
+	}
+	// 服务端无法人工确认工具调用
+	switch serve.Approval {
+	case "", "deny", "auto":
+	default:
+		return fmt.Errorf("不支持的工具确认方式: %s（可选 deny、auto）", serve.Approval)
+	}
+	if serve.Timeout < 0 || serve.MaxConcurrent < 0 || serve.MaxBodyBytes < 0 || serve.MaxMessages < 0 {
+		return fmt.Errorf("超时、并发与请求限制配置不能为负数")
+	}
+	return nil
+}
+
+// 验证 IM 机器人配置，启用的平台必须配置签名密钥与用户白名单
+func validateBotConfig(bot *BotConfig) error {
+	switch bot.Mode {
+	case "", "chat", "agent":
+	default:
+		return fmt.Errorf("不支持的会话模式: %s（可选 chat、agent）", bot.Mode)
+	}
+	switch bot.Approval {
+	case "", "prompt", "auto", "deny":
+	default:
+		return fmt.Errorf("不支持的工具确认方式: %s（可选 prompt、auto、deny）", bot.Approval)
+	}
+	if bot.Timeout < 0 || bot.ApprovalTimeout < 0 || bot.IdleTimeout < 0 || bot.MaxConcurrent < 0 {
+		return fmt.Errorf("超时与并发配置不能为负数")
+	}
+	if bot.Slack.Enable {
+		if bot.Slack.SigningSecret == "" || bot.Slack.BotToken == "" {
+			return fmt.Errorf("slack 需要配置 signing_secret 与 bot_token")
+		}
+		if len(bot.Slack.AllowedUsers) == 0 {
+			return fmt.Errorf("slack 未配置 allowed_users")
+		}
+	}
+	if bot.Feishu.Enable {
+		if bot.Feishu.AppID == "" || bot.Feishu.AppSecret == "" {
+			return fmt.Errorf("feishu 需要配置 app_id 与 app_secret")
+		}
+		if bot.Feishu.VerificationToken == "" && bot.Feishu.EncryptKey == "" {
+			return fmt.Errorf("feishu 需要配置 verification_token 或 encrypt_key 以校验回调")
+		}
+		if len(bot.Feishu.AllowedUsers) == 0 {
+			return fmt.Errorf("feishu 未配置 allowed_users")
+		}
+	}
+	if bot.DingTalk.Enable {
+		if bot.DingTalk.AppSecret == "" {
+			return fmt.Errorf("dingtalk 需要配置 app_secret")
+		}
+		if len(bot.DingTalk.AllowedUsers) == 0 {
+			return fmt.Errorf("dingtalk 未配置 allowed_users")
+		}
+	}
+	return nil
+}
+
+// 验证通知配置
+func validateNotifyConfig(notify *NotifyConfig) error {
+	if notify.Timeout < 0 || notify.Retries < 0 || notify.RetryDelay < 0 {
+		return fmt.Errorf("超时与重试配置不能为负数")
+	}
+	for name, channel := range notify.Channels {
+		switch channel.Type {
+		case "slack", "dingtalk", "feishu", "webhook":
+			if channel.URL == "" {
+				return fmt.Errorf("通知渠道 %s 未配置 url", name)
+			}
+		case "email":
+			if channel.Host == "" || channel.From == "" || len(channel.To) == 0 {
+				return fmt.Errorf("通知渠道 %s 需要配置 host、from 与 to", name)
+			}
+		default:
+			return fmt.Errorf("通知渠道 %s 的类型不支持: %s（可选 slack、dingtalk、feishu、webhook、email）", name, channel.Type)
+		}
+	}
+	return nil
+}
+
+// 验证链路追踪配置
+func validateTracingConfig(tracing *TracingConfig) error {
+	switch tracing.Exporter {
+	case "", "otlp":
+		if tracing.Endpoint == "" {
+			return fmt.Errorf("OTLP 收集器地址未配置")
+		}
+		if !strings.HasPrefix(tracing.Endpoint, "http://") && !strings.HasPrefix(tracing.Endpoint, "https://") {
+			return fmt.Errorf("OTLP 收集器地址格式不正确: %s", tracing.Endpoint)
+		}
+	case "file":
+		if tracing.File == "" {
+			return fmt.Errorf("追踪输出文件未配置")
+		}
+	default:
+		return fmt.Errorf("不支持的追踪导出器: %s（可选 otlp、file）", tracing.Exporter)
+	}
+
+	return nil
+}
+
+// 验证脱敏配置
+func validateRedactionConfig(redaction *RedactionConfig) error {
+	for i, pattern := range redaction.Patterns {
+		if pattern.Name == "" {
+			return fmt.Errorf("第%d条自定义脱敏规则缺少名称", i+1)
+		}
+		if _, err := regexp.Compile(pattern.Regex); err != nil {
+			return fmt.Errorf("脱敏规则 %s 的正则表达式无效: %w", pattern.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// 获取当前配置，配置可能随时被热重载替换，同一处理中需要一致的配置时应保存返回值
 func GetConfig() *AppConfig {
-	return Config
+	return current.Load()
+}
+
+// 获取当前加载的配置文件路径
+func GetConfigPath() string {
+	return configFilePath
 }
 
 // 获取指定模型的配置
 func GetModelConfig(modelName string) (ModelConfig, error) {
-	if Config == nil {
+	config := GetConfig()
+	if config == nil {
 		return ModelConfig{}, fmt.Errorf("配置未初始化")
 	}
 
 	if modelName == "" {
-		modelName = Config.AI.DefaultModel
+		modelName = config.AI.DefaultModel
 	}
 
-	model, exists := Config.AI.Models[modelName]
+	model, exists := config.AI.Models[modelName]
 	if !exists {
 		return ModelConfig{}, fmt.Errorf("模型 '%s' 未配置", modelName)
 	}
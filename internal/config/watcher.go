@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	util "ai-ops/internal/util"
+)
+
+// 默认的配置文件轮询间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// ChangeHandler 配置变更回调，参数为变更前后的配置
+type ChangeHandler func(oldConfig, newConfig *AppConfig)
+
+// Watcher 通过轮询配置文件的修改时间和大小检测变更，并在变更后重新加载配置
+type Watcher struct {
+	path     string
+	interval time.Duration
+	onChange ChangeHandler
+
+	modTime time.Time
+	size    int64
+
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWatcher 创建配置文件监听器，interval 小于等于0时使用默认间隔
+func NewWatcher(path string, interval time.Duration, onChange ChangeHandler) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &Watcher{
+		path:     path,
+		interval: interval,
+		onChange: onChange,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start 启动后台轮询
+func (w *Watcher) Start() {
+	if info, err := os.Stat(w.path); err == nil {
+		w.modTime = info.ModTime()
+		w.size = info.Size()
+	}
+
+	go func() {
+		defer close(w.doneCh)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+
+	util.Debugw("配置文件监听已启动", map[string]interface{}{
+		"path":     w.path,
+		"interval": w.interval.String(),
+	})
+}
+
+// Stop 停止轮询并等待后台协程退出
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		<-w.doneCh
+	})
+}
+
+// check 检查配置文件是否变更，变更时重新解析并替换全局配置
+func (w *Watcher) check() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+
+	newConfig, err := parseConfigFile(w.path)
+	if err != nil {
+		// 保留旧配置，等待下一次修改
+		util.Warnw("配置文件重新加载失败，继续使用旧配置", map[string]interface{}{
+			"path":  w.path,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	oldConfig := current.Swap(newConfig)
+	util.Infow("配置文件已重新加载", map[string]interface{}{"path": w.path})
+
+	if w.onChange != nil {
+		w.onChange(oldConfig, newConfig)
+	}
+}
@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util/errors"
+)
+
+// judgePrompt 裁判模型的系统提示词
+const judgePrompt = `你是一名严格的评测裁判，负责判断运维助手的回答是否满足给定的评判标准。
+只根据评判标准判断，不要考虑标准之外的因素。
+只输出一个 JSON 对象，不要输出其他内容：{"pass": true 或 false, "reason": "一句话说明理由"}`
+
+// verdict 裁判模型的判断结果
+type verdict struct {
+	Pass   bool   `json:"pass"`
+	Reason string `json:"reason"`
+}
+
+// judge 使用裁判模型判断回答是否满足标准
+type judge struct {
+	client llm.ModelAdapter
+}
+
+// evaluate 请求裁判模型判断回答，返回结果与裁判消耗的令牌
+func (j *judge) evaluate(ctx context.Context, input, answer, criterion string) (*verdict, llm.TokenUsage, error) {
+	messages := []llm.Message{
+		{Role: "system", Content: judgePrompt},
+		{Role: "user", Content: fmt.Sprintf("## 用户问题\n%s\n\n## 助手回答\n%s\n\n## 评判标准\n%s", input, answer, criterion)},
+	}
+	resp, err := j.client.SendMessage(ctx, messages, nil, llm.ToolChoice{Mode: llm.ToolChoiceNone})
+	if err != nil {
+		return nil, llm.TokenUsage{}, err
+	}
+
+	// 模型可能在 JSON 前后附加说明或代码块标记，取第一个对象
+	content := resp.Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, resp.Usage, errors.NewErrorWithDetails(errors.ErrCodeInvalidResponse, "裁判模型没有返回 JSON", content)
+	}
+	var result verdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, resp.Usage, errors.NewErrorWithDetails(errors.ErrCodeInvalidResponse, "解析裁判结果失败", content)
+	}
+	return &result, resp.Usage, nil
+}
@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+
+	"ai-ops/internal/util/errors"
+)
+
+// JUnit XML 结构，每个模型对应一个 testsuite，便于 CI 按模型展示结果
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit 将评测结果写入 JUnit XML 文件
+func WriteJUnit(path string, report *Report) error {
+	data, err := xml.MarshalIndent(junitReport(report), "", "  ")
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "生成 JUnit 报告失败", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.WrapError(errors.ErrCodeInvalidParameters, "写入 JUnit 报告失败", err)
+	}
+	return nil
+}
+
+// junitReport 将评测结果转换为 JUnit 结构
+func junitReport(report *Report) junitTestSuites {
+	suites := junitTestSuites{Name: report.Suite, Time: seconds(report.DurationMs)}
+	for _, model := range report.Models {
+		suite := junitTestSuite{
+			Name:      fmt.Sprintf("%s/%s", report.Suite, model.Adapter),
+			Tests:     model.Total(),
+			Failures:  model.Failed,
+			Errors:    model.Errors,
+			Time:      seconds(model.DurationMs),
+			Timestamp: report.StartedAt.Format("2006-01-02T15:04:05"),
+			Properties: []junitProperty{
+				{Name: "adapter", Value: model.Adapter},
+				{Name: "model", Value: model.Model},
+				{Name: "pass_rate", Value: fmt.Sprintf("%.4f", model.PassRate())},
+				{Name: "total_tokens", Value: fmt.Sprint(model.Usage.TotalTokens)},
+				{Name: "cost", Value: fmt.Sprintf("%.6f", model.Cost)},
+			},
+		}
+		for _, result := range model.Cases {
+			testCase := junitTestCase{
+				Name:      result.Name,
+				ClassName: suite.Name,
+				Time:      seconds(result.DurationMs),
+				SystemOut: result.Answer,
+			}
+			switch result.Status {
+			case StatusFailed:
+				testCase.Failure = &junitMessage{
+					Message: result.Failures[0],
+					Type:    "AssertionFailed",
+					Text:    strings.Join(result.Failures, "\n"),
+				}
+			case StatusError:
+				testCase.Error = &junitMessage{Message: result.Error, Type: "ExecutionError", Text: result.Error}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+	return suites
+}
+
+// seconds 将毫秒转换为 JUnit 使用的秒数
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
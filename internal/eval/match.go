@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-ops/internal/chat"
+)
+
+// checkTools 检查工具调用是否按顺序包含全部期望的调用，返回不满足的原因
+func checkTools(expected []ToolExpectation, calls []chat.ToolCallTrace) []string {
+	if expected == nil {
+		return nil
+	}
+	if len(expected) == 0 {
+		if len(calls) > 0 {
+			return []string{fmt.Sprintf("期望不调用工具，实际调用了 %s", callNames(calls))}
+		}
+		return nil
+	}
+
+	// 期望的调用按顺序匹配，允许中间穿插其他调用
+	next := 0
+	var mismatches []string
+	for i := range expected {
+		expectation := &expected[i]
+		matched := false
+		for next < len(calls) {
+			call := calls[next]
+			next++
+			if call.Name != expectation.Name {
+				continue
+			}
+			reasons := matchArgs(expectation.Args, call.Arguments)
+			if len(reasons) == 0 {
+				matched = true
+				break
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", call.Name, strings.Join(reasons, "；")))
+		}
+		if !matched {
+			failure := fmt.Sprintf("缺少期望的工具调用 %s（实际调用: %s）", expectation.Name, callNames(calls))
+			if len(mismatches) > 0 {
+				failure += "，参数不匹配: " + strings.Join(mismatches, "；")
+			}
+			return []string{failure}
+		}
+		mismatches = nil
+	}
+	return nil
+}
+
+// matchArgs 检查工具参数，返回不满足的条件
+func matchArgs(matchers map[string]ArgMatcher, args map[string]any) []string {
+	names := make([]string, 0, len(matchers))
+	for name := range matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var reasons []string
+	for _, name := range names {
+		if reason := matchers[name].match(args, name); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// match 检查单个参数，满足时返回空字符串
+func (m ArgMatcher) match(args map[string]any, name string) string {
+	value, exists := args[name]
+	if m.Exists != nil {
+		if exists != *m.Exists {
+			if exists {
+				return fmt.Sprintf("参数 %s 不应出现", name)
+			}
+			return fmt.Sprintf("缺少参数 %s", name)
+		}
+		if !exists {
+			return ""
+		}
+	}
+	if !exists {
+		return fmt.Sprintf("缺少参数 %s", name)
+	}
+
+	text := argText(value)
+	switch {
+	case m.Equals != nil && canonicalJSON(m.Equals) != canonicalJSON(value):
+		return fmt.Sprintf("参数 %s 为 %s，期望 %s", name, text, canonicalJSON(m.Equals))
+	case m.Contains != "" && !strings.Contains(text, m.Contains):
+		return fmt.Sprintf("参数 %s 为 %s，不包含 %q", name, text, m.Contains)
+	case m.Regex != nil && !m.Regex.MatchString(text):
+		return fmt.Sprintf("参数 %s 为 %s，不匹配 /%s/", name, text, m.Regex)
+	}
+	return ""
+}
+
+// argText 返回参数用于文本匹配的形式，字符串保持原样，其他值使用 JSON
+func argText(value any) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return canonicalJSON(value)
+}
+
+// canonicalJSON 将值序列化为 JSON，使 YAML 中的整数与模型参数中的浮点数可以比较
+func canonicalJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// callNames 返回工具调用名称列表的描述
+func callNames(calls []chat.ToolCallTrace) string {
+	if len(calls) == 0 {
+		return "无"
+	}
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	return strings.Join(names, " → ")
+}
+
+// checkText 检查不依赖模型的回答断言，满足时返回空字符串
+func checkText(assertion Assertion, answer string) string {
+	switch {
+	case assertion.Contains != "" && !strings.Contains(answer, assertion.Contains):
+	case assertion.NotContains != "" && strings.Contains(answer, assertion.NotContains):
+	case assertion.Regex != nil && !assertion.Regex.MatchString(answer):
+	default:
+		return ""
+	}
+	return "回答不满足断言: " + assertion.String()
+}
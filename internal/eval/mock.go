@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util/errors"
+)
+
+// mockToolManager 评测使用的工具管理器：向模型提供与真实环境相同的工具定义，
+// 但工具调用只返回用例配置的模拟结果，不会真正执行
+type mockToolManager struct {
+	tools map[string]*mockTool
+}
+
+// newMockToolManager 以真实工具为基础创建模拟工具管理器，
+// 模拟结果中不存在的工具作为新工具提供给模型
+func newMockToolManager(real tools.ToolManager, mocks map[string]MockTool) *mockToolManager {
+	manager := &mockToolManager{tools: make(map[string]*mockTool)}
+	if real != nil {
+		for _, tool := range real.GetTools() {
+			mock, mocked := mocks[tool.ID()]
+			manager.tools[tool.ID()] = &mockTool{
+				name:        tool.ID(),
+				description: tool.Description(),
+				parameters:  tool.Parameters(),
+				risk:        tools.RiskOf(tool),
+				mock:        mock,
+				mocked:      mocked,
+			}
+		}
+	}
+
+	for name, mock := range mocks {
+		if _, exists := manager.tools[name]; exists {
+			continue
+		}
+		tool := &mockTool{
+			name:        name,
+			description: mock.Description,
+			parameters:  mock.Parameters,
+			risk:        tools.RiskReadOnly,
+			mock:        mock,
+			mocked:      true,
+		}
+		if tool.description == "" {
+			tool.description = name
+		}
+		if tool.parameters == nil {
+			tool.parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		manager.tools[name] = tool
+	}
+	return manager
+}
+
+// RegisterTool 评测期间不注册新工具
+func (m *mockToolManager) RegisterTool(tool tools.Tool) error {
+	return errors.NewError(errors.ErrCodeInvalidParameters, "评测使用的工具管理器不支持注册工具")
+}
+
+// RegisterToolFactory 评测期间不注册插件
+func (m *mockToolManager) RegisterToolFactory(name string, factory tools.PluginFactory) {}
+
+// InitializePlugins 评测期间不初始化插件
+func (m *mockToolManager) InitializePlugins() {}
+
+// GetTools 获取所有工具，按名称排序
+func (m *mockToolManager) GetTools() []tools.Tool {
+	names := make([]string, 0, len(m.tools))
+	for name := range m.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]tools.Tool, 0, len(names))
+	for _, name := range names {
+		result = append(result, m.tools[name])
+	}
+	return result
+}
+
+// GetTool 根据名称获取工具
+func (m *mockToolManager) GetTool(name string) (tools.Tool, error) {
+	tool, exists := m.tools[name]
+	if !exists {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeToolNotFound, "工具不存在", name)
+	}
+	return tool, nil
+}
+
+// ExecuteToolCall 返回工具的模拟结果
+func (m *mockToolManager) ExecuteToolCall(ctx context.Context, call tools.ToolCall) (string, error) {
+	tool, err := m.GetTool(call.Name)
+	if err != nil {
+		return "", err
+	}
+	return tool.Execute(ctx, call.Arguments)
+}
+
+// GetToolDefinitions 获取工具定义列表
+func (m *mockToolManager) GetToolDefinitions() []tools.ToolDefinition {
+	all := m.GetTools()
+	defs := make([]tools.ToolDefinition, 0, len(all))
+	for _, tool := range all {
+		defs = append(defs, tools.ToolDefinition{
+			Name:        tool.ID(),
+			Description: tool.Description(),
+			Parameters:  tool.Parameters(),
+		})
+	}
+	return defs
+}
+
+// mockTool 返回模拟结果的工具
+type mockTool struct {
+	name        string
+	description string
+	parameters  map[string]any
+	risk        tools.RiskLevel // 与真实工具一致，执行记录中的风险等级保持不变
+	mock        MockTool
+	mocked      bool // 是否配置了模拟结果
+}
+
+func (t *mockTool) ID() string                 { return t.name }
+func (t *mockTool) Name() string               { return t.name }
+func (t *mockTool) Type() string               { return "mock" }
+func (t *mockTool) Description() string        { return t.description }
+func (t *mockTool) Parameters() map[string]any { return t.parameters }
+func (t *mockTool) RiskLevel() tools.RiskLevel { return t.risk }
+
+// Execute 返回模拟结果，未配置模拟结果的工具不会执行
+func (t *mockTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	switch {
+	case !t.mocked:
+		return "", fmt.Errorf("评测用例没有为工具 %s 配置模拟结果", t.name)
+	case t.mock.Error != "":
+		return "", fmt.Errorf("%s", t.mock.Error)
+	default:
+		return t.mock.Result, nil
+	}
+}
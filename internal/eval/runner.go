@@ -0,0 +1,348 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/config"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 套件与命令行均未设置超时时使用的单个用例超时时间
+const defaultTimeout = 2 * time.Minute
+
+// 用例结果状态
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed" // 断言不满足
+	StatusError  = "error"  // 模型调用失败、超时等，无法得出结论
+)
+
+// Options 评测参数，未设置的项使用套件中的配置
+type Options struct {
+	Models  []string       // 参与评测的模型适配器，覆盖套件的 models
+	Judge   string         // 裁判模型，覆盖套件的 judge
+	Timeout time.Duration  // 单个用例的超时时间，覆盖套件的 timeout
+	Filter  *regexp.Regexp // 只执行名称匹配的用例
+	// OnCase 每个用例执行完成后调用，用于显示进度
+	OnCase func(model *ModelReport, result *CaseResult)
+}
+
+// CaseResult 一个用例在一个模型上的执行结果
+type CaseResult struct {
+	Name       string               `json:"name"`
+	Status     string               `json:"status"`
+	Failures   []string             `json:"failures,omitempty"` // 不满足的断言
+	Error      string               `json:"error,omitempty"`
+	Answer     string               `json:"answer"`
+	ToolCalls  []chat.ToolCallTrace `json:"tool_calls"`
+	Rounds     int                  `json:"rounds"`
+	Usage      llm.TokenUsage       `json:"usage"`
+	Cost       float64              `json:"cost"`
+	DurationMs int64                `json:"duration_ms"`
+}
+
+// ModelReport 一个模型的评测结果
+type ModelReport struct {
+	Adapter    string         `json:"adapter"`
+	Model      string         `json:"model"`
+	Passed     int            `json:"passed"`
+	Failed     int            `json:"failed"`
+	Errors     int            `json:"errors"`
+	Usage      llm.TokenUsage `json:"usage"`
+	Cost       float64        `json:"cost"`
+	Priced     bool           `json:"priced"` // 模型是否配置了价格，未配置时费用为 0
+	DurationMs int64          `json:"duration_ms"`
+	Cases      []*CaseResult  `json:"cases"`
+}
+
+// Total 返回执行的用例数
+func (m *ModelReport) Total() int {
+	return m.Passed + m.Failed + m.Errors
+}
+
+// PassRate 返回通过率（0-1）
+func (m *ModelReport) PassRate() float64 {
+	if m.Total() == 0 {
+		return 0
+	}
+	return float64(m.Passed) / float64(m.Total())
+}
+
+// AvgLatency 返回用例的平均耗时
+func (m *ModelReport) AvgLatency() time.Duration {
+	if m.Total() == 0 {
+		return 0
+	}
+	return time.Duration(m.DurationMs/int64(m.Total())) * time.Millisecond
+}
+
+// Report 一次评测的结果
+type Report struct {
+	Suite      string         `json:"suite"`
+	Judge      string         `json:"judge,omitempty"`
+	JudgeUsage llm.TokenUsage `json:"judge_usage"` // 裁判模型消耗的令牌，不计入被评测模型
+	StartedAt  time.Time      `json:"started_at"`
+	DurationMs int64          `json:"duration_ms"`
+	Models     []*ModelReport `json:"models"`
+}
+
+// Passed 是否所有模型的所有用例均通过
+func (r *Report) Passed() bool {
+	for _, model := range r.Models {
+		if model.Passed != model.Total() {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner 使用独立的会话在每个模型上依次执行评测用例
+type Runner struct {
+	toolManager tools.ToolManager
+	base        chat.SessionConfig // 会话的公共设置，ModelName 为默认模型
+	options     Options
+}
+
+// NewRunner 创建评测执行器
+func NewRunner(toolManager tools.ToolManager, base chat.SessionConfig, options Options) *Runner {
+	return &Runner{toolManager: toolManager, base: base, options: options}
+}
+
+// Run 执行套件中的用例。ctx 取消后不再开始新的用例，返回已完成用例的结果。
+func (r *Runner) Run(ctx context.Context, suite *Suite) (*Report, error) {
+	models := r.options.Models
+	if len(models) == 0 {
+		models = suite.Models
+	}
+	if len(models) == 0 && r.base.ModelName != "" {
+		models = []string{r.base.ModelName}
+	}
+	if len(models) == 0 {
+		return nil, errors.NewError(errors.ErrCodeClientNotFound, "没有可用的AI模型配置，请检查config.toml")
+	}
+	clients := make([]llm.ModelAdapter, len(models))
+	for i, name := range models {
+		client, exists := llm.GetAdapter(name)
+		if !exists {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", name)
+		}
+		clients[i] = client
+	}
+
+	cases := r.selectCases(suite)
+	if len(cases) == 0 {
+		return nil, errors.NewError(errors.ErrCodeInvalidParameters, "没有匹配的评测用例")
+	}
+
+	report := &Report{Suite: suite.Name, StartedAt: time.Now()}
+	var judgeClient *judge
+	if usesJudge(cases) {
+		report.Judge = r.options.Judge
+		if report.Judge == "" {
+			report.Judge = suite.Judge
+		}
+		if report.Judge == "" {
+			report.Judge = models[0]
+		}
+		client, exists := llm.GetAdapter(report.Judge)
+		if !exists {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "裁判模型不存在", report.Judge)
+		}
+		judgeClient = &judge{client: client}
+	}
+
+	timeout := r.options.Timeout
+	if timeout <= 0 {
+		timeout = suite.timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	for i, name := range models {
+		model := &ModelReport{Adapter: name, Model: clients[i].GetModelInfo().Name}
+		report.Models = append(report.Models, model)
+		for _, c := range cases {
+			if ctx.Err() != nil {
+				break
+			}
+			result := r.runCase(ctx, suite, c, name, clients[i], timeout)
+			if result.Status != StatusError && len(result.Failures) == 0 {
+				failures, err := r.checkAnswer(ctx, judgeClient, report, c, result)
+				result.Failures = failures
+				if err != nil {
+					result.Status = StatusError
+					result.Error = err.Error()
+				}
+			}
+			if result.Status == StatusPassed && len(result.Failures) > 0 {
+				result.Status = StatusFailed
+			}
+			model.add(result)
+			if r.options.OnCase != nil {
+				r.options.OnCase(model, result)
+			}
+		}
+		model.Priced = priced(name)
+	}
+
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	util.Infow("评测完成", map[string]any{
+		"suite":       suite.Name,
+		"models":      len(report.Models),
+		"duration_ms": report.DurationMs,
+	})
+	return report, ctx.Err()
+}
+
+// selectCases 返回名称匹配过滤条件的用例
+func (r *Runner) selectCases(suite *Suite) []*Case {
+	cases := make([]*Case, 0, len(suite.Cases))
+	for i := range suite.Cases {
+		if r.options.Filter == nil || r.options.Filter.MatchString(suite.Cases[i].Name) {
+			cases = append(cases, &suite.Cases[i])
+		}
+	}
+	return cases
+}
+
+// runCase 使用新的会话执行一个用例，检查工具调用
+func (r *Runner) runCase(ctx context.Context, suite *Suite, c *Case, modelName string, client llm.ModelAdapter, timeout time.Duration) *CaseResult {
+	result := &CaseResult{Name: c.Name, Status: StatusPassed, ToolCalls: []chat.ToolCallTrace{}}
+
+	sessionConfig := r.base
+	sessionConfig.Mode = suite.mode(c)
+	sessionConfig.ModelName = modelName
+	// 无人值守执行，有风险的工具调用按配置自动批准或拒绝（prompt 模式下拒绝）
+	sessionConfig.Approver = nil
+	sessionConfig.Store = nil
+	switch {
+	case c.Profile != "":
+		sessionConfig.Profile = c.Profile
+	case suite.Profile != "":
+		sessionConfig.Profile = suite.Profile
+	}
+	if sessionConfig.Prompts != nil {
+		if _, err := sessionConfig.Prompts.Load(sessionConfig.Profile, sessionConfig.Mode); err != nil {
+			result.Status = StatusError
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	toolManager := r.toolManager
+	if mocks := suite.mocks(c); mocks != nil {
+		// 模拟的工具不会真正执行，无需人工确认
+		toolManager = newMockToolManager(r.toolManager, mocks)
+		sessionConfig.ApprovalMode = "auto"
+		sessionConfig.DisableTools = false
+	}
+
+	caseCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	session := chat.NewSession(client, toolManager, sessionConfig)
+	defer session.Close()
+
+	startTime := time.Now()
+	var answer string
+	var err error
+	if sessionConfig.Mode == "agent" {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(caseCtx, c.Input, chat.AgentHooks{})
+		if report != nil {
+			answer = report.Final
+		}
+	} else {
+		answer, err = session.ProcessMessage(caseCtx, c.Input)
+	}
+	result.DurationMs = time.Since(startTime).Milliseconds()
+
+	turn := session.LastTurn()
+	result.Rounds = turn.Rounds
+	result.ToolCalls = turn.ToolCalls
+	result.Usage = turn.Usage
+	result.Cost = session.Usage().Cost
+	result.Answer = chat.ExtractThinking(answer).Content
+	if err != nil {
+		if caseCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			err = fmt.Errorf("执行超过 %s 未完成: %w", timeout, err)
+		}
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Failures = checkTools(c.ExpectTools, result.ToolCalls)
+	return result
+}
+
+// checkAnswer 检查回答断言，返回不满足的原因。裁判模型调用失败时无法得出结论，返回错误。
+// 裁判模型的令牌计入报告的 JudgeUsage。
+func (r *Runner) checkAnswer(ctx context.Context, j *judge, report *Report, c *Case, result *CaseResult) ([]string, error) {
+	var failures []string
+	for _, assertion := range c.Expect {
+		if assertion.Judge == "" {
+			if failure := checkText(assertion, result.Answer); failure != "" {
+				failures = append(failures, failure)
+			}
+			continue
+		}
+
+		verdict, usage, err := j.evaluate(ctx, c.Input, result.Answer, assertion.Judge)
+		report.JudgeUsage.PromptTokens += usage.PromptTokens
+		report.JudgeUsage.CompletionTokens += usage.CompletionTokens
+		report.JudgeUsage.TotalTokens += usage.TotalTokens
+		if err != nil {
+			util.Warnw("裁判模型调用失败", map[string]any{"case": c.Name, "error": err.Error()})
+			return failures, fmt.Errorf("裁判模型调用失败: %w", err)
+		}
+		if !verdict.Pass {
+			failures = append(failures, fmt.Sprintf("裁判判定不满足（%s）: %s", assertion.String(), verdict.Reason))
+		}
+	}
+	return failures, nil
+}
+
+// priced 判断模型是否配置了价格
+func priced(modelName string) bool {
+	modelConfig, err := config.GetModelConfig(modelName)
+	return err == nil && (modelConfig.InputPrice > 0 || modelConfig.OutputPrice > 0)
+}
+
+// add 累计用例结果
+func (m *ModelReport) add(result *CaseResult) {
+	m.Cases = append(m.Cases, result)
+	switch result.Status {
+	case StatusPassed:
+		m.Passed++
+	case StatusFailed:
+		m.Failed++
+	default:
+		m.Errors++
+	}
+	m.Usage.PromptTokens += result.Usage.PromptTokens
+	m.Usage.CompletionTokens += result.Usage.CompletionTokens
+	m.Usage.TotalTokens += result.Usage.TotalTokens
+	m.Cost += result.Cost
+	m.DurationMs += result.DurationMs
+}
+
+// usesJudge 判断用例中是否有需要裁判模型的断言
+func usesJudge(cases []*Case) bool {
+	for _, c := range cases {
+		for _, assertion := range c.Expect {
+			if assertion.Judge != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"ai-ops/internal/util/errors"
+)
+
+// Suite 评测套件，由一个 YAML 文件定义
+type Suite struct {
+	Name    string   `yaml:"name"`    // 套件名称，默认为文件名
+	Models  []string `yaml:"models"`  // 参与评测的模型适配器，默认使用 default_model
+	Judge   string   `yaml:"judge"`   // judge 断言使用的裁判模型，默认为第一个参与评测的模型
+	Mode    string   `yaml:"mode"`    // 默认的对话模式：chat 或 agent
+	Profile string   `yaml:"profile"` // 默认的提示词模板
+	Timeout string   `yaml:"timeout"` // 单个用例的超时时间，如 60s
+	// Tools 所有用例共用的工具模拟结果，配置后工具不会真正执行
+	Tools map[string]MockTool `yaml:"tools"`
+	Cases []Case              `yaml:"cases"`
+
+	Path    string        `yaml:"-"` // 套件文件路径
+	timeout time.Duration // 解析后的超时时间
+}
+
+// Case 评测用例
+type Case struct {
+	Name    string `yaml:"name"`
+	Input   string `yaml:"input"`   // 发送给模型的输入
+	Mode    string `yaml:"mode"`    // 覆盖套件的对话模式
+	Profile string `yaml:"profile"` // 覆盖套件的提示词模板
+	// Tools 本用例的工具模拟结果，与套件的模拟结果合并
+	Tools map[string]MockTool `yaml:"tools"`
+	// ExpectTools 期望按顺序出现的工具调用，配置为空列表表示不应调用任何工具
+	ExpectTools []ToolExpectation `yaml:"expect_tools"`
+	// Expect 对最终回答的断言
+	Expect []Assertion `yaml:"expect"`
+}
+
+// MockTool 工具的模拟结果
+type MockTool struct {
+	Result      string         `yaml:"result"`      // 返回给模型的结果
+	Error       string         `yaml:"error"`       // 模拟执行失败
+	Description string         `yaml:"description"` // 工具不存在时使用的描述
+	Parameters  map[string]any `yaml:"parameters"`  // 工具不存在时使用的参数 schema
+}
+
+// ToolExpectation 期望的工具调用
+type ToolExpectation struct {
+	Name string                `yaml:"name"`
+	Args map[string]ArgMatcher `yaml:"args"` // 参数匹配条件，未列出的参数不检查
+}
+
+// ArgMatcher 工具参数的匹配条件。直接写值表示相等，
+// 也可以写 {contains: ...}、{regex: ...} 或 {exists: true/false}
+type ArgMatcher struct {
+	Equals   any
+	Contains string
+	Regex    *regexp.Regexp
+	Exists   *bool
+}
+
+// 参数匹配条件的关键字
+var matcherKeys = map[string]bool{"equals": true, "contains": true, "regex": true, "exists": true}
+
+// UnmarshalYAML 区分匹配条件与期望的对象值：只包含匹配关键字的映射视为匹配条件
+func (m *ArgMatcher) UnmarshalYAML(node *yaml.Node) error {
+	isMatcher := node.Kind == yaml.MappingNode && len(node.Content) > 0
+	for i := 0; isMatcher && i < len(node.Content); i += 2 {
+		isMatcher = matcherKeys[node.Content[i].Value]
+	}
+	if !isMatcher {
+		return node.Decode(&m.Equals)
+	}
+
+	var raw struct {
+		Equals   any    `yaml:"equals"`
+		Contains string `yaml:"contains"`
+		Regex    string `yaml:"regex"`
+		Exists   *bool  `yaml:"exists"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	m.Equals = raw.Equals
+	m.Contains = raw.Contains
+	m.Exists = raw.Exists
+	if raw.Regex != "" {
+		re, err := regexp.Compile(raw.Regex)
+		if err != nil {
+			return fmt.Errorf("第 %d 行的正则表达式无效: %w", node.Line, err)
+		}
+		m.Regex = re
+	}
+	return nil
+}
+
+// Assertion 对最终回答的断言，每条断言只能设置一项
+type Assertion struct {
+	Contains    string // 回答包含该文本
+	NotContains string // 回答不包含该文本
+	Regex       *regexp.Regexp
+	Judge       string // 由裁判模型判断回答是否满足该标准
+}
+
+// UnmarshalYAML 解析断言并检查只设置了一项
+func (a *Assertion) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Contains    string `yaml:"contains"`
+		NotContains string `yaml:"not_contains"`
+		Regex       string `yaml:"regex"`
+		Judge       string `yaml:"judge"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	set := 0
+	for _, value := range []string{raw.Contains, raw.NotContains, raw.Regex, raw.Judge} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("第 %d 行的断言应设置 contains、not_contains、regex、judge 中的一项", node.Line)
+	}
+
+	a.Contains = raw.Contains
+	a.NotContains = raw.NotContains
+	a.Judge = raw.Judge
+	if raw.Regex != "" {
+		re, err := regexp.Compile(raw.Regex)
+		if err != nil {
+			return fmt.Errorf("第 %d 行的正则表达式无效: %w", node.Line, err)
+		}
+		a.Regex = re
+	}
+	return nil
+}
+
+// String 返回断言的简短描述
+func (a Assertion) String() string {
+	switch {
+	case a.Contains != "":
+		return fmt.Sprintf("包含 %q", a.Contains)
+	case a.NotContains != "":
+		return fmt.Sprintf("不包含 %q", a.NotContains)
+	case a.Regex != nil:
+		return fmt.Sprintf("匹配 /%s/", a.Regex)
+	default:
+		return "满足标准: " + a.Judge
+	}
+}
+
+// Load 读取并校验评测套件
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "评测套件不存在", path)
+		}
+		return nil, errors.WrapError(errors.ErrCodeInvalidParameters, "读取评测套件失败", err)
+	}
+
+	var suite Suite
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	// 拼写错误的字段会让断言悄悄失效，提前报错
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&suite); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeConfigParseFailed, "解析评测套件失败: "+path, err)
+	}
+
+	suite.Path = path
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := suite.validate(); err != nil {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid, "评测套件无效: "+path, err.Error())
+	}
+	return &suite, nil
+}
+
+// validate 校验套件配置
+func (s *Suite) validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("没有评测用例")
+	}
+	if err := validateMode(s.Mode); err != nil {
+		return err
+	}
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("无效的超时时间: %s", s.Timeout)
+		}
+		s.timeout = timeout
+	}
+
+	names := make(map[string]bool, len(s.Cases))
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("用例名称重复: %s", c.Name)
+		}
+		names[c.Name] = true
+		if strings.TrimSpace(c.Input) == "" {
+			return fmt.Errorf("用例 %s 的 input 不能为空", c.Name)
+		}
+		if err := validateMode(c.Mode); err != nil {
+			return fmt.Errorf("用例 %s: %w", c.Name, err)
+		}
+		for _, expectation := range c.ExpectTools {
+			if expectation.Name == "" {
+				return fmt.Errorf("用例 %s 的 expect_tools 缺少工具名称", c.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateMode 检查对话模式
+func validateMode(mode string) error {
+	switch mode {
+	case "", "chat", "agent":
+		return nil
+	default:
+		return fmt.Errorf("不支持的模式: %s（可选 chat、agent）", mode)
+	}
+}
+
+// mode 返回用例使用的对话模式
+func (s *Suite) mode(c *Case) string {
+	switch {
+	case c.Mode != "":
+		return c.Mode
+	case s.Mode != "":
+		return s.Mode
+	default:
+		return "chat"
+	}
+}
+
+// mocks 合并套件与用例的工具模拟结果，没有模拟时返回 nil
+func (s *Suite) mocks(c *Case) map[string]MockTool {
+	if len(s.Tools) == 0 && len(c.Tools) == 0 {
+		return nil
+	}
+	mocks := make(map[string]MockTool, len(s.Tools)+len(c.Tools))
+	for name, mock := range s.Tools {
+		mocks[name] = mock
+	}
+	for name, mock := range c.Tools {
+		mocks[name] = mock
+	}
+	return mocks
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"ai-ops/internal/metrics"
 	"ai-ops/internal/tools"
 	"context"
 )
@@ -33,8 +34,8 @@ type AdapterInfo struct {
 
 // ModelAdapter 定义了与 LLM 交互的统一接口
 type ModelAdapter interface {
-	// SendMessage 发送消息并获取响应
-	SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition) (*Response, error)
+	// SendMessage 发送消息并获取响应，toolChoice 控制模型的工具调用行为
+	SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) (*Response, error)
 
 	// GetModelInfo 获取模型信息
 	GetModelInfo() ModelInfo
@@ -63,10 +64,11 @@ type ConfigValidator func(config interface{}) error
 
 // AdapterMetrics 适配器指标
 type AdapterMetrics struct {
-	RequestCount        int64  `json:"request_count"`
-	ErrorCount          int64  `json:"error_count"`
-	AverageResponseTime int64  `json:"average_response_time"`
-	LastRequestTime     int64  `json:"last_request_time"`
-	TokensUsed          int64  `json:"tokens_used"`
-	LastError           string `json:"last_error,omitempty"`
+	RequestCount        int64                     `json:"request_count"`
+	ErrorCount          int64                     `json:"error_count"`
+	AverageResponseTime int64                     `json:"average_response_time"`
+	LastRequestTime     int64                     `json:"last_request_time"`
+	TokensUsed          int64                     `json:"tokens_used"`
+	LastError           string                    `json:"last_error,omitempty"`
+	LatencyHistogram    metrics.HistogramSnapshot `json:"latency_histogram"` // 请求延迟分布（秒）
 }
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"ai-ops/internal/metrics"
 	pkg "ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 	"context"
@@ -16,6 +17,9 @@ type BaseAdapter struct {
 	// metrics 性能指标
 	metrics AdapterMetrics
 
+	// latency 请求延迟直方图
+	latency *metrics.Histogram
+
 	// mu 互斥锁，保证线程安全
 	mu sync.RWMutex
 
@@ -37,6 +41,7 @@ func NewBaseAdapter(info AdapterInfo) *BaseAdapter {
 	return &BaseAdapter{
 		info:        info,
 		metrics:     AdapterMetrics{},
+		latency:     metrics.NewHistogram(nil),
 		initialized: false,
 	}
 }
@@ -96,6 +101,7 @@ func (b *BaseAdapter) UpdateMetrics(responseTime int64, success bool, tokensUsed
 
 	b.metrics.RequestCount++
 	b.metrics.LastRequestTime = time.Now().Unix()
+	b.latency.Observe(float64(responseTime) / 1000)
 	b.metrics.TokensUsed += tokensUsed
 
 	if !success {
@@ -116,7 +122,9 @@ func (b *BaseAdapter) UpdateMetrics(responseTime int64, success bool, tokensUsed
 func (b *BaseAdapter) GetMetrics() AdapterMetrics {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.metrics
+	result := b.metrics
+	result.LatencyHistogram = b.latency.Snapshot()
+	return result
 }
 
 // SetErrorMapper 设置错误映射器
@@ -42,8 +42,8 @@ func createGeminiClient(modelCfg cfg.ModelConfig) (*GeminiClient, error) {
 
 	// 获取超时配置，从全局 AI 配置或默认值
 	var timeout time.Duration
-	if cfg.Config != nil && cfg.Config.AI.Timeout > 0 {
-		timeout = time.Duration(cfg.Config.AI.Timeout) * time.Second
+	if config := cfg.GetConfig(); config != nil && config.AI.Timeout > 0 {
+		timeout = time.Duration(config.AI.Timeout) * time.Second
 	} else {
 		timeout = 60 * time.Second // Gemini 可能需要更长的时间
 	}
@@ -138,16 +138,19 @@ func createGeminiClient(modelCfg cfg.ModelConfig) (*GeminiClient, error) {
 }
 
 // SendMessage 发送消息并获取响应
-func (c *GeminiClient) SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition) (*Response, error) {
+func (c *GeminiClient) SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) (resp *Response, err error) {
+	ctx, span := startSendSpan(ctx, c.modelInfo, messages, toolDefs, toolChoice)
+	defer func() { endSendSpan(span, resp, err) }()
+
 	startTime := time.Now()
 
-	request := c.buildRequest(messages, toolDefs)
+	request := c.buildRequest(messages, toolDefs, toolChoice)
 
 	// Gemini API 端点格式为 models/MODEL_NAME:generateContent
 	endpoint := fmt.Sprintf("models/%s:generateContent", c.modelInfo.Name)
 
 	var response GeminiResponse
-	err := c.httpClient.PostJSONWithRetry(ctx, endpoint, request, &response)
+	err = c.httpClient.PostJSONWithRetry(ctx, endpoint, request, &response)
 
 	// 计算响应时间并更新指标
 	responseTime := time.Since(startTime).Milliseconds()
@@ -223,7 +226,7 @@ func (c *GeminiClient) HealthCheck(ctx context.Context) error {
 	healthCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	_, err := c.SendMessage(healthCtx, testMessages, nil)
+	_, err := c.SendMessage(healthCtx, testMessages, nil, ToolChoice{})
 	if err != nil {
 		return errors.WrapError(errors.ErrCodeServiceUnavailable, "Gemini service health check failed", err)
 	}
@@ -232,7 +235,7 @@ func (c *GeminiClient) HealthCheck(ctx context.Context) error {
 }
 
 // buildRequest 构建 Gemini API 请求
-func (c *GeminiClient) buildRequest(messages []Message, toolDefs []tools.ToolDefinition) *GeminiRequest {
+func (c *GeminiClient) buildRequest(messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) *GeminiRequest {
 	contents := make([]GeminiContent, 0, len(messages))
 	for _, msg := range messages {
 		if msg.Role == "tool" {
@@ -294,11 +297,29 @@ func (c *GeminiClient) buildRequest(messages []Message, toolDefs []tools.ToolDef
 
 	if len(toolDefs) > 0 {
 		req.Tools = c.convertToolsToGeminiTools(toolDefs)
+		req.ToolConfig = c.convertToolChoice(toolChoice)
 	}
 
 	return req
 }
 
+// convertToolChoice 将工具选择设置转换为 Gemini functionCallingConfig，auto 模式不发送
+func (c *GeminiClient) convertToolChoice(toolChoice ToolChoice) *GeminiToolConfig {
+	var config GeminiFunctionCallingConfig
+	switch toolChoice.Mode {
+	case ToolChoiceNone:
+		config.Mode = "NONE"
+	case ToolChoiceRequired:
+		config.Mode = "ANY"
+	case ToolChoiceTool:
+		config.Mode = "ANY"
+		config.AllowedFunctionNames = []string{toolChoice.Name}
+	default:
+		return nil
+	}
+	return &GeminiToolConfig{FunctionCallingConfig: config}
+}
+
 // convertToolsToGeminiTools 将工具定义转换为 Gemini 的格式
 func (c *GeminiClient) convertToolsToGeminiTools(toolDefs []tools.ToolDefinition) []GeminiTool {
 	functions := make([]GeminiFunctionDeclaration, len(toolDefs))
@@ -401,9 +422,19 @@ func (c *GeminiClient) Close() error {
 // Gemini API 数据结构
 
 type GeminiRequest struct {
-	Contents         []GeminiContent `json:"contents"`
-	Tools            []GeminiTool    `json:"tools,omitempty"`
-	GenerationConfig *struct{}       `json:"generationConfig,omitempty"`
+	Contents         []GeminiContent   `json:"contents"`
+	Tools            []GeminiTool      `json:"tools,omitempty"`
+	ToolConfig       *GeminiToolConfig `json:"toolConfig,omitempty"`
+	GenerationConfig *struct{}         `json:"generationConfig,omitempty"`
+}
+
+type GeminiToolConfig struct {
+	FunctionCallingConfig GeminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO, ANY, NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type GeminiContent struct {
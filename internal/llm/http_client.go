@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"ai-ops/internal/tracing"
 	util "ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 	"bytes"
@@ -82,6 +83,7 @@ func (c *AIHTTPClient) Post(ctx context.Context, endpoint string, payload interf
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	tracing.Inject(ctx, req.Header)
 
 	// 记录详细的请求信息（脱敏与限长）
 	headersMap := make(map[string]string)
@@ -156,6 +158,7 @@ func (c *AIHTTPClient) Get(ctx context.Context, endpoint string) (*http.Response
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	tracing.Inject(ctx, req.Header)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
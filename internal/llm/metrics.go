@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"ai-ops/internal/metrics"
+)
+
+// CollectMetrics 将所有已创建适配器的 AdapterMetrics 写入指标输出
+func CollectMetrics(w *metrics.Writer) {
+	for _, name := range ListAdapters() {
+		adapter, exists := GetAdapter(name)
+		if !exists {
+			continue
+		}
+
+		m := adapter.GetMetrics()
+		labels := metrics.Labels{
+			"adapter": name,
+			"type":    adapter.GetModelInfo().Type,
+			"model":   adapter.GetModelInfo().Name,
+		}
+
+		w.Counter("llm_requests_total", "LLM 请求总数", labels, float64(m.RequestCount))
+		w.Counter("llm_errors_total", "LLM 请求失败总数", labels, float64(m.ErrorCount))
+		w.Counter("llm_tokens_total", "LLM 消耗的令牌总数", labels, float64(m.TokensUsed))
+		w.Gauge("llm_last_request_timestamp_seconds", "最近一次 LLM 请求的时间戳", labels, float64(m.LastRequestTime))
+		w.Histogram("llm_request_duration_seconds", "LLM 请求耗时分布", labels, m.LatencyHistogram)
+	}
+}
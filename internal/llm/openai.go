@@ -62,8 +62,8 @@ func createOpenAIClient(modelCfg cfg.ModelConfig) (*OpenAIClient, error) {
 
 	// 获取超时配置，从全局 AI 配置或默认值
 	var timeout time.Duration
-	if cfg.Config != nil && cfg.Config.AI.Timeout > 0 {
-		timeout = time.Duration(cfg.Config.AI.Timeout) * time.Second
+	if config := cfg.GetConfig(); config != nil && config.AI.Timeout > 0 {
+		timeout = time.Duration(config.AI.Timeout) * time.Second
 	} else {
 		timeout = 30 * time.Second
 	}
@@ -168,16 +168,19 @@ func createOpenAIClient(modelCfg cfg.ModelConfig) (*OpenAIClient, error) {
 }
 
 // SendMessage 发送消息并获取响应
-func (c *OpenAIClient) SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition) (*Response, error) {
+func (c *OpenAIClient) SendMessage(ctx context.Context, messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) (resp *Response, err error) {
+	ctx, span := startSendSpan(ctx, c.modelInfo, messages, toolDefs, toolChoice)
+	defer func() { endSendSpan(span, resp, err) }()
+
 	startTime := time.Now()
 
-	request := c.buildRequest(messages, toolDefs)
+	request := c.buildRequest(messages, toolDefs, toolChoice)
 
 	// base_url 已经包含完整的 api 请求地址，不需要传递endpoint，保持为空
 	endpoint := ""
 
 	var response OpenAIResponse
-	err := c.httpClient.PostJSONWithRetry(ctx, endpoint, request, &response)
+	err = c.httpClient.PostJSONWithRetry(ctx, endpoint, request, &response)
 
 	// 计算响应时间并更新指标
 	responseTime := time.Since(startTime).Milliseconds()
@@ -252,7 +255,7 @@ func (c *OpenAIClient) HealthCheck(ctx context.Context) error {
 	healthCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	_, err := c.SendMessage(healthCtx, testMessages, nil)
+	_, err := c.SendMessage(healthCtx, testMessages, nil, ToolChoice{})
 	if err != nil {
 		return errors.WrapError(errors.ErrCodeServiceUnavailable, "OpenAI service health check failed", err)
 	}
@@ -269,7 +272,7 @@ func (c *OpenAIClient) Close() error {
 }
 
 // buildRequest 构建 OpenAI API 请求
-func (c *OpenAIClient) buildRequest(messages []Message, toolDefs []tools.ToolDefinition) *OpenAIRequest {
+func (c *OpenAIClient) buildRequest(messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) *OpenAIRequest {
 	openaiMessages := make([]OpenAIMessage, len(messages))
 	for i, msg := range messages {
 		// 这部分需要根据 Message 结构转换为 OpenAIMessage
@@ -296,12 +299,29 @@ func (c *OpenAIClient) buildRequest(messages []Message, toolDefs []tools.ToolDef
 	// 添加工具定义
 	if len(toolDefs) > 0 {
 		request.Tools = c.convertToolsToOpenAITools(toolDefs)
-		request.ToolChoice = "auto"
+		request.ToolChoice = c.convertToolChoice(toolChoice)
 	}
 
 	return request
 }
 
+// convertToolChoice 将工具选择设置转换为 OpenAI tool_choice 格式
+func (c *OpenAIClient) convertToolChoice(toolChoice ToolChoice) interface{} {
+	switch toolChoice.Mode {
+	case ToolChoiceNone:
+		return "none"
+	case ToolChoiceRequired:
+		return "required"
+	case ToolChoiceTool:
+		return OpenAIToolChoice{
+			Type:     "function",
+			Function: OpenAIToolChoiceFunction{Name: toolChoice.Name},
+		}
+	default:
+		return "auto"
+	}
+}
+
 // convertToolsToOpenAITools 将工具定义转换为 OpenAI 工具格式
 func (c *OpenAIClient) convertToolsToOpenAITools(toolDefs []tools.ToolDefinition) []OpenAITool {
 	openaiTools := make([]OpenAITool, len(toolDefs))
@@ -386,6 +406,17 @@ type OpenAIRequest struct {
 	ToolChoice interface{}     `json:"tool_choice,omitempty"`
 }
 
+// OpenAIToolChoice 指定函数的工具选择
+type OpenAIToolChoice struct {
+	Type     string                   `json:"type"`
+	Function OpenAIToolChoiceFunction `json:"function"`
+}
+
+// OpenAIToolChoiceFunction 指定调用的函数
+type OpenAIToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
 // OpenAIMessage 消息结构
 type OpenAIMessage struct {
 	Role       string           `json:"role"`
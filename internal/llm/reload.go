@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cfg "ai-ops/internal/config"
+	"ai-ops/internal/util"
+)
+
+// ReloadResult 记录一次模型配置热重载产生的适配器变更
+type ReloadResult struct {
+	Added   []string         `json:"added,omitempty"`
+	Updated []string         `json:"updated,omitempty"`
+	Removed []string         `json:"removed,omitempty"`
+	Failed  map[string]error `json:"-"`
+}
+
+// HasChanges 是否有任何适配器发生变化
+func (r ReloadResult) HasChanges() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Removed)+len(r.Failed) > 0
+}
+
+// Summary 返回适合展示给用户的变更摘要
+func (r ReloadResult) Summary() string {
+	var parts []string
+	if len(r.Added) > 0 {
+		parts = append(parts, "新增: "+strings.Join(r.Added, ", "))
+	}
+	if len(r.Updated) > 0 {
+		parts = append(parts, "更新: "+strings.Join(r.Updated, ", "))
+	}
+	if len(r.Removed) > 0 {
+		parts = append(parts, "移除: "+strings.Join(r.Removed, ", "))
+	}
+	if len(r.Failed) > 0 {
+		names := make([]string, 0, len(r.Failed))
+		for name := range r.Failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, "失败: "+strings.Join(names, ", "))
+	}
+	if len(parts) == 0 {
+		return "模型配置无变化"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ReloadAdapters 比较新旧模型配置，重建发生变化的适配器。
+// 被移除或被替换的适配器实例会先从注册表移除再调用 Close。
+func ReloadAdapters(oldModels, newModels map[string]cfg.ModelConfig) ReloadResult {
+	result := ReloadResult{Failed: make(map[string]error)}
+
+	// 移除已删除的模型
+	for _, name := range sortedModelNames(oldModels) {
+		if _, exists := newModels[name]; exists {
+			continue
+		}
+		if err := closeAndRemoveAdapter(name); err != nil {
+			result.Failed[name] = err
+			continue
+		}
+		result.Removed = append(result.Removed, name)
+	}
+
+	// 新增或重建变更的模型
+	for _, name := range sortedModelNames(newModels) {
+		newCfg := newModels[name]
+		oldCfg, existed := oldModels[name]
+		_, running := GetAdapter(name)
+
+		if existed && running && oldCfg == newCfg {
+			continue
+		}
+
+		if running {
+			if err := closeAndRemoveAdapter(name); err != nil {
+				result.Failed[name] = err
+				continue
+			}
+		}
+
+		if _, err := CreateAdapter(name, newCfg.Type, newCfg); err != nil {
+			util.Warnw(fmt.Sprintf("重建 AI 适配器 '%s' 失败", name), map[string]interface{}{"error": err})
+			result.Failed[name] = err
+			continue
+		}
+
+		if existed && running {
+			result.Updated = append(result.Updated, name)
+		} else {
+			result.Added = append(result.Added, name)
+		}
+	}
+
+	util.Infow("模型配置已重新加载", map[string]interface{}{
+		"added":   result.Added,
+		"updated": result.Updated,
+		"removed": result.Removed,
+		"failed":  len(result.Failed),
+	})
+
+	return result
+}
+
+// closeAndRemoveAdapter 从注册表移除适配器并关闭旧实例
+func closeAndRemoveAdapter(name string) error {
+	adapter, exists := GetAdapter(name)
+	if !exists {
+		return nil
+	}
+	if err := RemoveAdapter(name); err != nil {
+		return err
+	}
+	if err := adapter.Close(); err != nil {
+		util.Warnw(fmt.Sprintf("关闭 AI 适配器 '%s' 失败", name), map[string]interface{}{"error": err})
+	}
+	return nil
+}
+
+// sortedModelNames 返回排序后的模型名称，保证重载顺序稳定
+func sortedModelNames(models map[string]cfg.ModelConfig) []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
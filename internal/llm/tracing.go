@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"context"
+
+	"ai-ops/internal/tools"
+	"ai-ops/internal/tracing"
+)
+
+// startSendSpan 为一次模型请求开始追踪跨度
+func startSendSpan(ctx context.Context, info ModelInfo, messages []Message, toolDefs []tools.ToolDefinition, toolChoice ToolChoice) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "llm.send_message", tracing.SpanKindClient)
+	span.SetAttributes(map[string]any{
+		"llm.provider":    info.Type,
+		"llm.model":       info.Name,
+		"llm.messages":    len(messages),
+		"llm.tools":       len(toolDefs),
+		"llm.tool_choice": toolChoice.String(),
+	})
+	return ctx, span
+}
+
+// endSendSpan 记录模型响应的令牌与工具调用信息并结束跨度
+func endSendSpan(span *tracing.Span, resp *Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+	} else if resp != nil {
+		span.SetAttributes(map[string]any{
+			"llm.usage.prompt_tokens":     resp.Usage.PromptTokens,
+			"llm.usage.completion_tokens": resp.Usage.CompletionTokens,
+			"llm.usage.total_tokens":      resp.Usage.TotalTokens,
+			"llm.finish_reason":           resp.FinishReason,
+			"llm.tool_calls":              len(resp.ToolCalls),
+		})
+	}
+	span.End()
+}
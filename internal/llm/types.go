@@ -41,3 +41,58 @@ type ModelInfo struct {
 
 // ClientManager has been deprecated and will be removed.
 // All adapter lifecycle management is now handled by the llmRegistry.
+
+// ToolChoiceMode 工具选择模式
+type ToolChoiceMode string
+
+const (
+	// ToolChoiceAuto 由模型自行决定是否调用工具
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	// ToolChoiceNone 禁止调用工具，模型必须直接回答
+	ToolChoiceNone ToolChoiceMode = "none"
+	// ToolChoiceRequired 模型必须调用至少一个工具
+	ToolChoiceRequired ToolChoiceMode = "required"
+	// ToolChoiceTool 强制调用指定名称的工具
+	ToolChoiceTool ToolChoiceMode = "tool"
+)
+
+// ToolChoice 与提供商无关的工具选择设置，零值等价于 auto
+type ToolChoice struct {
+	Mode ToolChoiceMode `json:"mode"`
+	Name string         `json:"name,omitempty"` // 仅 Mode 为 tool 时有效
+}
+
+// ParseToolChoice 解析工具选择字符串：auto/none/required，其余视为工具名称
+func ParseToolChoice(value string) ToolChoice {
+	switch ToolChoiceMode(value) {
+	case "", ToolChoiceAuto:
+		return ToolChoice{Mode: ToolChoiceAuto}
+	case ToolChoiceNone:
+		return ToolChoice{Mode: ToolChoiceNone}
+	case ToolChoiceRequired:
+		return ToolChoice{Mode: ToolChoiceRequired}
+	default:
+		return ToolChoice{Mode: ToolChoiceTool, Name: value}
+	}
+}
+
+// IsAuto 是否为自动模式
+func (c ToolChoice) IsAuto() bool {
+	return c.Mode == "" || c.Mode == ToolChoiceAuto
+}
+
+// IsForced 是否强制模型调用工具
+func (c ToolChoice) IsForced() bool {
+	return c.Mode == ToolChoiceRequired || c.Mode == ToolChoiceTool
+}
+
+// String 返回工具选择的可读表示
+func (c ToolChoice) String() string {
+	if c.Mode == ToolChoiceTool {
+		return c.Name
+	}
+	if c.Mode == "" {
+		return string(ToolChoiceAuto)
+	}
+	return string(c.Mode)
+}
@@ -176,6 +176,13 @@ func (m *DefaultMCPManager) GetClient(name string) (*mcp.ClientSession, bool) {
 	return session, exists
 }
 
+// GetSettings 获取已加载的MCP配置
+func (m *DefaultMCPManager) GetSettings() *MCPSettings {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.settings
+}
+
 // Shutdown 关闭所有客户端
 func (m *DefaultMCPManager) Shutdown() error {
 	m.mutex.Lock()
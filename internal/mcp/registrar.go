@@ -33,6 +33,7 @@ func (r *MCPToolRegistrar) RegisterTools(ctx context.Context) error {
 	util.Debugw("开始注册MCP工具", nil)
 
 	sessions := r.manager.GetClients()
+	settings := r.manager.GetSettings()
 	totalTools := 0
 
 	for serverName, session := range sessions {
@@ -46,9 +47,15 @@ func (r *MCPToolRegistrar) RegisterTools(ctx context.Context) error {
 			continue
 		}
 
+		var serverConfig MCPServerConfig
+		if settings != nil {
+			serverConfig = settings.MCPServers[serverName]
+		}
+
 		// 注册每个工具
 		for _, toolInfo := range result.Tools {
-			mcpTool := NewMCPTool(serverName, session, toolInfo, r.timeout)
+			risk := resolveToolRisk(serverName, serverConfig, toolInfo)
+			mcpTool := NewMCPTool(serverName, session, toolInfo, r.timeout, risk)
 
 			if err := r.toolManager.RegisterTool(mcpTool); err != nil {
 				wrappedErr := errors.WrapErrorWithDetails(errors.ErrCodeMCPToolListFailed,
@@ -64,6 +71,7 @@ func (r *MCPToolRegistrar) RegisterTools(ctx context.Context) error {
 				"server_name": serverName,
 				"tool_name":   toolInfo.Name,
 				"full_name":   mcpTool.Name(),
+				"risk":        risk,
 			})
 		}
 	}
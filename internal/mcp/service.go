@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"ai-ops/internal/metrics"
 	"ai-ops/internal/tools"
 	"ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
@@ -96,11 +98,19 @@ func (s *MCPService) GetConnectedServers() []string {
 	return servers
 }
 
-// GetServerStatus 获取服务器状态信息
+// GetServerStatus 获取服务器状态信息，包含已配置但未连接的服务器
 func (s *MCPService) GetServerStatus() map[string]bool {
 	sessions := s.manager.GetClients()
 	status := make(map[string]bool)
 
+	if settings := s.manager.GetSettings(); settings != nil {
+		for serverName, config := range settings.MCPServers {
+			if !config.Disabled {
+				status[serverName] = false
+			}
+		}
+	}
+
 	for serverName := range sessions {
 		status[serverName] = true // 如果存在于sessions中，则视为已连接
 	}
@@ -108,6 +118,27 @@ func (s *MCPService) GetServerStatus() map[string]bool {
 	return status
 }
 
+// CollectMetrics 将MCP服务器连接状态写入指标输出
+func (s *MCPService) CollectMetrics(w *metrics.Writer) {
+	status := s.GetServerStatus()
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	connected := 0
+	for _, name := range names {
+		value := 0.0
+		if status[name] {
+			value = 1
+			connected++
+		}
+		w.Gauge("mcp_server_up", "MCP服务器连接状态（1 已连接，0 未连接）", metrics.Labels{"server": name}, value)
+	}
+	w.Gauge("mcp_servers_connected", "已连接的MCP服务器数量", nil, float64(connected))
+}
+
 // isConfigFileExists 检查配置文件是否存在
 func (s *MCPService) isConfigFileExists() bool {
 	if s.configPath == "" {
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"ai-ops/internal/tools"
+	"ai-ops/internal/tracing"
 	"ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 
@@ -13,15 +15,46 @@ import (
 )
 
 // NewMCPTool 创建新的MCP工具包装器
-func NewMCPTool(serverName string, session *mcp.ClientSession, toolInfo *mcp.Tool, timeout time.Duration) *MCPTool {
+func NewMCPTool(serverName string, session *mcp.ClientSession, toolInfo *mcp.Tool, timeout time.Duration, risk tools.RiskLevel) *MCPTool {
 	return &MCPTool{
 		serverName: serverName,
 		session:    session,
 		toolInfo:   toolInfo,
 		timeout:    timeout,
+		risk:       risk,
 	}
 }
 
+// resolveToolRisk 根据服务器配置和工具注解确定风险等级。
+// 优先级：toolRisk 单独配置 > defaultRisk > 服务器声明的注解 > mutating
+func resolveToolRisk(serverName string, cfg MCPServerConfig, toolInfo *mcp.Tool) tools.RiskLevel {
+	for _, value := range []string{cfg.ToolRisk[toolInfo.Name], cfg.DefaultRisk} {
+		if value == "" {
+			continue
+		}
+		level, err := tools.ParseRiskLevel(value)
+		if err != nil {
+			util.Warnw("MCP工具风险等级配置无效，已忽略", map[string]any{
+				"server_name": serverName,
+				"tool_name":   toolInfo.Name,
+				"risk":        value,
+			})
+			continue
+		}
+		return level
+	}
+
+	if annotations := toolInfo.Annotations; annotations != nil {
+		if annotations.ReadOnlyHint {
+			return tools.RiskReadOnly
+		}
+		if annotations.DestructiveHint != nil && *annotations.DestructiveHint {
+			return tools.RiskDestructive
+		}
+	}
+	return tools.RiskMutating
+}
+
 // Name 获取工具名称
 func (t *MCPTool) Name() string {
 	return fmt.Sprintf("%s_%s", t.serverName, t.toolInfo.Name)
@@ -42,6 +75,11 @@ func (t *MCPTool) Type() string {
 	return "mcp"
 }
 
+// RiskLevel 获取工具风险等级
+func (t *MCPTool) RiskLevel() tools.RiskLevel {
+	return t.risk
+}
+
 // Parameters 获取工具参数schema
 func (t *MCPTool) Parameters() map[string]any {
 	if t.toolInfo.InputSchema == nil {
@@ -109,7 +147,19 @@ func fixSchema(node map[string]any) {
 }
 
 // Execute 执行工具
-func (t *MCPTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+func (t *MCPTool) Execute(ctx context.Context, args map[string]any) (output string, err error) {
+	ctx, span := tracing.Start(ctx, "mcp.call_tool", tracing.SpanKindClient)
+	defer func() {
+		span.RecordError(err)
+		span.SetAttribute("tool.result_length", len(output))
+		span.End()
+	}()
+	span.SetAttributes(map[string]any{
+		"mcp.server": t.serverName,
+		"mcp.tool":   t.toolInfo.Name,
+		"tool.name":  t.Name(),
+	})
+
 	// 设置超时
 	if t.timeout > 0 {
 		var cancel context.CancelFunc
@@ -128,6 +178,10 @@ func (t *MCPTool) Execute(ctx context.Context, args map[string]any) (string, err
 		Name:      t.toolInfo.Name,
 		Arguments: args,
 	}
+	// stdio 传输没有请求头，追踪上下文通过 _meta.traceparent 传递给 MCP 服务器
+	if traceparent := tracing.Traceparent(ctx); traceparent != "" {
+		params.Meta = mcp.Meta{tracing.TraceparentHeader: traceparent}
+	}
 
 	result, err := t.session.CallTool(ctx, params)
 	if err != nil {
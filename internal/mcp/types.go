@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"ai-ops/internal/tools"
 	"ai-ops/internal/util/errors"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -18,6 +19,10 @@ type MCPServerConfig struct {
 	Args             []string          `json:"args"`
 	Env              map[string]string `json:"env,omitempty"`
 	FilterNonJSONRPC bool              `json:"filterNonJSONRPC,omitempty"` // 是否过滤非JSON-RPC输出
+	// DefaultRisk 未在 ToolRisk 中配置的工具的风险等级（read_only/mutating/destructive），
+	// 为空时参考服务器声明的工具注解，仍无法判断时按 mutating 处理
+	DefaultRisk string            `json:"defaultRisk,omitempty"`
+	ToolRisk    map[string]string `json:"toolRisk,omitempty"` // 按工具名称（不含服务器前缀）配置风险等级
 }
 
 // MCPSettings MCP配置文件结构
@@ -39,6 +44,9 @@ type MCPManager interface {
 	// GetClient 根据名称获取客户端
 	GetClient(name string) (*mcp.ClientSession, bool)
 
+	// GetSettings 获取已加载的MCP配置，未加载时返回nil
+	GetSettings() *MCPSettings
+
 	// Shutdown 关闭所有客户端
 	Shutdown() error
 }
@@ -123,4 +131,5 @@ type MCPTool struct {
 	session    *mcp.ClientSession
 	toolInfo   *mcp.Tool
 	timeout    time.Duration
+	risk       tools.RiskLevel
 }
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标名称前缀
+const namespace = "ai_ops"
+
+// Labels 指标标签
+type Labels map[string]string
+
+// Collector 在每次抓取时向 Writer 写入当前指标值
+type Collector func(w *Writer)
+
+// Registry 指标收集器注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// DefaultRegistry 全局默认的指标注册表
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 创建新的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register 注册收集器，同名收集器会被替换
+func (r *Registry) Register(name string, collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[name] = collector
+}
+
+// Unregister 移除收集器
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.collectors, name)
+}
+
+// Gather 执行所有收集器并返回 Prometheus 文本格式的指标
+func (r *Registry) Gather() string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	w := newWriter()
+	for _, collect := range collectors {
+		collect(w)
+	}
+	return w.String()
+}
+
+// Register 向默认注册表注册收集器
+func Register(name string, collector Collector) {
+	DefaultRegistry.Register(name, collector)
+}
+
+// family 同名指标的集合
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []string
+}
+
+// Writer 按指标名称聚合样本，保证输出符合 Prometheus 文本格式
+type Writer struct {
+	families map[string]*family
+	order    []string
+}
+
+func newWriter() *Writer {
+	return &Writer{families: make(map[string]*family)}
+}
+
+// Counter 写入计数器样本
+func (w *Writer) Counter(name, help string, labels Labels, value float64) {
+	fullName := metricName(name)
+	w.family(fullName, help, "counter").add(fullName, labels, value)
+}
+
+// Gauge 写入仪表盘样本
+func (w *Writer) Gauge(name, help string, labels Labels, value float64) {
+	fullName := metricName(name)
+	w.family(fullName, help, "gauge").add(fullName, labels, value)
+}
+
+// Histogram 写入直方图样本（_bucket/_sum/_count）
+func (w *Writer) Histogram(name, help string, labels Labels, snapshot HistogramSnapshot) {
+	fullName := metricName(name)
+	f := w.family(fullName, help, "histogram")
+
+	var cumulative uint64
+	for i, upper := range snapshot.Buckets {
+		cumulative += snapshot.Counts[i]
+		f.add(fullName+"_bucket", withLabel(labels, "le", formatFloat(upper)), float64(cumulative))
+	}
+	f.add(fullName+"_bucket", withLabel(labels, "le", "+Inf"), float64(snapshot.Count))
+	f.add(fullName+"_sum", labels, snapshot.Sum)
+	f.add(fullName+"_count", labels, float64(snapshot.Count))
+}
+
+// String 渲染为 Prometheus 文本格式
+func (w *Writer) String() string {
+	var b strings.Builder
+	for _, name := range w.order {
+		f := w.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		for _, sample := range f.samples {
+			b.WriteString(sample)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func (w *Writer) family(name, help, kind string) *family {
+	if f, ok := w.families[name]; ok {
+		return f
+	}
+	f := &family{name: name, help: help, kind: kind}
+	w.families[name] = f
+	w.order = append(w.order, name)
+	return f
+}
+
+func (f *family) add(name string, labels Labels, value float64) {
+	f.samples = append(f.samples, name+formatLabels(labels)+" "+formatFloat(value))
+}
+
+// metricName 为指标名称添加命名空间前缀
+func metricName(name string) string {
+	return namespace + "_" + name
+}
+
+// withLabel 复制标签并追加一个标签
+func withLabel(labels Labels, key, value string) Labels {
+	out := make(Labels, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// formatLabels 按键排序渲染标签
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+func escapeHelp(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
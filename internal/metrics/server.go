@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// Server 指标 HTTP 监听器
+type Server struct {
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// Handler 返回输出默认注册表指标的 HTTP 处理器
+func Handler() http.Handler {
+	return HandlerFor(DefaultRegistry)
+}
+
+// HandlerFor 返回输出指定注册表指标的 HTTP 处理器
+func HandlerFor(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(registry.Gather()))
+	})
+}
+
+// StartServer 在后台启动指标监听器
+func StartServer(listenAddr, path string) (*Server, error) {
+	if path == "" {
+		path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, errors.HTTPMiddleware(Handler()))
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, errors.WrapErrorWithDetails(errors.ErrCodeInitializationFailed,
+			"指标监听器启动失败", err, "监听地址: "+listenAddr)
+	}
+
+	s := &Server{
+		httpServer: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		listener: listener,
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			util.Warnw("指标监听器异常退出", map[string]interface{}{"error": err.Error()})
+		}
+	}()
+
+	util.Infow("指标监听器已启动", map[string]interface{}{
+		"address": listener.Addr().String(),
+		"path":    path,
+	})
+	return s, nil
+}
+
+// Addr 返回实际监听地址
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Shutdown 关闭指标监听器
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
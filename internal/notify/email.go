@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/util/errors"
+)
+
+// emailSink 通过 SMTP 发送邮件，正文包含纯文本（Markdown 原文）与 HTML 两种格式
+type emailSink struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+	tls      bool
+}
+
+func newEmailSink(channel config.NotifyChannel) (Sink, error) {
+	port := channel.Port
+	if port == 0 {
+		port = 587
+		if channel.TLS {
+			port = 465
+		}
+	}
+	return &emailSink{
+		host:     channel.Host,
+		port:     port,
+		username: channel.Username,
+		password: channel.Password,
+		from:     channel.From,
+		to:       channel.To,
+		tls:      channel.TLS,
+	}, nil
+}
+
+// Send 发送邮件，标题为邮件主题
+func (s *emailSink) Send(ctx context.Context, msg Message) error {
+	subject := msg.Title
+	if subject == "" {
+		subject = "ai-ops 通知"
+	}
+	content, err := s.compose(subject, msg)
+	if err != nil {
+		return permanent(errors.WrapError(errors.ErrCodeInternalErr, "生成邮件失败", err))
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "连接 SMTP 服务器失败", err)
+	}
+	defer client.Close()
+
+	if err := s.deliver(client, content); err != nil {
+		// 服务器的 5xx 回复（认证失败、收件人被拒绝等）重试无效
+		var reply *textproto.Error
+		if stderrors.As(err, &reply) && reply.Code >= 500 {
+			return permanent(errors.WrapError(errors.ErrCodeAPIRequestFailed, "发送邮件失败", err))
+		}
+		return errors.WrapError(errors.ErrCodeNetworkFailed, "发送邮件失败", err)
+	}
+	return nil
+}
+
+// dial 建立 SMTP 连接，超时由 ctx 控制
+func (s *emailSink) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if s.tls {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !s.tls {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
+
+// deliver 认证并发送邮件内容
+func (s *emailSink) deliver(client *smtp.Client, content []byte) error {
+	if s.username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose 生成 multipart/alternative 邮件
+func (s *emailSink) compose(subject string, msg Message) ([]byte, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	boundary := "ai-ops-" + hex.EncodeToString(buf)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	html := "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head><body>\n" + toHTML(msg.Content) + "</body></html>\n"
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Content},
+		{"text/html", html},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=UTF-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		writer := quotedprintable.NewWriter(&b)
+		if _, err := writer.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	mdHeadingPattern = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+?)[ \t]*#*$`)
+	mdBoldPattern    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdStrikePattern  = regexp.MustCompile(`~~(.+?)~~`)
+	mdLinkPattern    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdListPattern    = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdFencePattern   = regexp.MustCompile("(?m)^```[A-Za-z0-9_+-]*\\s*$\n?")
+)
+
+// mapOutsideCode 只对代码块以外的部分应用转换，代码块原样保留
+func mapOutsideCode(markdown string, convert func(string) string) string {
+	var b strings.Builder
+	inCode := false
+	var text strings.Builder
+	flush := func() {
+		b.WriteString(convert(text.String()))
+		text.Reset()
+	}
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inCode {
+				flush()
+			}
+			inCode = !inCode
+			b.WriteString(line)
+			continue
+		}
+		if inCode {
+			b.WriteString(line)
+		} else {
+			text.WriteString(line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// ToSlack 将 Markdown 转换为 Slack mrkdwn：标题与加粗使用 *text*，链接使用 <url|text>
+func ToSlack(markdown string) string {
+	return mapOutsideCode(markdown, func(text string) string {
+		text = mdHeadingPattern.ReplaceAllString(text, "**$1**")
+		text = mdListPattern.ReplaceAllString(text, "$1• ")
+		text = mdBoldPattern.ReplaceAllString(text, "*$1$2*")
+		text = mdStrikePattern.ReplaceAllString(text, "~$1~")
+		text = mdLinkPattern.ReplaceAllString(text, "<$2|$1>")
+		return text
+	})
+}
+
+// ToFeishu 转换为飞书卡片的 Markdown：卡片不支持标题语法，标题改为加粗
+func ToFeishu(markdown string) string {
+	return mapOutsideCode(markdown, func(text string) string {
+		return mdHeadingPattern.ReplaceAllString(text, "**$1**")
+	})
+}
+
+// ToDingTalk 转换为钉钉的 Markdown：钉钉不渲染代码块，去掉围栏后按普通文本显示
+func ToDingTalk(markdown string) string {
+	return mdFencePattern.ReplaceAllString(markdown, "")
+}
+
+// notifyMarkdownRenderer 邮件正文的 Markdown 渲染器，原始 HTML 不输出
+var notifyMarkdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// toHTML 将 Markdown 渲染为邮件使用的 HTML，失败时按预格式化文本输出
+func toHTML(markdown string) string {
+	var buf bytes.Buffer
+	if err := notifyMarkdownRenderer.Convert([]byte(markdown), &buf); err != nil {
+		return "<pre>" + template.HTMLEscapeString(markdown) + "</pre>"
+	}
+	return buf.String()
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 未设置时使用的发送参数
+const (
+	defaultTimeout    = 15 * time.Second
+	defaultRetryDelay = 2 * time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// Message 一条通知消息
+type Message struct {
+	Title   string    // 消息标题
+	Content string    // Markdown 正文，发送时按渠道转换格式
+	Source  string    // 消息来源，如 chat、agent、task:disk-check
+	Time    time.Time // 产生时间
+}
+
+// Sink 通知渠道的发送实现
+type Sink interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SinkFactory 根据渠道配置创建发送实现，配置中的 ${VAR} 已替换为环境变量
+type SinkFactory func(channel config.NotifyChannel) (Sink, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]SinkFactory{
+		"slack":    newSlackSink,
+		"dingtalk": newDingTalkSink,
+		"feishu":   newFeishuSink,
+		"webhook":  newWebhookSink,
+		"email":    newEmailSink,
+	}
+)
+
+// RegisterSink 注册通知渠道类型，已存在的类型被替换
+func RegisterSink(kind string, factory SinkFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[kind] = factory
+}
+
+// channel 一个已配置的通知渠道
+type channel struct {
+	kind     string
+	sink     Sink
+	template *template.Template // nil 表示直接发送正文
+}
+
+// Notifier 按名称向已配置的渠道发送通知，失败时按退避时间重试
+type Notifier struct {
+	channels   map[string]*channel
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+// NewFromConfig 根据配置创建通知器，未配置渠道时返回的通知器没有可用渠道
+func NewFromConfig(cfg config.NotifyConfig) (*Notifier, error) {
+	n := &Notifier{
+		channels:   make(map[string]*channel, len(cfg.Channels)),
+		timeout:    time.Duration(cfg.Timeout) * time.Second,
+		retries:    cfg.Retries,
+		retryDelay: time.Duration(cfg.RetryDelay) * time.Second,
+	}
+	if n.timeout <= 0 {
+		n.timeout = defaultTimeout
+	}
+	if n.retryDelay <= 0 {
+		n.retryDelay = defaultRetryDelay
+	}
+
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	for name, cfgChannel := range cfg.Channels {
+		factory, ok := factories[cfgChannel.Type]
+		if !ok {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid,
+				"不支持的通知渠道类型", name+": "+cfgChannel.Type)
+		}
+		sink, err := factory(expandChannel(cfgChannel))
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrCodeConfigInvalid, "通知渠道配置无效: "+name, err)
+		}
+		ch := &channel{kind: cfgChannel.Type, sink: sink}
+		if cfgChannel.Template != "" {
+			ch.template, err = template.New(name).Option("missingkey=zero").Parse(cfgChannel.Template)
+			if err != nil {
+				return nil, errors.WrapError(errors.ErrCodeConfigInvalid, "通知渠道的消息模板无效: "+name, err)
+			}
+		}
+		n.channels[name] = ch
+	}
+	return n, nil
+}
+
+// expandChannel 替换配置中的环境变量
+func expandChannel(channel config.NotifyChannel) config.NotifyChannel {
+	channel.URL = os.ExpandEnv(channel.URL)
+	channel.Secret = os.ExpandEnv(channel.Secret)
+	channel.Username = os.ExpandEnv(channel.Username)
+	channel.Password = os.ExpandEnv(channel.Password)
+	headers := make(map[string]string, len(channel.Headers))
+	for key, value := range channel.Headers {
+		headers[key] = os.ExpandEnv(value)
+	}
+	channel.Headers = headers
+	return channel
+}
+
+// Channels 返回已配置的渠道名称，按名称排序
+func (n *Notifier) Channels() []string {
+	names := make([]string, 0, len(n.channels))
+	for name := range n.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has 判断渠道是否已配置
+func (n *Notifier) Has(name string) bool {
+	_, ok := n.channels[name]
+	return ok
+}
+
+// Send 按渠道模板生成消息并发送，网络错误与服务端错误按退避时间重试
+func (n *Notifier) Send(ctx context.Context, name string, msg Message) error {
+	ch, ok := n.channels[name]
+	if !ok {
+		return errors.NewErrorWithDetails(errors.ErrCodeNotFound, "通知渠道不存在",
+			name+"（已配置: "+strings.Join(n.Channels(), ", ")+"）")
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if ch.template != nil {
+		var b strings.Builder
+		if err := ch.template.Execute(&b, msg); err != nil {
+			return errors.WrapError(errors.ErrCodeConfigInvalid, "生成通知消息失败: "+name, err)
+		}
+		msg.Content = b.String()
+	}
+
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		err := ch.sink.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			util.Infow("通知发送成功", map[string]any{"channel": name, "type": ch.kind, "attempts": attempt})
+			return nil
+		}
+		if attempt > n.retries || isPermanent(err) || ctx.Err() != nil {
+			return errors.WrapErrorWithDetails(errors.CodeOf(err), "发送通知失败", err, "渠道: "+name)
+		}
+
+		util.Warnw("通知发送失败，准备重试", map[string]any{
+			"channel": name,
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return errors.WrapErrorWithDetails(errors.ErrCodeTimeout, "发送通知失败", ctx.Err(), "渠道: "+name)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// permanentError 重试无效的错误，如请求被拒绝或平台返回的业务错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// permanent 标记错误不需要重试
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isPermanent 判断错误是否不需要重试
+func isPermanent(err error) bool {
+	var p *permanentError
+	return stderrors.As(err, &p)
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai-ops/internal/config"
+)
+
+// newTestNotifier 创建只有一个渠道的通知器，重试间隔缩短为 1 毫秒
+func newTestNotifier(t *testing.T, channel config.NotifyChannel, retries int) *Notifier {
+	t.Helper()
+	n, err := NewFromConfig(config.NotifyConfig{
+		Retries:  retries,
+		Channels: map[string]config.NotifyChannel{"test": channel},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	n.retryDelay = time.Millisecond
+	return n
+}
+
+// decodeBody 解析请求体中的 JSON
+func decodeBody(t *testing.T, r *http.Request) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("请求体不是有效的 JSON: %v", err)
+	}
+	return body
+}
+
+func TestDingTalkSign(t *testing.T) {
+	const secret = "SEC-test"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("access_token") != "abc" {
+			t.Errorf("access_token = %q，原有的查询参数应保留", query.Get("access_token"))
+		}
+		timestamp := query.Get("timestamp")
+		ms, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.UnixMilli(ms)).Abs() > time.Minute {
+			t.Errorf("timestamp = %q，应为当前的毫秒时间戳", timestamp)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "\n" + secret))
+		if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); query.Get("sign") != want {
+			t.Errorf("sign = %q, want %q", query.Get("sign"), want)
+		}
+
+		body := decodeBody(t, r)
+		markdown, _ := body["markdown"].(map[string]any)
+		if body["msgtype"] != "markdown" || markdown["title"] != "磁盘告警" {
+			t.Errorf("消息内容不正确: %v", body)
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	n := newTestNotifier(t, config.NotifyChannel{Type: "dingtalk", URL: server.URL + "/robot/send?access_token=abc", Secret: secret}, 0)
+	if err := n.Send(context.Background(), "test", Message{Title: "磁盘告警", Content: "使用率 95%"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestDingTalkRejectedNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer server.Close()
+
+	n := newTestNotifier(t, config.NotifyChannel{Type: "dingtalk", URL: server.URL, Secret: "wrong"}, 3)
+	if err := n.Send(context.Background(), "test", Message{Content: "hi"}); err == nil {
+		t.Fatal("钉钉返回业务错误时应发送失败")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("发送了 %d 次，业务错误不应重试", got)
+	}
+}
+
+func TestFeishuSign(t *testing.T) {
+	const secret = "feishu-secret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodeBody(t, r)
+		timestamp, _ := body["timestamp"].(string)
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > time.Minute {
+			t.Errorf("timestamp = %q，应为当前的秒级时间戳", timestamp)
+		}
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+		if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); body["sign"] != want {
+			t.Errorf("sign = %v, want %q", body["sign"], want)
+		}
+
+		card, _ := body["card"].(map[string]any)
+		header, _ := card["header"].(map[string]any)
+		title, _ := header["title"].(map[string]any)
+		if body["msg_type"] != "interactive" || title["content"] != "磁盘告警" {
+			t.Errorf("消息卡片不正确: %v", body)
+		}
+		_, _ = w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer server.Close()
+
+	n := newTestNotifier(t, config.NotifyChannel{Type: "feishu", URL: server.URL, Secret: secret}, 0)
+	if err := n.Send(context.Background(), "test", Message{Title: "磁盘告警", Content: "**使用率** 95%"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // 依次返回的状态码，用完后返回最后一个
+		retries      int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "5xx 后成功", statuses: []int{500, 502, 200}, retries: 2, wantAttempts: 3},
+		{name: "429 重试", statuses: []int{429, 200}, retries: 2, wantAttempts: 2},
+		{name: "4xx 不重试", statuses: []int{400}, retries: 3, wantAttempts: 1, wantErr: true},
+		{name: "重试次数用完", statuses: []int{503}, retries: 2, wantAttempts: 3, wantErr: true},
+		{name: "不重试", statuses: []int{500}, retries: 0, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			n := newTestNotifier(t, config.NotifyChannel{Type: "webhook", URL: server.URL}, tt.retries)
+			err := n.Send(context.Background(), "test", Message{Content: "hi"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("发送了 %d 次, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- decodeBody(t, r)
+	}))
+	defer server.Close()
+
+	n := newTestNotifier(t, config.NotifyChannel{
+		Type:     "webhook",
+		URL:      server.URL,
+		Template: `[{{ .Source }}] {{ .Title }} {{ .Time.Format "2006-01-02" }}: {{ .Content }}`,
+	}, 0)
+	msg := Message{
+		Title:   "磁盘告警",
+		Content: "使用率 95%",
+		Source:  "task:disk-check",
+		Time:    time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+	}
+	if err := n.Send(context.Background(), "test", msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	body := <-received
+	if want := "[task:disk-check] 磁盘告警 2026-10-18: 使用率 95%"; body["text"] != want {
+		t.Errorf("text = %q, want %q", body["text"], want)
+	}
+	if body["title"] != "磁盘告警" || body["source"] != "task:disk-check" || body["time"] != "2026-10-18T09:30:00Z" {
+		t.Errorf("消息字段不正确: %v", body)
+	}
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	_, err := NewFromConfig(config.NotifyConfig{Channels: map[string]config.NotifyChannel{
+		"test": {Type: "webhook", URL: "https://hooks.example.com", Template: "{{ .Title "},
+	}})
+	if err == nil {
+		t.Fatal("无效的模板应在加载配置时报错")
+	}
+}
+
+func TestInvalidURL(t *testing.T) {
+	t.Setenv("AI_OPS_TEST_EMPTY_URL", "")
+	for _, kind := range []string{"slack", "dingtalk", "feishu", "webhook"} {
+		for _, url := range []string{"", "${AI_OPS_TEST_EMPTY_URL}", "hooks.example.com/path", "ftp://hooks.example.com"} {
+			_, err := NewFromConfig(config.NotifyConfig{Channels: map[string]config.NotifyChannel{
+				"test": {Type: kind, URL: url},
+			}})
+			if err == nil {
+				t.Errorf("%s 渠道的 url %q 应在加载配置时报错", kind, url)
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-ops/internal/config"
+	"ai-ops/internal/util/errors"
+)
+
+// 读取的响应体上限
+const maxResponseBytes = 64 * 1024
+
+// postJSON 以 POST 发送 JSON，返回响应体。
+// 网络错误、429 与 5xx 可以重试，其余非 2xx 状态码不重试。
+func postJSON(ctx context.Context, target string, headers map[string]string, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, permanent(errors.WrapError(errors.ErrCodeInternalErr, "序列化通知消息失败", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanent(errors.WrapError(errors.ErrCodeConfigInvalid, "创建通知请求失败", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrCodeNetworkFailed, "通知请求失败", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode >= 300 {
+		err := errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed,
+			fmt.Sprintf("通知服务返回状态码 %d", resp.StatusCode), strings.TrimSpace(string(data)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, err
+		}
+		return nil, permanent(err)
+	}
+	return data, nil
+}
+
+// checkURL 检查渠道地址，配置引用的环境变量未设置时地址为空，在加载配置时报错而不是等到发送时
+func checkURL(channel config.NotifyChannel) error {
+	if channel.URL == "" {
+		return errors.NewError(errors.ErrCodeConfigInvalid, "未配置 url 或 url 引用的环境变量为空")
+	}
+	u, err := url.Parse(channel.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// 地址中可能包含 access_token，不在错误中输出
+		return errors.NewError(errors.ErrCodeConfigInvalid, "url 不是有效的 http(s) 地址")
+	}
+	return nil
+}
+
+// withTitle 将标题作为一级标题加在正文前
+func withTitle(msg Message) string {
+	if msg.Title == "" {
+		return msg.Content
+	}
+	return "# " + msg.Title + "\n\n" + msg.Content
+}
+
+// slackSink Slack incoming webhook
+type slackSink struct {
+	url string
+}
+
+func newSlackSink(channel config.NotifyChannel) (Sink, error) {
+	if err := checkURL(channel); err != nil {
+		return nil, err
+	}
+	return &slackSink{url: channel.URL}, nil
+}
+
+// Send 发送 mrkdwn 格式的文本消息
+func (s *slackSink) Send(ctx context.Context, msg Message) error {
+	_, err := postJSON(ctx, s.url, nil, map[string]any{"text": ToSlack(withTitle(msg))})
+	return err
+}
+
+// dingTalkSink 钉钉自定义机器人，配置 secret 时使用加签
+type dingTalkSink struct {
+	url    string
+	secret string
+}
+
+func newDingTalkSink(channel config.NotifyChannel) (Sink, error) {
+	if err := checkURL(channel); err != nil {
+		return nil, err
+	}
+	return &dingTalkSink{url: channel.URL, secret: channel.Secret}, nil
+}
+
+// Send 发送 markdown 消息
+func (s *dingTalkSink) Send(ctx context.Context, msg Message) error {
+	target := s.url
+	if s.secret != "" {
+		// 签名为 HmacSHA256(secret, timestamp + "\n" + secret) 的 Base64，时间戳为毫秒
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write([]byte(timestamp + "\n" + s.secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		target = appendQuery(target, url.Values{"timestamp": {timestamp}, "sign": {sign}})
+	}
+
+	title := msg.Title
+	if title == "" {
+		title = "ai-ops 通知"
+	}
+	data, err := postJSON(ctx, target, nil, map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": title, "text": ToDingTalk(withTitle(msg))},
+	})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(data, &result); err == nil && result.ErrCode != 0 {
+		// 签名错误、关键词不匹配等业务错误重试无效
+		return permanent(errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "钉钉机器人拒绝了消息",
+			fmt.Sprintf("errcode=%d, errmsg=%s", result.ErrCode, result.ErrMsg)))
+	}
+	return nil
+}
+
+// feishuSink 飞书自定义机器人，以消息卡片发送，配置 secret 时使用签名校验
+type feishuSink struct {
+	url    string
+	secret string
+}
+
+func newFeishuSink(channel config.NotifyChannel) (Sink, error) {
+	if err := checkURL(channel); err != nil {
+		return nil, err
+	}
+	return &feishuSink{url: channel.URL, secret: channel.Secret}, nil
+}
+
+// Send 发送包含标题与 Markdown 正文的消息卡片
+func (s *feishuSink) Send(ctx context.Context, msg Message) error {
+	title := msg.Title
+	if title == "" {
+		title = "ai-ops 通知"
+	}
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": title},
+				"template": "blue",
+			},
+			"elements": []map[string]string{{"tag": "markdown", "content": ToFeishu(msg.Content)}},
+		},
+	}
+	if s.secret != "" {
+		// 签名以 timestamp + "\n" + secret 为密钥对空内容计算 HmacSHA256，时间戳为秒
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+s.secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	data, err := postJSON(ctx, s.url, nil, payload)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(data, &result); err == nil && result.Code != 0 {
+		return permanent(errors.NewErrorWithDetails(errors.ErrCodeAPIRequestFailed, "飞书机器人拒绝了消息",
+			fmt.Sprintf("code=%d, msg=%s", result.Code, result.Msg)))
+	}
+	return nil
+}
+
+// webhookSink 通用 JSON webhook
+type webhookSink struct {
+	url     string
+	headers map[string]string
+}
+
+func newWebhookSink(channel config.NotifyChannel) (Sink, error) {
+	if err := checkURL(channel); err != nil {
+		return nil, err
+	}
+	return &webhookSink{url: channel.URL, headers: channel.Headers}, nil
+}
+
+// Send 发送 {"title", "text", "source", "time"}，text 为 Markdown 正文
+func (s *webhookSink) Send(ctx context.Context, msg Message) error {
+	_, err := postJSON(ctx, s.url, s.headers, map[string]any{
+		"title":  msg.Title,
+		"text":   msg.Content,
+		"source": msg.Source,
+		"time":   msg.Time.Format(time.RFC3339),
+	})
+	return err
+}
+
+// appendQuery 在地址已有的查询参数后追加参数
+func appendQuery(target string, values url.Values) string {
+	separator := "?"
+	if strings.Contains(target, "?") {
+		separator = "&"
+	}
+	return target + separator + values.Encode()
+}
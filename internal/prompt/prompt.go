@@ -0,0 +1,231 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// DefaultProfile 内置提示词模板的名称，模板目录中的同名模板会覆盖内置模板
+const DefaultProfile = "default"
+
+// 模板文件扩展名
+const templateExt = ".tmpl"
+
+//go:embed builtin/*.tmpl
+var builtinTemplates embed.FS
+
+// Tool 模板中可引用的工具信息
+type Tool struct {
+	Name        string
+	Description string
+}
+
+// Data 渲染系统提示词时可用的模板变量
+type Data struct {
+	Mode             string            // 对话模式：chat 或 agent
+	Model            string            // 实际使用的模型名称
+	Hostname         string            // 主机名
+	OS               string            // 操作系统与架构，如 linux/amd64
+	User             string            // 当前用户名
+	Date             string            // 当前日期，如 2025-01-02
+	Tools            []Tool            // 会话中启用的工具
+	ToolDescriptions string            // 每行一个工具的简要描述，如 "- sysinfo: 查看系统信息"
+	Vars             map[string]string // [prompts.vars] 中的自定义变量
+}
+
+// NewData 生成模板变量，主机名、操作系统、用户与日期从当前环境获取
+func NewData(mode, model string, tools []Tool, vars map[string]string) Data {
+	hostname, _ := os.Hostname()
+	data := Data{
+		Mode:     mode,
+		Model:    model,
+		Hostname: hostname,
+		OS:       runtime.GOOS + "/" + runtime.GOARCH,
+		User:     currentUser(),
+		Date:     time.Now().Format("2006-01-02"),
+		Tools:    tools,
+		Vars:     vars,
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+
+	descriptions := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		descriptions = append(descriptions, "- "+tool.Name+": "+summarize(tool.Description, 80))
+	}
+	data.ToolDescriptions = strings.Join(descriptions, "\n")
+	return data
+}
+
+// currentUser 返回当前用户名
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// summarize 取描述的第一行并截断到指定字符数
+func summarize(text string, maxRunes int) string {
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes]) + "..."
+}
+
+// Profile 可用的提示词模板
+type Profile struct {
+	Name   string
+	Source string // 模板来源：内置或文件路径
+}
+
+// Library 提示词模板库。
+// 模板目录中的 <profile>.tmpl 用于所有模式，<profile>/<mode>.tmpl 只用于对应模式，
+// 目录形式的模板缺少某个模式时使用内置模板。
+type Library struct {
+	dir string
+}
+
+// NewLibrary 创建模板库，dir 为空时使用默认目录
+func NewLibrary(dir string) *Library {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	return &Library{dir: dir}
+}
+
+// Builtin 只包含内置模板的模板库
+func Builtin() *Library {
+	return &Library{}
+}
+
+// DefaultDir 默认的模板目录 ~/.ai-ops/prompts
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ai-ops", "prompts")
+	}
+	return filepath.Join(homeDir, ".ai-ops", "prompts")
+}
+
+// Dir 返回模板目录
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// Profiles 列出内置模板与模板目录中的模板
+func (l *Library) Profiles() ([]Profile, error) {
+	profiles := map[string]Profile{DefaultProfile: {Name: DefaultProfile, Source: "内置"}}
+	if l.dir != "" {
+		entries, err := os.ReadDir(l.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取提示词模板目录失败", err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			switch {
+			case entry.IsDir():
+				profiles[name] = Profile{Name: name, Source: filepath.Join(l.dir, name)}
+			case strings.HasSuffix(name, templateExt):
+				name = strings.TrimSuffix(name, templateExt)
+				profiles[name] = Profile{Name: name, Source: filepath.Join(l.dir, entry.Name())}
+			}
+		}
+	}
+
+	result := make([]Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		result = append(result, profile)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Load 加载指定模式的模板，profile 为空时使用 default
+func (l *Library) Load(profile, mode string) (*template.Template, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if strings.ContainsAny(profile, `/\`) || strings.HasPrefix(profile, ".") {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters, "提示词模板名称无效", profile)
+	}
+
+	if l.dir != "" {
+		profileDir := filepath.Join(l.dir, profile)
+		if info, err := os.Stat(profileDir); err == nil && info.IsDir() {
+			path := filepath.Join(profileDir, mode+templateExt)
+			if _, err := os.Stat(path); err == nil {
+				return parseFile(path)
+			}
+			util.Debugw("提示词模板缺少当前模式，使用内置模板", map[string]any{"profile": profile, "mode": mode})
+			return loadBuiltin(mode)
+		}
+		path := filepath.Join(l.dir, profile+templateExt)
+		if _, err := os.Stat(path); err == nil {
+			return parseFile(path)
+		}
+	}
+
+	if profile == DefaultProfile {
+		return loadBuiltin(mode)
+	}
+	return nil, errors.NewErrorWithDetails(errors.ErrCodeNotFound, "提示词模板不存在",
+		"模板: "+profile+"，目录: "+l.dir)
+}
+
+// Render 渲染指定模式的系统提示词
+func (l *Library) Render(profile, mode string, data Data) (string, error) {
+	tmpl, err := l.Load(profile, mode)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.WrapError(errors.ErrCodeConfigInvalid, "渲染提示词模板失败", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// loadBuiltin 加载内置模板，未知模式使用 chat 模板
+func loadBuiltin(mode string) (*template.Template, error) {
+	content, err := builtinTemplates.ReadFile("builtin/" + mode + templateExt)
+	if err != nil {
+		content, err = builtinTemplates.ReadFile("builtin/chat" + templateExt)
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取内置提示词模板失败", err)
+		}
+	}
+	return parse("builtin/"+mode, string(content))
+}
+
+// parseFile 读取并解析模板文件
+func parseFile(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrCodeConfigLoadFailed, "读取提示词模板失败", err)
+	}
+	return parse(path, string(content))
+}
+
+// parse 解析模板，引用未定义的自定义变量时输出空字符串
+func parse(name, content string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigParseFailed, "解析提示词模板失败", err.Error())
+	}
+	return tmpl, nil
+}
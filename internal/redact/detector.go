@@ -0,0 +1,81 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"ai-ops/internal/util/errors"
+)
+
+// secretGroup 检测器正则中用于标记敏感片段的命名分组，未定义时替换整个匹配
+const secretGroup = "secret"
+
+// Detector 敏感信息检测器
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// 内置检测器
+var builtinDetectors = map[string]string{
+	// AWS Access Key ID 与 Secret Access Key
+	"aws_key": `\b(?P<secret>(?:AKIA|ASIA)[0-9A-Z]{16})\b|(?i)aws_secret_access_key["']?\s*[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`,
+	// Authorization: Bearer xxx
+	"bearer_token": `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{8,}=*)`,
+	// PEM 格式私钥
+	"private_key": `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	// password=xxx、DB_PASSWORD="xxx"、"token": "xxx" 等键值对
+	"password": `(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)(?:[_-][A-Za-z0-9_]*)?["']?\s*[=:]\s*(?:"(?P<secret>[^"\n]+)"|'(?P<secret>[^'\n]+)'|(?P<secret>[^\s"',;&]+))`,
+	// 电子邮箱
+	"email": `\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`,
+}
+
+// BuiltinDetectorNames 返回所有内置检测器名称
+func BuiltinDetectorNames() []string {
+	names := make([]string, 0, len(builtinDetectors))
+	for name := range builtinDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinDetector 获取指定名称的内置检测器
+func BuiltinDetector(name string) (Detector, error) {
+	expr, ok := builtinDetectors[name]
+	if !ok {
+		return Detector{}, errors.NewErrorWithDetails(errors.ErrCodeInvalidConfig,
+			"未知的内置脱敏检测器", fmt.Sprintf("名称: %s，可选: %v", name, BuiltinDetectorNames()))
+	}
+	return NewDetector(name, expr)
+}
+
+// NewDetector 根据正则表达式创建检测器，正则中可用 (?P<secret>...) 只替换敏感片段
+func NewDetector(name, expr string) (Detector, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return Detector{}, errors.WrapErrorWithDetails(errors.ErrCodeInvalidConfig,
+			"脱敏规则正则表达式无效", err, fmt.Sprintf("名称: %s", name))
+	}
+	return Detector{Name: name, Pattern: pattern}, nil
+}
+
+// secretSpans 返回文本中所有需要替换的区间
+func (d Detector) secretSpans(text string) [][2]int {
+	names := d.Pattern.SubexpNames()
+	var spans [][2]int
+	for _, match := range d.Pattern.FindAllStringSubmatchIndex(text, -1) {
+		span := [2]int{match[0], match[1]}
+		for i, name := range names {
+			if name == secretGroup && match[2*i] >= 0 {
+				span = [2]int{match[2*i], match[2*i+1]}
+				break
+			}
+		}
+		if span[1] > span[0] {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
@@ -0,0 +1,13 @@
+// Package redact 在发送给模型前将敏感信息替换为可逆占位符
+//
+// Redactor 保存占位符与原文的映射，Restore 会把模型回答与工具参数中的占位符还原为原文。
+// 因此任何能让模型复述占位符的人都能取回对应的原文：一个 Redactor 只能在同一信任边界内使用，
+// 不能在不同的 API 请求、IM 对话或任务执行之间共享。需要为每个会话创建脱敏器时，
+// 使用 Fresh 从配置好的脱敏器派生出映射为空的新实例。
+//
+// 基本用法：
+//
+//	redactor, err := redact.NewFromConfig(config.GetConfig().Redaction)
+//	text, n := redactor.Redact("password=hunter2")   // "password=[REDACTED_PASSWORD_1]", 1
+//	redactor.Restore("密码是 [REDACTED_PASSWORD_1]") // "密码是 hunter2"
+package redact
@@ -0,0 +1,234 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ai-ops/internal/config"
+)
+
+// 按原文再次替换时要求的最小长度
+const minKnownValueLength = 4
+
+// placeholderPattern 匹配脱敏占位符，如 [REDACTED_PASSWORD_1]，分组为类型与编号
+var placeholderPattern = regexp.MustCompile(`\[REDACTED_([A-Z0-9_]+)_(\d+)\]`)
+
+// Redactor 将敏感信息替换为可逆占位符。
+// 同一原文在会话内始终映射到同一占位符，占位符与原文的对应关系只保存在本地。
+// nil Redactor 的所有方法都原样返回输入。
+type Redactor struct {
+	detectors []Detector
+
+	mu            sync.Mutex
+	byValue       map[string]string // 原文 -> 占位符
+	byPlaceholder map[string]string // 占位符 -> 原文
+	counters      map[string]int
+}
+
+// New 使用指定的检测器创建脱敏器
+func New(detectors []Detector) *Redactor {
+	return &Redactor{
+		detectors:     detectors,
+		byValue:       make(map[string]string),
+		byPlaceholder: make(map[string]string),
+		counters:      make(map[string]int),
+	}
+}
+
+// NewFromConfig 根据配置创建脱敏器，未启用时返回 nil
+func NewFromConfig(cfg config.RedactionConfig) (*Redactor, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+
+	names := cfg.Detectors
+	if len(names) == 0 {
+		names = BuiltinDetectorNames()
+	}
+
+	detectors := make([]Detector, 0, len(names)+len(cfg.Patterns))
+	// 私钥跨多行，优先检测，避免其中的片段被其他规则拆分
+	for _, name := range names {
+		detector, err := BuiltinDetector(name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "private_key" {
+			detectors = append([]Detector{detector}, detectors...)
+		} else {
+			detectors = append(detectors, detector)
+		}
+	}
+	for _, pattern := range cfg.Patterns {
+		detector, err := NewDetector(pattern.Name, pattern.Regex)
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, detector)
+	}
+
+	return New(detectors), nil
+}
+
+// Fresh 返回使用相同检测器、不含任何映射的新脱敏器，用于为每个会话创建独立的映射。nil 返回 nil
+func (r *Redactor) Fresh() *Redactor {
+	if r == nil {
+		return nil
+	}
+	return New(r.detectors)
+}
+
+// Redact 替换文本中的敏感信息，返回脱敏后的文本和替换次数
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil || text == "" {
+		return text, 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	for _, detector := range r.detectors {
+		spans := detector.secretSpans(text)
+		if len(spans) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, span := range spans {
+			value := text[span[0]:span[1]]
+			// 已脱敏的内容不再重复处理
+			if placeholderPattern.MatchString(value) {
+				continue
+			}
+			b.WriteString(text[last:span[0]])
+			b.WriteString(r.placeholderFor(detector.Name, value))
+			last = span[1]
+			total++
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+
+	// 已登记的原文可能以规则无法识别的形式再次出现（如工具回显了还原后的参数）
+	for _, value := range r.knownValues() {
+		if n := strings.Count(text, value); n > 0 {
+			text = strings.ReplaceAll(text, value, r.byValue[value])
+			total += n
+		}
+	}
+	return text, total
+}
+
+// knownValues 返回已登记的原文，按长度倒序以优先替换较长的值，调用方需持有锁
+func (r *Redactor) knownValues() []string {
+	values := make([]string, 0, len(r.byValue))
+	for value := range r.byValue {
+		// 过短的值容易误伤正常文本
+		if len(value) >= minKnownValueLength {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	return values
+}
+
+// Restore 将文本中的占位符还原为原文
+func (r *Redactor) Restore(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if value, ok := r.byPlaceholder[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// RestoreArguments 递归还原工具参数中的占位符，返回新的参数副本
+func (r *Redactor) RestoreArguments(args map[string]any) map[string]any {
+	if r == nil || args == nil {
+		return args
+	}
+	restored, _ := r.restoreValue(args).(map[string]any)
+	return restored
+}
+
+// Reserve 登记文本中已有的占位符（如恢复的会话记录中的占位符），之后分配的编号从其后开始，
+// 避免新的敏感信息与历史中的占位符同名。这些占位符没有对应的原文，Restore 时原样保留
+func (r *Redactor) Reserve(text string) {
+	if r == nil || text == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		n, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		if n > r.counters[match[1]] {
+			r.counters[match[1]] = n
+		}
+	}
+}
+
+// Count 返回会话内已登记的敏感信息数量
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.byValue)
+}
+
+func (r *Redactor) restoreValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.Restore(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = r.restoreValue(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.restoreValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// placeholderFor 获取或分配原文对应的占位符，调用方需持有锁
+func (r *Redactor) placeholderFor(detector, value string) string {
+	if placeholder, ok := r.byValue[value]; ok {
+		return placeholder
+	}
+	kind := strings.ToUpper(nonWordPattern.ReplaceAllString(detector, "_"))
+	r.counters[kind]++
+	placeholder := fmt.Sprintf("[REDACTED_%s_%d]", kind, r.counters[kind])
+	r.byValue[value] = placeholder
+	r.byPlaceholder[placeholder] = value
+	return placeholder
+}
+
+var nonWordPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
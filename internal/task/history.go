@@ -0,0 +1,282 @@
+package task
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 执行状态
+const (
+	StatusSuccess = "success" // 执行成功
+	StatusFailed  = "failed"  // 执行失败
+	StatusTimeout = "timeout" // 超时未完成
+	StatusSkipped = "skipped" // 上一次执行尚未结束，跳过本次执行
+)
+
+// 触发方式
+const (
+	TriggerManual   = "manual"   // ai-ops run 手动执行
+	TriggerSchedule = "schedule" // 调度器按计划执行
+	TriggerAlert    = "alert"    // ai-ops alert-receiver 收到告警后执行
+)
+
+// 未配置时保留的执行记录条数
+const defaultHistoryLimit = 1000
+
+// RunRecord 一次任务执行的记录
+type RunRecord struct {
+	ID         string               `json:"id"`
+	Task       string               `json:"task"`
+	Trigger    string               `json:"trigger"`
+	Status     string               `json:"status"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at"`
+	DurationMs int64                `json:"duration_ms"`
+	Adapter    string               `json:"adapter,omitempty"`
+	Model      string               `json:"model,omitempty"`
+	Mode       string               `json:"mode,omitempty"`
+	SessionID  string               `json:"session_id,omitempty"` // 会话记录ID，可通过 ai-ops sessions show 查看
+	Context    string               `json:"context,omitempty"`    // 触发执行的上下文（如告警详情）
+	Output     string               `json:"output,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	ToolCalls  []chat.ToolCallTrace `json:"tool_calls,omitempty"`
+	Usage      llm.TokenUsage       `json:"usage"`
+
+	err error // 执行失败的原始错误，用于确定退出码
+}
+
+// newRunRecord 创建执行记录
+func newRunRecord(taskName, trigger string) *RunRecord {
+	buf := make([]byte, 2)
+	_, _ = rand.Read(buf)
+	now := time.Now()
+	return &RunRecord{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(buf),
+		Task:      taskName,
+		Trigger:   trigger,
+		StartedAt: now,
+	}
+}
+
+// finish 记录执行结束时间与状态
+func (r *RunRecord) finish(status string, err error) {
+	r.Status = status
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	if err != nil {
+		r.err = err
+		r.Error = err.Error()
+	}
+}
+
+// Err 返回执行失败的原因，执行成功时返回 nil
+func (r *RunRecord) Err() error {
+	return r.err
+}
+
+// StatusLabel 返回带图标的执行状态
+func (r *RunRecord) StatusLabel() string {
+	switch r.Status {
+	case StatusSuccess:
+		return "✅ 成功"
+	case StatusTimeout:
+		return "⏱️ 超时"
+	case StatusSkipped:
+		return "⏭️ 跳过"
+	default:
+		return "❌ 失败"
+	}
+}
+
+// Title 返回包含任务名称与执行状态的标题
+func (r *RunRecord) Title() string {
+	return fmt.Sprintf("任务 %s %s", r.Task, r.StatusLabel())
+}
+
+// Markdown 生成执行结果的 Markdown 报告
+func (r *RunRecord) Markdown() string {
+	return "## " + r.Title() + "\n\n" + r.markdownBody()
+}
+
+// markdownBody 生成不含标题的 Markdown 报告
+func (r *RunRecord) markdownBody() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- 开始时间: %s\n", r.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- 耗时: %s\n", (time.Duration(r.DurationMs) * time.Millisecond).String())
+	if r.Model != "" {
+		fmt.Fprintf(&b, "- 模型: %s · %s\n", r.Adapter, r.Model)
+	}
+	if len(r.ToolCalls) > 0 {
+		fmt.Fprintf(&b, "- 工具调用: %d 次\n", len(r.ToolCalls))
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "- 错误: %s\n", r.Error)
+	}
+	if r.Context != "" {
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(r.Context))
+		b.WriteString("\n")
+	}
+	if r.Output != "" {
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(r.Output))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// History 以 JSONL 文件保存任务的执行记录
+type History struct {
+	mu    sync.Mutex
+	path  string
+	limit int
+}
+
+// NewHistory 创建执行记录存储，path 为空时使用默认路径，limit 为保留的记录条数（0 使用默认值）
+func NewHistory(path string, limit int) *History {
+	if path == "" {
+		path = DefaultHistoryPath()
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	return &History{path: path, limit: limit}
+}
+
+// DefaultHistoryPath 默认的执行记录文件 ~/.ai-ops/tasks/history.jsonl
+func DefaultHistoryPath() string {
+	return filepath.Join(DefaultDir(), "history.jsonl")
+}
+
+// DefaultDir 默认的任务目录 ~/.ai-ops/tasks
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".ai-ops", "tasks")
+	}
+	return filepath.Join(homeDir, ".ai-ops", "tasks")
+}
+
+// Path 返回执行记录文件路径
+func (h *History) Path() string {
+	return h.path
+}
+
+// Append 追加一条执行记录，记录数超过上限的两倍时只保留最近的记录
+func (h *History) Append(record *RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "序列化执行记录失败", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "创建执行记录目录失败", err)
+	}
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "打开执行记录文件失败", err)
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "写入执行记录失败", err)
+	}
+
+	return h.compact()
+}
+
+// compact 记录数超过上限的两倍时重写文件，避免每次追加都重写
+func (h *History) compact() error {
+	records, err := h.read()
+	if err != nil || len(records) <= h.limit*2 {
+		return err
+	}
+	records = records[len(records)-h.limit:]
+
+	var b strings.Builder
+	for _, record := range records {
+		data, _ := json.Marshal(record)
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(b.String()), 0644); err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "重写执行记录失败", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		return errors.WrapError(errors.ErrCodeInternalErr, "重写执行记录失败", err)
+	}
+	return nil
+}
+
+// List 返回最近的执行记录，按时间倒序；taskName 非空时只返回该任务的记录，n 为 0 时返回全部
+func (h *History) List(taskName string, n int) ([]*RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	records, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	var result []*RunRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		if taskName != "" && records[i].Task != taskName {
+			continue
+		}
+		result = append(result, records[i])
+		if n > 0 && len(result) >= n {
+			break
+		}
+	}
+	return result, nil
+}
+
+// read 按写入顺序读取全部执行记录
+func (h *History) read() ([]*RunRecord, error) {
+	file, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "打开执行记录文件失败", err)
+	}
+	defer file.Close()
+
+	var records []*RunRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record RunRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// 进程异常退出可能留下不完整的最后一行，忽略即可
+			util.Debugw("忽略无法解析的执行记录", map[string]any{"file": h.path, "error": err.Error()})
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取执行记录失败", err)
+	}
+	return records, nil
+}
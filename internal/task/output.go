@@ -0,0 +1,202 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ai-ops/internal/notify"
+)
+
+// 输出目标类型
+const (
+	outputStdout  = "stdout"
+	outputFile    = "file"
+	outputWebhook = "webhook"
+	outputNotify  = "notify"
+)
+
+// webhook 请求超时
+const webhookTimeout = 15 * time.Second
+
+// Output 执行结果的输出目标
+type Output struct {
+	Type   string `toml:"type"`   // stdout、file、webhook 或 notify
+	Format string `toml:"format"` // markdown（默认）或 json（完整的执行记录）
+	// When 何时输出：always（默认）、success 或 failure
+	When string `toml:"when"`
+	// Path 输出文件路径，可使用 {name}、{date}、{time}、{id} 占位符
+	Path    string            `toml:"path"`
+	Append  bool              `toml:"append"`  // 追加写入而不是覆盖
+	URL     string            `toml:"url"`     // webhook 地址，以 POST 发送
+	Headers map[string]string `toml:"headers"` // webhook 请求附加头（如鉴权），值中的 ${VAR} 替换为环境变量
+	Channel string            `toml:"channel"` // notify 输出使用的 [notify.channels] 渠道名称
+}
+
+// validate 校验输出目标并填充默认值
+func (o *Output) validate() error {
+	switch o.Type {
+	case outputStdout:
+	case outputFile:
+		if o.Path == "" {
+			return fmt.Errorf("file 输出需要配置 path")
+		}
+	case outputWebhook:
+		if !strings.HasPrefix(o.URL, "http://") && !strings.HasPrefix(o.URL, "https://") {
+			return fmt.Errorf("webhook 地址格式不正确: %s", o.URL)
+		}
+	case outputNotify:
+		if o.Channel == "" {
+			return fmt.Errorf("notify 输出需要配置 channel")
+		}
+		if o.Format == "json" {
+			return fmt.Errorf("notify 输出只支持 markdown 格式")
+		}
+	default:
+		return fmt.Errorf("不支持的输出类型: %s（可选 stdout、file、webhook、notify）", o.Type)
+	}
+
+	switch o.Format {
+	case "":
+		o.Format = "markdown"
+	case "markdown", "json":
+	default:
+		return fmt.Errorf("不支持的输出格式: %s（可选 markdown、json）", o.Format)
+	}
+
+	switch o.When {
+	case "":
+		o.When = "always"
+	case "always", "success", "failure":
+	default:
+		return fmt.Errorf("不支持的输出时机: %s（可选 always、success、failure）", o.When)
+	}
+	return nil
+}
+
+// matches 判断本次执行结果是否需要输出
+func (o *Output) matches(record *RunRecord) bool {
+	switch o.When {
+	case "success":
+		return record.Status == StatusSuccess
+	case "failure":
+		return record.Status != StatusSuccess
+	default:
+		return true
+	}
+}
+
+// render 按输出格式生成内容
+func (o *Output) render(record *RunRecord) ([]byte, error) {
+	if o.Format == "json" {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return []byte(record.Markdown()), nil
+}
+
+// deliver 将执行结果写入输出目标
+func (o *Output) deliver(ctx context.Context, record *RunRecord, stdout io.Writer, notifier *notify.Notifier) error {
+	if o.Type == outputNotify {
+		if notifier == nil {
+			return fmt.Errorf("未配置通知渠道")
+		}
+		return notifier.Send(ctx, o.Channel, notify.Message{
+			Title:   record.Title(),
+			Content: record.markdownBody(),
+			Source:  "task:" + record.Task,
+			Time:    record.StartedAt,
+		})
+	}
+
+	content, err := o.render(record)
+	if err != nil {
+		return fmt.Errorf("生成输出内容失败: %w", err)
+	}
+
+	switch o.Type {
+	case outputStdout:
+		_, err = stdout.Write(content)
+		return err
+	case outputFile:
+		return writeOutputFile(expandOutputPath(o.Path, record), content, o.Append)
+	case outputWebhook:
+		return postWebhook(ctx, o.URL, o.Headers, o.Format, content)
+	}
+	return nil
+}
+
+// expandOutputPath 替换输出路径中的占位符
+func expandOutputPath(path string, record *RunRecord) string {
+	replacer := strings.NewReplacer(
+		"{name}", record.Task,
+		"{date}", record.StartedAt.Format("2006-01-02"),
+		"{time}", record.StartedAt.Format("150405"),
+		"{id}", record.ID,
+	)
+	return replacer.Replace(path)
+}
+
+// writeOutputFile 写入输出文件，自动创建所在目录
+func writeOutputFile(path string, content []byte, appendMode bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("打开输出文件失败: %w", err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("写入输出文件失败: %w", err)
+	}
+	return file.Close()
+}
+
+// postWebhook 以 POST 发送执行结果，markdown 格式包装为 {"text": ...}
+func postWebhook(ctx context.Context, url string, headers map[string]string, format string, content []byte) error {
+	body := content
+	if format != "json" {
+		var err error
+		body, err = json.Marshal(map[string]string{"text": string(content)})
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建 webhook 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 webhook 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
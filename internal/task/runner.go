@@ -0,0 +1,255 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-ops/internal/chat"
+	"ai-ops/internal/llm"
+	"ai-ops/internal/notify"
+	"ai-ops/internal/tools"
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// 任务未配置超时且 [tasks] timeout 未设置时使用的超时时间
+const defaultTimeout = 10 * time.Minute
+
+// Runner 通过 chat.Session 执行任务，记录执行结果并写入输出目标
+type Runner struct {
+	toolManager tools.ToolManager
+	base        chat.SessionConfig // 会话的公共设置，ModelName 为默认模型
+	history     *History
+	notifier    *notify.Notifier // notify 输出使用的通知器，nil 表示未配置
+	timeout     time.Duration
+
+	stdoutMu sync.Mutex // 调度器并发执行任务时避免标准输出交错
+	stdout   io.Writer
+}
+
+// NewRunner 创建任务执行器，history 为 nil 时不保存执行记录，timeout 为 0 时使用默认值
+func NewRunner(toolManager tools.ToolManager, base chat.SessionConfig, history *History, notifier *notify.Notifier, timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Runner{
+		toolManager: toolManager,
+		base:        base,
+		history:     history,
+		notifier:    notifier,
+		timeout:     timeout,
+		stdout:      os.Stdout,
+	}
+}
+
+// Run 执行一次任务，写入输出目标与执行记录后返回。执行失败的原因记录在返回的记录中。
+func (r *Runner) Run(ctx context.Context, task *Task, trigger string) *RunRecord {
+	record := newRunRecord(task.Name, trigger)
+	record.Mode = task.Mode
+	record.Context = task.Context
+	util.Infow("任务开始执行", map[string]any{
+		"task":    task.Name,
+		"run_id":  record.ID,
+		"trigger": trigger,
+	})
+
+	timeout := task.timeout
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := r.execute(runCtx, task, record)
+	timedOut := runCtx.Err() == context.DeadlineExceeded
+	cancel()
+
+	switch {
+	case err == nil:
+		record.finish(StatusSuccess, nil)
+	case timedOut:
+		record.finish(StatusTimeout, fmt.Errorf("执行超过 %s 未完成: %w", timeout, err))
+	default:
+		record.finish(StatusFailed, err)
+	}
+
+	fields := map[string]any{
+		"task":        task.Name,
+		"run_id":      record.ID,
+		"status":      record.Status,
+		"duration_ms": record.DurationMs,
+		"tool_calls":  len(record.ToolCalls),
+	}
+	if record.Status == StatusSuccess {
+		util.Infow("任务执行完成", fields)
+	} else {
+		fields["error"] = record.Error
+		util.Warnw("任务执行失败", fields)
+	}
+
+	// 任务被取消时仍然发送结果，webhook 请求有独立的超时
+	r.deliver(context.WithoutCancel(ctx), task, record)
+	r.save(record)
+	return record
+}
+
+// Skip 记录因上一次执行尚未结束而跳过的执行
+func (r *Runner) Skip(task *Task, trigger string) *RunRecord {
+	record := newRunRecord(task.Name, trigger)
+	record.Mode = task.Mode
+	record.finish(StatusSkipped, fmt.Errorf("上一次执行尚未结束"))
+	util.Warnw("任务仍在执行，跳过本次执行", map[string]any{
+		"task":   task.Name,
+		"run_id": record.ID,
+	})
+	r.save(record)
+	return record
+}
+
+// execute 创建会话并执行任务，将输出、工具调用与用量写入记录
+func (r *Runner) execute(ctx context.Context, task *Task, record *RunRecord) error {
+	modelName := task.Model
+	if modelName == "" {
+		modelName = r.base.ModelName
+	}
+	client, exists := llm.GetAdapter(modelName)
+	if !exists {
+		return errors.NewErrorWithDetails(errors.ErrCodeModelNotFound, "模型不存在", modelName)
+	}
+	record.Adapter = modelName
+	record.Model = client.GetModelInfo().Name
+
+	config := r.base
+	config.Mode = task.Mode
+	config.ModelName = modelName
+	config.DisableTools = task.NoTools
+	// 无人值守执行，有风险的工具调用只能按任务配置自动批准或拒绝
+	config.ApprovalMode = task.Approval
+	config.Approver = nil
+	// 每次执行使用独立的脱敏映射，告警等外部输入不能取回其他执行中的原文
+	config.Redactor = r.base.Redactor.Fresh()
+	if task.Profile != "" {
+		config.Profile = task.Profile
+	}
+	if config.Prompts != nil {
+		if _, err := config.Prompts.Load(config.Profile, config.Mode); err != nil {
+			return err
+		}
+	}
+
+	session := chat.NewSession(client, r.toolManager, config)
+	defer session.Close()
+	record.SessionID = session.ID()
+	if config.Store != nil {
+		if _, err := session.Save("任务 " + task.Name); err != nil {
+			util.Warnw("保存任务会话失败", map[string]any{"task": task.Name, "error": err.Error()})
+		}
+	}
+	if err := restrictTools(session, task.Tools); err != nil {
+		return err
+	}
+
+	var err error
+	if task.Mode == "agent" {
+		var report *chat.AgentReport
+		report, err = session.RunAgent(ctx, task.Prompt, chat.AgentHooks{})
+		if report != nil {
+			record.Output = report.Markdown()
+		}
+	} else {
+		var answer string
+		answer, err = session.ProcessMessage(ctx, task.Prompt)
+		record.Output = chat.ExtractThinking(answer).Content
+	}
+
+	turn := session.LastTurn()
+	record.ToolCalls = turn.ToolCalls
+	record.Usage = turn.Usage
+	return err
+}
+
+// restrictTools 只保留任务允许的工具，allowed 为空时不限制
+func restrictTools(session *chat.Session, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+	statuses := session.Tools()
+	for _, status := range statuses {
+		delete(allowedSet, status.Name)
+	}
+	if len(allowedSet) > 0 {
+		missing := make([]string, 0, len(allowedSet))
+		for name := range allowedSet {
+			missing = append(missing, name)
+		}
+		return errors.NewErrorWithDetails(errors.ErrCodeToolNotFound, "任务允许的工具不存在", strings.Join(missing, ", "))
+	}
+
+	for _, status := range statuses {
+		if containsString(allowed, status.Name) {
+			continue
+		}
+		if err := session.SetToolEnabled(status.Name, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver 将执行结果写入任务的输出目标，未配置时输出到标准输出
+func (r *Runner) deliver(ctx context.Context, task *Task, record *RunRecord) {
+	outputs := task.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{{Type: outputStdout, Format: "markdown", When: "always"}}
+	}
+
+	for _, output := range outputs {
+		if !output.matches(record) {
+			continue
+		}
+		var err error
+		if output.Type == outputStdout {
+			r.stdoutMu.Lock()
+			err = output.deliver(ctx, record, r.stdout, r.notifier)
+			r.stdoutMu.Unlock()
+		} else {
+			err = output.deliver(ctx, record, r.stdout, r.notifier)
+		}
+		if err != nil {
+			util.Warnw("任务结果输出失败", map[string]any{
+				"task":   task.Name,
+				"run_id": record.ID,
+				"output": output.Type,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// save 保存执行记录
+func (r *Runner) save(record *RunRecord) {
+	if r.history == nil {
+		return
+	}
+	if err := r.history.Append(record); err != nil {
+		util.Warnw("保存任务执行记录失败", map[string]any{"run_id": record.ID, "error": err.Error()})
+	}
+}
+
+// containsString 判断列表中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
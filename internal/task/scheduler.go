@@ -0,0 +1,127 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// Scheduler 按执行计划运行任务，同一任务上一次执行尚未结束时跳过本次执行
+type Scheduler struct {
+	runner *Runner
+	tasks  []*Task
+
+	mu      sync.Mutex
+	running map[string]bool // 正在执行的任务
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 创建调度器，只调度配置了执行计划的任务，任务名称不能重复
+func NewScheduler(runner *Runner, tasks []*Task) (*Scheduler, error) {
+	scheduler := &Scheduler{runner: runner, running: make(map[string]bool)}
+	paths := make(map[string]string)
+	for _, task := range tasks {
+		if !task.Scheduled() {
+			util.Warnw("任务未配置执行计划，不参与调度", map[string]any{"task": task.Name, "path": task.Path})
+			continue
+		}
+		if path, exists := paths[task.Name]; exists {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid, "任务名称重复: "+task.Name,
+				path+", "+task.Path)
+		}
+		paths[task.Name] = task.Path
+		scheduler.tasks = append(scheduler.tasks, task)
+	}
+	if len(scheduler.tasks) == 0 {
+		return nil, errors.NewError(errors.ErrCodeInvalidParameters, "没有配置了执行计划（schedule）的任务")
+	}
+	return scheduler, nil
+}
+
+// Tasks 返回参与调度的任务
+func (s *Scheduler) Tasks() []*Task {
+	return s.tasks
+}
+
+// Run 按计划执行任务直到 ctx 取消，取消后不再启动新的执行，等待正在执行的任务结束（受任务超时限制）后返回
+func (s *Scheduler) Run(ctx context.Context) {
+	now := time.Now()
+	next := make(map[*Task]time.Time, len(s.tasks))
+	for _, task := range s.tasks {
+		next[task] = task.Next(now)
+		util.Infow("任务已加入调度", map[string]any{
+			"task":     task.Name,
+			"schedule": task.Schedule,
+			"next_run": next[task].Format(time.RFC3339),
+		})
+	}
+
+	for {
+		var earliest time.Time
+		for _, at := range next {
+			if !at.IsZero() && (earliest.IsZero() || at.Before(earliest)) {
+				earliest = at
+			}
+		}
+		if earliest.IsZero() {
+			util.Warn("没有可执行的任务，调度器退出")
+			break
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.wait()
+			return
+		case <-timer.C:
+		}
+
+		now = time.Now()
+		for _, task := range s.tasks {
+			if at := next[task]; !at.IsZero() && !at.After(now) {
+				s.start(ctx, task)
+				next[task] = task.Next(now)
+			}
+		}
+	}
+	s.wait()
+}
+
+// start 在后台执行任务，上一次执行尚未结束时记录为跳过
+func (s *Scheduler) start(ctx context.Context, task *Task) {
+	s.mu.Lock()
+	if s.running[task.Name] {
+		s.mu.Unlock()
+		s.runner.Skip(task, TriggerSchedule)
+		return
+	}
+	s.running[task.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, task.Name)
+			s.mu.Unlock()
+		}()
+		// 停止调度不中断正在执行的任务，由任务超时限制执行时间
+		s.runner.Run(context.WithoutCancel(ctx), task, TriggerSchedule)
+	}()
+}
+
+// wait 等待正在执行的任务结束
+func (s *Scheduler) wait() {
+	s.mu.Lock()
+	running := len(s.running)
+	s.mu.Unlock()
+	if running > 0 {
+		util.Infow("等待正在执行的任务结束", map[string]any{"running": running})
+	}
+	s.wg.Wait()
+}
@@ -0,0 +1,164 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"ai-ops/internal/util/errors"
+)
+
+// 任务文件扩展名
+const taskFileExt = ".toml"
+
+// Task 定时任务，由一个 TOML 文件定义
+type Task struct {
+	Name        string `toml:"name"`        // 任务名称，默认为文件名
+	Description string `toml:"description"` // 任务说明
+	Prompt      string `toml:"prompt"`      // 发送给模型的问题或任务目标
+	Model       string `toml:"model"`       // 模型适配器名称，默认使用 default_model
+	Mode        string `toml:"mode"`        // chat 或 agent，默认 chat
+	Profile     string `toml:"profile"`     // 提示词模板，默认使用 [prompts] profile
+	// Tools 允许调用的工具，未配置时允许全部工具
+	Tools   []string `toml:"tools"`
+	NoTools bool     `toml:"no_tools"` // 不向模型提供任何工具
+	// Approval 有风险工具调用的处理方式：deny（默认，无人值守时拒绝）或 auto（自动批准）
+	Approval string   `toml:"approval"`
+	Schedule string   `toml:"schedule"` // 执行计划，ai-ops scheduler 使用
+	Timeout  string   `toml:"timeout"`  // 单次执行超时，如 10m，默认使用 [tasks] timeout
+	Outputs  []Output `toml:"output"`   // 执行结果的输出目标，未配置时输出到标准输出
+	// Context 触发执行的上下文（如告警详情），写入执行记录并显示在输出中
+	Context string `toml:"-"`
+
+	Path     string        `toml:"-"` // 任务文件路径
+	schedule Schedule      // 解析后的执行计划
+	timeout  time.Duration // 解析后的超时时间
+}
+
+// Load 读取并校验任务文件
+func Load(path string) (*Task, error) {
+	var task Task
+	meta, err := toml.DecodeFile(path, &task)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewErrorWithDetails(errors.ErrCodeNotFound, "任务文件不存在", path)
+		}
+		return nil, errors.WrapError(errors.ErrCodeConfigParseFailed, "解析任务文件失败: "+path, err)
+	}
+	// 拼写错误的字段会被忽略，提前报错避免任务按默认设置运行
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid,
+			"任务文件包含未知字段: "+path, strings.Join(keys, ", "))
+	}
+
+	task.Path = path
+	if task.Name == "" {
+		task.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := task.Validate(); err != nil {
+		return nil, errors.NewErrorWithDetails(errors.ErrCodeConfigInvalid, "任务配置无效: "+path, err.Error())
+	}
+	return &task, nil
+}
+
+// LoadDir 读取目录中的全部任务文件，按名称排序
+func LoadDir(dir string) ([]*Task, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WrapError(errors.ErrCodeInternalErr, "读取任务目录失败", err)
+	}
+
+	var tasks []*Task
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), taskFileExt) {
+			continue
+		}
+		task, err := Load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Name < tasks[j].Name
+	})
+	return tasks, nil
+}
+
+// Validate 校验任务配置并解析执行计划与超时时间，程序构造的任务在执行前需要调用
+func (t *Task) Validate() error {
+	if strings.TrimSpace(t.Prompt) == "" {
+		return fmt.Errorf("prompt 不能为空")
+	}
+
+	switch t.Mode {
+	case "":
+		t.Mode = "chat"
+	case "chat", "agent":
+	default:
+		return fmt.Errorf("不支持的模式: %s（可选 chat、agent）", t.Mode)
+	}
+
+	switch t.Approval {
+	case "":
+		t.Approval = "deny"
+	case "deny", "auto":
+	default:
+		return fmt.Errorf("不支持的工具确认方式: %s（可选 deny、auto）", t.Approval)
+	}
+
+	if t.NoTools && len(t.Tools) > 0 {
+		return fmt.Errorf("no_tools 与 tools 不能同时配置")
+	}
+
+	if t.Schedule != "" {
+		schedule, err := ParseSchedule(t.Schedule)
+		if err != nil {
+			return err
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("执行计划 %q 没有可执行的时间", t.Schedule)
+		}
+		t.schedule = schedule
+	}
+
+	if t.Timeout != "" {
+		timeout, err := time.ParseDuration(t.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("无效的超时时间: %s", t.Timeout)
+		}
+		t.timeout = timeout
+	}
+
+	for i := range t.Outputs {
+		if err := t.Outputs[i].validate(); err != nil {
+			return fmt.Errorf("第 %d 个输出目标无效: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Scheduled 任务是否配置了执行计划
+func (t *Task) Scheduled() bool {
+	return t.schedule != nil
+}
+
+// Next 返回 after 之后的下一次执行时间，未配置执行计划时返回零值
+func (t *Task) Next(after time.Time) time.Time {
+	if t.schedule == nil {
+		return time.Time{}
+	}
+	return t.schedule.Next(after)
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"ai-ops/internal/tracing"
 	"ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 	"ai-ops/pkg/registry"
@@ -159,6 +160,13 @@ func (m *DefaultToolManager) GetToolDefinitions() []ToolDefinition {
 
 // ExecuteToolCall 执行工具调用
 func (m *DefaultToolManager) ExecuteToolCall(ctx context.Context, call ToolCall) (string, error) {
+	ctx, span := tracing.Start(ctx, "tool.execute", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttributes(map[string]any{
+		"tool.name":    call.Name,
+		"tool.call_id": call.ID,
+	})
+
 	startTime := time.Now()
 
 	//记录工具调用开始
@@ -171,6 +179,8 @@ func (m *DefaultToolManager) ExecuteToolCall(ctx context.Context, call ToolCall)
 	// 获取工具
 	tool, err := m.GetTool(call.Name)
 	if err != nil {
+		recordToolExecution(unknownToolName, time.Since(startTime), err)
+		span.RecordError(err)
 		util.LogErrorWithFields(err, "工具获取失败", map[string]any{
 			"tool_name": call.Name,
 			"call_id":   call.ID,
@@ -181,8 +191,11 @@ func (m *DefaultToolManager) ExecuteToolCall(ctx context.Context, call ToolCall)
 	// 执行工具
 	result, err := tool.Execute(ctx, call.Arguments)
 	executionTime := time.Since(startTime)
+	recordToolExecution(call.Name, executionTime, err)
 
 	if err != nil {
+		span.RecordError(err)
+
 		// 记录执行失败
 		util.LogErrorWithFields(err, "工具执行失败", map[string]any{
 			"tool_name":      call.Name,
@@ -203,6 +216,7 @@ func (m *DefaultToolManager) ExecuteToolCall(ctx context.Context, call ToolCall)
 		"execution_time": executionTime,
 		"result_length":  len(result),
 	})
+	span.SetAttribute("tool.result_length", len(result))
 
 	return result, nil
 }
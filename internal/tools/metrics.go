@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ai-ops/internal/metrics"
+)
+
+// ToolMetrics 单个工具的执行指标
+type ToolMetrics struct {
+	CallCount        int64                     `json:"call_count"`
+	ErrorCount       int64                     `json:"error_count"`
+	LatencyHistogram metrics.HistogramSnapshot `json:"latency_histogram"` // 执行耗时分布（秒）
+}
+
+// toolStats 工具执行统计
+type toolStats struct {
+	calls   int64
+	errors  int64
+	latency *metrics.Histogram
+}
+
+// 未注册的工具名称由模型生成，统一计入该名称，避免指标标签无限增长
+const unknownToolName = "unknown"
+
+var (
+	toolStatsMutex  sync.Mutex
+	toolStatsByName = make(map[string]*toolStats)
+)
+
+// recordToolExecution 记录一次工具执行
+func recordToolExecution(name string, duration time.Duration, err error) {
+	toolStatsMutex.Lock()
+	defer toolStatsMutex.Unlock()
+
+	stats, ok := toolStatsByName[name]
+	if !ok {
+		stats = &toolStats{latency: metrics.NewHistogram(nil)}
+		toolStatsByName[name] = stats
+	}
+	stats.calls++
+	if err != nil {
+		stats.errors++
+	}
+	stats.latency.Observe(duration.Seconds())
+}
+
+// GetToolMetrics 获取所有工具的执行指标
+func GetToolMetrics() map[string]ToolMetrics {
+	toolStatsMutex.Lock()
+	defer toolStatsMutex.Unlock()
+
+	result := make(map[string]ToolMetrics, len(toolStatsByName))
+	for name, stats := range toolStatsByName {
+		result[name] = ToolMetrics{
+			CallCount:        stats.calls,
+			ErrorCount:       stats.errors,
+			LatencyHistogram: stats.latency.Snapshot(),
+		}
+	}
+	return result
+}
+
+// CollectMetrics 将工具执行指标写入指标输出
+func CollectMetrics(w *metrics.Writer) {
+	all := GetToolMetrics()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := all[name]
+		labels := metrics.Labels{"tool": name}
+		w.Counter("tool_calls_total", "工具调用总数", labels, float64(m.CallCount))
+		w.Counter("tool_errors_total", "工具调用失败总数", labels, float64(m.ErrorCount))
+		w.Histogram("tool_duration_seconds", "工具执行耗时分布", labels, m.LatencyHistogram)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"ai-ops/internal/tools"
 	pkg "ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 )
@@ -11,10 +12,11 @@ import (
 // EchoTool 回显工具实现
 type EchoTool struct{}
 
-func (e *EchoTool) ID() string          { return "echo" }
-func (e *EchoTool) Name() string        { return "echo" }
-func (e *EchoTool) Type() string        { return "plugin" }
-func (e *EchoTool) Description() string { return "回显输入的消息" }
+func (e *EchoTool) ID() string                 { return "echo" }
+func (e *EchoTool) Name() string               { return "echo" }
+func (e *EchoTool) Type() string               { return "plugin" }
+func (e *EchoTool) Description() string        { return "回显输入的消息" }
+func (e *EchoTool) RiskLevel() tools.RiskLevel { return tools.RiskReadOnly }
 func (e *EchoTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"ai-ops/internal/config"
+	"ai-ops/internal/notify"
 	"ai-ops/internal/tools"
 	"ai-ops/internal/util"
 )
@@ -43,5 +44,22 @@ func RegisterPluginFactories(tm tools.ToolManager) {
 		})
 	}
 
+	// Notify工具 - 需要配置通知渠道，根据配置启用
+	if cfg.Tools.Notify {
+		notifier, err := notify.NewFromConfig(cfg.Notify)
+		switch {
+		case err != nil:
+			util.Warnw("通知渠道配置无效，通知工具不可用", map[string]any{"error": err.Error()})
+		case len(notifier.Channels()) == 0:
+			util.Warnw("未配置通知渠道，通知工具不可用", nil)
+		default:
+			tm.RegisterToolFactory("notify", func() interface{} { return NewNotifyTool(notifier) })
+			util.Debugw("可选工具注册", map[string]any{
+				"tool":     "notify",
+				"channels": notifier.Channels(),
+			})
+		}
+	}
+
 	util.Debugw("插件工厂注册完成", nil)
 }
@@ -0,0 +1,71 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ai-ops/internal/notify"
+	"ai-ops/internal/tools"
+	pkg "ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+// NotifyTool 通知工具实现，向 [notify.channels] 中配置的渠道发送消息
+type NotifyTool struct {
+	notifier *notify.Notifier
+}
+
+func (n *NotifyTool) ID() string   { return "notify" }
+func (n *NotifyTool) Name() string { return "notify" }
+func (n *NotifyTool) Type() string { return "plugin" }
+
+// RiskLevel 消息发送到外部渠道后无法撤回，执行前需要确认
+func (n *NotifyTool) RiskLevel() tools.RiskLevel { return tools.RiskMutating }
+func (n *NotifyTool) Description() string {
+	return "发送通知消息到团队聊天或邮件，可用渠道: " + strings.Join(n.notifier.Channels(), ", ")
+}
+func (n *NotifyTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"channel": map[string]any{
+				"type":        "string",
+				"description": "通知渠道名称",
+				"enum":        n.notifier.Channels(),
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "消息标题",
+			},
+			"content": map[string]any{
+				"type":        "string",
+				"description": "消息正文（Markdown）",
+			},
+		},
+		"required": []string{"channel", "content"},
+	}
+}
+
+func (n *NotifyTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	channel, ok := args["channel"].(string)
+	if !ok || channel == "" {
+		return "", errors.NewError(errors.ErrCodeInvalidParam, "缺少或无效的 channel 参数")
+	}
+	content, ok := args["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		return "", errors.NewError(errors.ErrCodeInvalidParam, "缺少或无效的 content 参数")
+	}
+	title, _ := args["title"].(string)
+
+	pkg.Debugw("执行通知工具", map[string]any{"channel": channel, "title": title})
+	if err := n.notifier.Send(ctx, channel, notify.Message{Title: title, Content: content, Source: "agent"}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("通知已发送到渠道 %s", channel), nil
+}
+
+// NewNotifyTool 创建通知工具实例
+func NewNotifyTool(notifier *notify.Notifier) *NotifyTool {
+	return &NotifyTool{notifier: notifier}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"ai-ops/internal/config"
+	"ai-ops/internal/tools"
 	pkg "ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 )
@@ -30,6 +31,10 @@ func (t *RAGTool) Type() string {
 	return "plugin"
 }
 
+func (t *RAGTool) RiskLevel() tools.RiskLevel {
+	return tools.RiskReadOnly
+}
+
 func (t *RAGTool) Description() string {
 	return "从知识库检索数据"
 }
@@ -53,12 +58,13 @@ func (t *RAGTool) Execute(ctx context.Context, args map[string]any) (string, err
 		return "", errors.NewError(errors.ErrCodeInvalidParam, "缺少或无效的 query 参数")
 	}
 
-	if config.Config == nil {
+	cfg := config.GetConfig()
+	if cfg == nil {
 		return "", errors.NewError(errors.ErrCodeConfigNotFound, "系统配置未初始化")
 	}
 
-	retrievalK := config.Config.RAG.RetrievalK
-	topK := config.Config.RAG.TopK
+	retrievalK := cfg.RAG.RetrievalK
+	topK := cfg.RAG.TopK
 	useReranker := true // 默认开启
 
 	pkg.Infow("执行RAG检索工具", map[string]any{
@@ -78,11 +84,12 @@ func (t *RAGTool) Execute(ctx context.Context, args map[string]any) (string, err
 }
 
 func (t *RAGTool) callRAGAPI(ctx context.Context, query string, retrievalK, topK int, useReranker bool) (string, error) {
-	if config.Config == nil {
+	cfg := config.GetConfig()
+	if cfg == nil {
 		return "", errors.NewError(errors.ErrCodeConfigNotFound, "系统配置未初始化")
 	}
 
-	apiHost := config.Config.RAG.ApiHost
+	apiHost := cfg.RAG.ApiHost
 	if apiHost == "" {
 		return `{"message":"RAG工具需要配置API主机才能正常工作","status":"demo"}`, nil
 	}
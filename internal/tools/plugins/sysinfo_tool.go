@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"ai-ops/internal/tools"
+
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/disk"
 	"github.com/shirou/gopsutil/v4/host"
@@ -37,6 +39,11 @@ func (t *SysInfoTool) Type() string {
 	return "system"
 }
 
+// RiskLevel 返回工具风险等级，仅读取系统信息
+func (t *SysInfoTool) RiskLevel() tools.RiskLevel {
+	return tools.RiskReadOnly
+}
+
 // Description 返回工具描述
 func (t *SysInfoTool) Description() string {
 	return "获取系统信息，包括CPU、内存、磁盘、网络等监控数据"
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"ai-ops/internal/config"
+	"ai-ops/internal/tools"
 	pkg "ai-ops/internal/util"
 	"ai-ops/internal/util/errors"
 )
@@ -19,10 +20,11 @@ import (
 // WeatherTool 天气工具实现
 type WeatherTool struct{}
 
-func (w *WeatherTool) ID() string          { return "weather" }
-func (w *WeatherTool) Name() string        { return "weather" }
-func (w *WeatherTool) Type() string        { return "plugin" }
-func (w *WeatherTool) Description() string { return "查询指定地点的实时天气信息" }
+func (w *WeatherTool) ID() string                 { return "weather" }
+func (w *WeatherTool) Name() string               { return "weather" }
+func (w *WeatherTool) Type() string               { return "plugin" }
+func (w *WeatherTool) RiskLevel() tools.RiskLevel { return tools.RiskReadOnly }
+func (w *WeatherTool) Description() string        { return "查询指定地点的实时天气信息" }
 func (w *WeatherTool) Parameters() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -57,12 +59,13 @@ func (w *WeatherTool) Execute(ctx context.Context, args map[string]any) (string,
 // callWeatherAPI 调用天气API
 func (w *WeatherTool) callWeatherAPI(ctx context.Context, location string) (string, error) {
 	// 配置验证
-	if config.Config == nil {
+	cfg := config.GetConfig()
+	if cfg == nil {
 		return "", errors.NewError(errors.ErrCodeConfigNotFound, "系统配置未初始化")
 	}
 
-	apiHost := config.Config.Weather.ApiHost
-	apiKey := config.Config.Weather.ApiKey
+	apiHost := cfg.Weather.ApiHost
+	apiKey := cfg.Weather.ApiKey
 
 	if apiHost == "" || apiKey == "" {
 		// 返回模拟结果而不是错误，便于演示
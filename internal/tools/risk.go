@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"ai-ops/internal/util/errors"
+)
+
+// RiskLevel 工具调用的风险等级
+type RiskLevel string
+
+const (
+	// RiskReadOnly 只读操作，无需确认
+	RiskReadOnly RiskLevel = "read_only"
+	// RiskMutating 会修改系统状态的操作，执行前需要人工确认
+	RiskMutating RiskLevel = "mutating"
+	// RiskDestructive 破坏性或不可逆的操作，执行前需要人工确认
+	RiskDestructive RiskLevel = "destructive"
+)
+
+// RiskDeclarer 由声明了风险等级的工具实现，未实现的工具按 RiskMutating 处理
+type RiskDeclarer interface {
+	RiskLevel() RiskLevel
+}
+
+// ParseRiskLevel 解析配置中的风险等级
+func ParseRiskLevel(value string) (RiskLevel, error) {
+	switch level := RiskLevel(value); level {
+	case RiskReadOnly, RiskMutating, RiskDestructive:
+		return level, nil
+	default:
+		return "", errors.NewErrorWithDetails(errors.ErrCodeInvalidParameters,
+			"不支持的风险等级", value+"（可选 read_only、mutating、destructive）")
+	}
+}
+
+// RiskOf 返回工具的风险等级
+func RiskOf(tool Tool) RiskLevel {
+	if declarer, ok := tool.(RiskDeclarer); ok {
+		if level, err := ParseRiskLevel(string(declarer.RiskLevel())); err == nil {
+			return level
+		}
+	}
+	return RiskMutating
+}
+
+// RequiresApproval 是否需要在执行前人工确认
+func (r RiskLevel) RequiresApproval() bool {
+	return r != RiskReadOnly
+}
+
+// Label 风险等级的中文名称
+func (r RiskLevel) Label() string {
+	switch r {
+	case RiskReadOnly:
+		return "只读"
+	case RiskDestructive:
+		return "破坏性"
+	default:
+		return "变更"
+	}
+}
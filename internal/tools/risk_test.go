@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+// plainTool 没有声明风险等级的工具
+type plainTool struct{}
+
+func (plainTool) ID() string                 { return "plain" }
+func (plainTool) Name() string               { return "plain" }
+func (plainTool) Type() string               { return "test" }
+func (plainTool) Description() string        { return "未声明风险等级" }
+func (plainTool) Parameters() map[string]any { return nil }
+func (plainTool) Execute(context.Context, map[string]any) (string, error) {
+	return "", nil
+}
+
+// declaredTool 声明了风险等级的工具
+type declaredTool struct {
+	plainTool
+	level RiskLevel
+}
+
+func (t declaredTool) RiskLevel() RiskLevel { return t.level }
+
+func TestRiskOf(t *testing.T) {
+	tests := []struct {
+		name string
+		tool Tool
+		want RiskLevel
+	}{
+		{name: "未声明时按变更处理", tool: plainTool{}, want: RiskMutating},
+		{name: "只读", tool: declaredTool{level: RiskReadOnly}, want: RiskReadOnly},
+		{name: "变更", tool: declaredTool{level: RiskMutating}, want: RiskMutating},
+		{name: "破坏性", tool: declaredTool{level: RiskDestructive}, want: RiskDestructive},
+		{name: "无效的声明按变更处理", tool: declaredTool{level: "harmless"}, want: RiskMutating},
+		{name: "空声明按变更处理", tool: declaredTool{}, want: RiskMutating},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RiskOf(tt.tool); got != tt.want {
+				t.Errorf("RiskOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRiskLevel(t *testing.T) {
+	for _, value := range []string{"read_only", "mutating", "destructive"} {
+		if level, err := ParseRiskLevel(value); err != nil || string(level) != value {
+			t.Errorf("ParseRiskLevel(%q) = %q, %v", value, level, err)
+		}
+	}
+	for _, value := range []string{"", "readonly", "READ_ONLY"} {
+		if _, err := ParseRiskLevel(value); err == nil {
+			t.Errorf("ParseRiskLevel(%q) 应报错", value)
+		}
+	}
+}
+
+func TestRequiresApproval(t *testing.T) {
+	tests := map[RiskLevel]bool{
+		RiskReadOnly:    false,
+		RiskMutating:    true,
+		RiskDestructive: true,
+		"":              true,
+	}
+	for level, want := range tests {
+		if got := level.RequiresApproval(); got != want {
+			t.Errorf("%q.RequiresApproval() = %v, want %v", level, got, want)
+		}
+	}
+}
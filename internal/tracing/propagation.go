@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// TraceparentHeader W3C Trace Context 的请求头名称
+const TraceparentHeader = "traceparent"
+
+// Traceparent 返回上下文中当前跨度的 W3C traceparent 值（版本 00，始终标记为已采样），
+// 追踪未启用或没有跨度时返回空字符串。只传播 traceparent，不传播 tracestate 与 baggage
+func Traceparent(ctx context.Context) string {
+	span := FromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	return "00-" + span.data.TraceID + "-" + span.data.SpanID + "-01"
+}
+
+// Inject 将当前跨度写入出站 HTTP 请求的 traceparent 请求头，使下游服务的跨度关联到同一追踪
+func Inject(ctx context.Context, header http.Header) {
+	if value := Traceparent(ctx); value != "" {
+		header.Set(TraceparentHeader, value)
+	}
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ai-ops/internal/util"
+	"ai-ops/internal/util/errors"
+)
+
+const (
+	// 单批次最多导出的跨度数量
+	maxBatchSize = 128
+	// 导出队列容量，队列满时丢弃新跨度
+	queueSize = 2048
+	// 定时导出间隔
+	flushInterval = 5 * time.Second
+)
+
+// Exporter 跨度导出器接口
+type Exporter interface {
+	// ExportSpans 导出一批已结束的跨度
+	ExportSpans(ctx context.Context, spans []SpanData) error
+
+	// Shutdown 关闭导出器并释放资源
+	Shutdown(ctx context.Context) error
+}
+
+// Provider 追踪提供者，负责在后台批量导出跨度
+type Provider struct {
+	exporter Exporter
+	queue    chan SpanData
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+var (
+	globalProvider *Provider
+	providerMutex  sync.RWMutex
+)
+
+// NewProvider 创建追踪提供者并启动后台导出协程
+func NewProvider(exporter Exporter) *Provider {
+	p := &Provider{
+		exporter: exporter,
+		queue:    make(chan SpanData, queueSize),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// SetProvider 设置全局追踪提供者，传入 nil 关闭追踪
+func SetProvider(p *Provider) {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+	globalProvider = p
+}
+
+// getProvider 获取全局追踪提供者
+func getProvider() *Provider {
+	providerMutex.RLock()
+	defer providerMutex.RUnlock()
+	return globalProvider
+}
+
+// Enabled 是否启用了追踪
+func Enabled() bool {
+	return getProvider() != nil
+}
+
+// enqueue 将结束的跨度放入导出队列
+func (p *Provider) enqueue(span SpanData) {
+	select {
+	case p.queue <- span:
+	default:
+		util.Debugw("追踪导出队列已满，丢弃跨度", map[string]interface{}{"span": span.Name})
+	}
+}
+
+// run 后台批量导出
+func (p *Provider) run() {
+	defer close(p.doneCh)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := p.exporter.ExportSpans(ctx, batch); err != nil {
+			util.Warnw("追踪数据导出失败", map[string]interface{}{
+				"error": err.Error(),
+				"spans": len(batch),
+			})
+		}
+		cancel()
+		batch = make([]SpanData, 0, maxBatchSize)
+	}
+
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stopCh:
+			// 导出队列中剩余的跨度
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= maxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Shutdown 导出剩余跨度并关闭导出器
+func (p *Provider) Shutdown(ctx context.Context) error {
+	var err error
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		select {
+		case <-p.doneCh:
+		case <-ctx.Done():
+			err = errors.WrapError(errors.ErrCodeTimeout, "等待追踪数据导出超时", ctx.Err())
+			return
+		}
+		err = p.exporter.Shutdown(ctx)
+	})
+	return err
+}
+
+// Shutdown 关闭全局追踪提供者，未启用时为空操作
+func Shutdown(ctx context.Context) error {
+	provider := getProvider()
+	if provider == nil {
+		return nil
+	}
+	SetProvider(nil)
+	return provider.Shutdown(ctx)
+}
@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"ai-ops/internal/util/errors"
+)
+
+// StatusCode 跨度状态码，取值与 OTLP 保持一致
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// SpanKind 跨度类型，取值与 OTLP 保持一致
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanData 已结束跨度的只读数据，供导出器使用
+type SpanData struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         SpanKind       `json:"kind"`
+	StartTime    time.Time      `json:"start_time"`
+	EndTime      time.Time      `json:"end_time"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	StatusCode   StatusCode     `json:"status_code"`
+	StatusMsg    string         `json:"status_message,omitempty"`
+}
+
+// Span 一个进行中的跨度，nil 跨度的所有方法都是空操作
+type Span struct {
+	mu       sync.Mutex
+	data     SpanData
+	ended    bool
+	provider *Provider
+}
+
+type spanContextKey struct{}
+
+// Start 开始一个新的跨度，父跨度从 ctx 中获取。追踪未启用时返回 nil 跨度。
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	provider := getProvider()
+	if provider == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		provider: provider,
+		data: SpanData{
+			SpanID:     newID(8),
+			Name:       name,
+			Kind:       kind,
+			StartTime:  time.Now(),
+			Attributes: make(map[string]any),
+		},
+	}
+
+	if parent := FromContext(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentSpanID = parent.data.SpanID
+	} else {
+		span.data.TraceID = newID(16)
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// FromContext 获取上下文中的当前跨度
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SetAttribute 设置单个属性
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// SetAttributes 批量设置属性
+func (s *Span) SetAttributes(attrs map[string]any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range attrs {
+		s.data.Attributes[k] = v
+	}
+}
+
+// RecordError 记录错误并将跨度状态置为错误
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = StatusError
+	s.data.StatusMsg = err.Error()
+	s.data.Attributes["error"] = true
+	s.data.Attributes["error.code"] = errors.GetErrorCode(err)
+}
+
+// End 结束跨度并提交给导出队列，重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	if s.data.StatusCode == StatusUnset {
+		s.data.StatusCode = StatusOK
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	s.provider.enqueue(data)
+}
+
+// TraceID 返回跨度所属的追踪ID
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.TraceID
+}
+
+// newID 生成指定字节数的随机十六进制ID
+func newID(size int) string {
+	buf := make([]byte, size)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -32,4 +32,6 @@
 //  5. 使用中间件：
 //     // HTTP中间件
 //     http.Handle("/", errors.HTTPMiddleware(myHandler))
+//     // 返回错误的处理函数，错误按错误代码转换为 JSON 错误响应
+//     http.Handle("/api", errors.HTTPMiddleware(errors.HandlerFunc(myFunc)))
 package errors
@@ -1,6 +1,8 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -26,6 +28,7 @@ const (
 	ErrCodeAPIRequestFailed   = "API_REQUEST_FAILED"  // API请求失败
 	ErrCodeTimeout            = "TIMEOUT"             // 请求超时
 	ErrCodeRateLimited        = "RATE_LIMITED"        // 请求频率限制
+	ErrCodeUnauthorized       = "UNAUTHORIZED"        // 未认证
 	ErrCodeForbidden          = "FORBIDDEN"           // 禁止访问
 	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE" // 服务不可用
 
@@ -134,6 +137,21 @@ func GetErrorCode(err error) string {
 	return ErrCodeInternalErr
 }
 
+// CodeOf 提取错误链中的错误代码，超时归为 TIMEOUT，取消归为 CONTEXT_CANCELED
+func CodeOf(err error) string {
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return ErrCodeTimeout
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return ErrCodeContextCanceled
+	}
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ErrCodeInternalErr
+}
+
 // GetErrorDetails 获取错误详情
 func GetErrorDetails(err error) string {
 	if appErr, ok := err.(*AppError); ok {
@@ -58,6 +58,8 @@ func (h *DefaultErrorHandler) GetUserFriendlyMessage(err error) string {
 		return "请求超时，请稍后重试"
 	case ErrCodeRateLimited:
 		return "请求频率过高，请稍后重试"
+	case ErrCodeUnauthorized:
+		return "未认证，请提供有效的访问令牌"
 	case ErrCodeForbidden:
 		return "访问被拒绝，请检查权限设置"
 	case ErrCodeServiceUnavailable:
@@ -1,10 +1,12 @@
 package errors
 
 import (
+	"encoding/json"
+	stderrors "errors"
 	"net/http"
 )
 
-// HTTPMiddleware HTTP错误处理中间件
+// HTTPMiddleware HTTP错误处理中间件，将处理器的 panic 转换为 JSON 错误响应
 func HTTPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -22,14 +24,7 @@ func HTTPMiddleware(next http.Handler) http.Handler {
 
 				// 处理错误
 				DefaultHandler.HandleError(appErr)
-
-				// 返回错误响应
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(getHTTPStatus(appErr.Code))
-
-				// 在实际应用中，这里应该返回JSON格式的错误响应
-				// 例如：json.NewEncoder(w).Encode(ErrorResponse{Code: appErr.Code, Message: appErr.Message})
-				_, _ = w.Write([]byte(DefaultHandler.GetUserFriendlyMessage(appErr)))
+				WriteHTTPError(w, appErr)
 			}
 		}()
 
@@ -37,6 +32,50 @@ func HTTPMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// HandlerFunc 返回错误的 HTTP 处理函数，返回的错误由 WriteHTTPError 转换为 JSON 错误响应
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP 实现 http.Handler 接口
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		DefaultHandler.HandleError(err)
+		WriteHTTPError(w, err)
+	}
+}
+
+// ErrorResponse HTTP 错误响应
+type ErrorResponse struct {
+	Error *AppError `json:"error"`
+}
+
+// WriteHTTPError 按错误代码对应的状态码写入 JSON 错误响应
+func WriteHTTPError(w http.ResponseWriter, err error) {
+	status, body := NewErrorResponse(err)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// NewErrorResponse 返回错误对应的 HTTP 状态码与响应体，
+// 服务端错误只返回用户友好的消息，避免泄露内部细节
+func NewErrorResponse(err error) (int, ErrorResponse) {
+	code := CodeOf(err)
+	status := getHTTPStatus(code)
+
+	// 包装后的错误按其中的 AppError 生成消息
+	var appErr *AppError
+	wrapped := stderrors.As(err, &appErr)
+	if !wrapped {
+		appErr = &AppError{Code: code}
+	}
+	body := &AppError{Code: code, Message: DefaultHandler.GetUserFriendlyMessage(appErr)}
+	if wrapped && status < http.StatusInternalServerError {
+		body.Message = appErr.Message
+		body.Details = appErr.Details
+	}
+	return status, ErrorResponse{Error: body}
+}
+
 // getHTTPStatus 根据错误代码获取HTTP状态码
 func getHTTPStatus(code string) int {
 	switch code {
@@ -45,6 +84,8 @@ func getHTTPStatus(code string) int {
 		return http.StatusBadRequest
 	case ErrCodeNotFound:
 		return http.StatusNotFound
+	case ErrCodeUnauthorized:
+		return http.StatusUnauthorized
 	case ErrCodeAPIKeyMissing, ErrCodeForbidden:
 		return http.StatusForbidden
 	case ErrCodeRateLimited:
@@ -57,7 +98,7 @@ func getHTTPStatus(code string) int {
 		return http.StatusInternalServerError
 	case ErrCodeNetworkFailed, ErrCodeAPIRequestFailed:
 		return http.StatusBadGateway
-	case ErrCodeTimeout:
+	case ErrCodeTimeout, ErrCodeContextCanceled:
 		return http.StatusRequestTimeout
 	case ErrCodeServiceUnavailable:
 		return http.StatusServiceUnavailable
@@ -0,0 +1,25 @@
+package util
+
+import "strings"
+
+// TruncateRunes 按字符截断文本，maxRunes 不大于 0 时不截断，返回截断后的文本与是否发生截断
+func TruncateRunes(text string, maxRunes int) (string, bool) {
+	runes := []rune(text)
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return text, false
+	}
+	return string(runes[:maxRunes]), true
+}
+
+// Ellipsize 按字符截断文本，截断时追加省略号
+func Ellipsize(text string, maxRunes int) string {
+	if short, truncated := TruncateRunes(text, maxRunes); truncated {
+		return short + "..."
+	}
+	return text
+}
+
+// OneLine 将文本压缩为单行后按字符截断，用于列表、标题等单行展示
+func OneLine(text string, maxRunes int) string {
+	return Ellipsize(strings.Join(strings.Fields(text), " "), maxRunes)
+}